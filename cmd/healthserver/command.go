@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/logging"
+	"github.com/eslutz/torarr/internal/supervisor"
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+const usage = `usage: healthserver [command]
+
+Without a command, runs the health server.
+
+Commands:
+  supervise               Run the health server and launch Tor as a child
+                          process, restarting it if it exits.
+  config validate [file]  Print the resolved configuration with secrets
+                          redacted and report invalid values. The file
+                          defaults to CONFIG_FILE.
+  torrc prepare           Apply the Tor settings to TORRC_FILE, rendering
+                          the whole file when TORRC_GENERATE is set.
+  torrc render            Print the torrc rendered from the configuration.
+`
+
+// runCommand runs a subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	if len(args) == 1 && args[0] == "supervise" {
+		return serve(true)
+	}
+	if len(args) >= 2 && args[0] == "config" && args[1] == "validate" {
+		return validateConfig(args[2:])
+	}
+	if len(args) == 2 && args[0] == "torrc" && (args[1] == "prepare" || args[1] == "render") {
+		return torrcCommand(args[1])
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+	return 2
+}
+
+func validateConfig(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	path := os.Getenv("CONFIG_FILE")
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	cfg, problems, err := config.Inspect(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	out, err := cfg.MarshalRedacted()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	_, _ = os.Stdout.Write(out)
+
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "invalid: %v\n", problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func torrcCommand(action string) int {
+	if err := logging.Setup(logging.FormatJSON, "INFO"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %v\n", err)
+		return 1
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return 1
+	}
+
+	if action == "prepare" {
+		if err := supervisor.PrepareTorrc(cfg.TorrcFile, cfg); err != nil {
+			slog.Error("Failed to prepare torrc", "path", cfg.TorrcFile, "error", err)
+			return 1
+		}
+		slog.Info("Prepared torrc", "path", cfg.TorrcFile, "generated", cfg.TorrcGenerate)
+		return 0
+	}
+
+	c, err := supervisor.TorrcConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	out, err := torrc.Render(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	_, _ = os.Stdout.Write(out)
+	return 0
+}
@@ -2,24 +2,56 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/eslutz/torarr/internal/config"
 	"github.com/eslutz/torarr/internal/health"
+	"github.com/eslutz/torarr/internal/logging"
+	"github.com/eslutz/torarr/internal/supervisor"
+	"github.com/eslutz/torarr/internal/tlsconfig"
 )
 
 func main() {
-	// Setup JSON logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slog.SetDefault(logger)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+	os.Exit(serve(false))
+}
+
+// serve runs the health server until SIGINT or SIGTERM and returns the exit
+// code. With supervise set it also launches Tor and manages its lifetime.
+func serve(supervise bool) int {
+	// Setup JSON logger; the level is applied once configuration is loaded
+	if err := logging.Setup(logging.FormatJSON, "INFO"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %v\n", err)
+		return 1
+	}
 
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		return 1
+	}
+	if err := logging.Setup(cfg.LogFormat, cfg.LogLevel); err != nil {
+		slog.Error("Failed to configure logging", "format", cfg.LogFormat, "level", cfg.LogLevel, "error", err)
+	}
+
+	var torSupervisor *supervisor.Supervisor
+	if supervise {
+		if err := supervisor.PrepareTorrc(cfg.TorrcFile, cfg); err != nil {
+			slog.Error("Failed to prepare torrc", "path", cfg.TorrcFile, "error", err)
+			return 1
+		}
+		torSupervisor = supervisor.New(cfg)
+	}
 
 	handler := health.NewHandler(cfg)
 	defer func() {
@@ -28,37 +60,125 @@ func main() {
 		}
 	}()
 
-	mux := http.NewServeMux()
-	handler.SetupRoutes(mux)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go handler.WatchTorEvents(watchCtx)
+	go handler.RunRenewalSchedule(watchCtx)
+	go handler.RunMonitor(watchCtx)
+
+	var tlsConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		reloader, err := tlsconfig.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, cfg.TLSClientAuth)
+		if err != nil {
+			slog.Error("Failed to load TLS configuration", "error", err)
+			return 1
+		}
+		tlsConfig = reloader.TLSConfig()
+		go reloader.Watch(watchCtx, cfg.TLSReloadInterval)
+	}
+
+	var servers []*http.Server
+	for _, listener := range cfg.Listeners() {
+		mux := http.NewServeMux()
+		for _, group := range listener.Groups {
+			handler.SetupGroupRoutes(mux, group)
+		}
+
+		server := &http.Server{
+			Addr:         listener.Address,
+			Handler:      mux,
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  120 * time.Second,
+		}
+		server.RegisterOnShutdown(handler.CloseEvents)
+		servers = append(servers, server)
 
-	server := &http.Server{
-		Addr:         ":" + cfg.HealthPort,
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		go func() {
+			slog.Info("Starting health server",
+				"address", listener.Address,
+				"routes", listener.Groups,
+				"tls", tlsConfig != nil,
+				"client_auth", cfg.TLSClientAuth,
+			)
+			var err error
+			if tlsConfig != nil {
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				slog.Error("Failed to start server", "address", listener.Address, "error", err)
+				os.Exit(1)
+			}
+		}()
 	}
 
+	// SIGHUP reloads configuration; failures are logged by Reload and the
+	// running configuration is kept.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		slog.Info("Starting health server", "port", cfg.HealthPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("Failed to start server", "error", err)
-			os.Exit(1)
+		for range hup {
+			slog.Info("Received SIGHUP, reloading configuration")
+			_, _ = handler.Reload()
 		}
 	}()
 
+	// Tor stops when watchCtx is cancelled; torExited stays nil (blocking
+	// forever) when Tor is not supervised.
+	var torExited chan error
+	if torSupervisor != nil {
+		torExited = make(chan error, 1)
+		go func() { torExited <- torSupervisor.Run(watchCtx) }()
+
+		// SIGUSR1 (log statistics) and SIGUSR2 (debug logging) are meant for Tor.
+		forward := make(chan os.Signal, 1)
+		signal.Notify(forward, syscall.SIGUSR1, syscall.SIGUSR2)
+		defer signal.Stop(forward)
+		go func() {
+			for sig := range forward {
+				if err := torSupervisor.Signal(sig); err != nil {
+					slog.Warn("Failed to forward signal to Tor", "signal", sig, "error", err)
+				}
+			}
+		}()
+	}
+
+	exitCode := 0
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case err := <-torExited:
+		slog.Error("Tor supervisor stopped", "error", err)
+		torExited = nil
+		exitCode = 1
+	}
+	signal.Stop(hup)
 
 	slog.Info("Shutting down server...")
+	stopWatching()
+	if torExited != nil {
+		if err := <-torExited; err != nil {
+			slog.Error("Tor supervisor stopped", "error", err)
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		slog.Error("Server forced to shutdown", "error", err)
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Go(func() {
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Error("Server forced to shutdown", "address", server.Addr, "error", err)
+			}
+		})
 	}
+	wg.Wait()
 
 	fmt.Println("Server stopped")
+	return exitCode
 }
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scope is the level of access a credential grants. Control implies read.
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"
+	ScopeControl Scope = "control"
+)
+
+// Allows reports whether a credential with scope s may access required.
+func (s Scope) Allows(required Scope) bool {
+	return s == required || s == ScopeControl
+}
+
+// Credential is a bearer token, or a username and password for HTTP basic
+// auth, granting access to routes of its scope.
+type Credential struct {
+	Scope    Scope
+	Username string // Empty for bearer tokens
+	Secret   string
+}
+
+// loadCredentials gathers credentials from HEALTH_ADMIN_TOKEN,
+// HEALTH_AUTH_TOKENS, HEALTH_AUTH_USERS and HEALTH_AUTH_FILE. Invalid entries
+// are logged and skipped.
+func (l *loader) loadCredentials() []Credential {
+	var credentials []Credential
+
+	if token := l.lookup("HEALTH_ADMIN_TOKEN"); token != "" {
+		credentials = append(credentials, Credential{Scope: ScopeControl, Secret: token})
+	}
+
+	for _, entry := range parseEndpoints(l.getString("HEALTH_AUTH_TOKENS", "")) {
+		cred, err := parseTokenEntry(entry)
+		if err != nil {
+			l.invalid("Invalid HEALTH_AUTH_TOKENS entry", "ignoring", "error", err)
+			continue
+		}
+		credentials = append(credentials, cred)
+	}
+
+	for _, entry := range parseEndpoints(l.getString("HEALTH_AUTH_USERS", "")) {
+		cred, err := parseUserEntry(entry)
+		if err != nil {
+			l.invalid("Invalid HEALTH_AUTH_USERS entry", "ignoring", "error", err)
+			continue
+		}
+		credentials = append(credentials, cred)
+	}
+
+	if path := l.getString("HEALTH_AUTH_FILE", ""); path != "" {
+		fileCredentials, err := l.readCredentialsFile(path)
+		if err != nil {
+			l.invalid("Failed to read auth file", "ignoring", "path", path, "error", err)
+		}
+		credentials = append(credentials, fileCredentials...)
+	}
+
+	return credentials
+}
+
+// parseTokenEntry parses "scope:token".
+func parseTokenEntry(entry string) (Credential, error) {
+	scope, token, found := strings.Cut(entry, ":")
+	if !found || token == "" {
+		return Credential{}, fmt.Errorf("expected scope:token")
+	}
+	return newCredential(scope, "", token)
+}
+
+// parseUserEntry parses "scope:username:password".
+func parseUserEntry(entry string) (Credential, error) {
+	scope, rest, _ := strings.Cut(entry, ":")
+	username, password, found := strings.Cut(rest, ":")
+	if !found || username == "" || password == "" {
+		return Credential{}, fmt.Errorf("expected scope:username:password")
+	}
+	return newCredential(scope, username, password)
+}
+
+func newCredential(scope, username, secret string) (Credential, error) {
+	switch Scope(strings.ToLower(scope)) {
+	case ScopeRead, ScopeControl:
+		return Credential{Scope: Scope(strings.ToLower(scope)), Username: username, Secret: secret}, nil
+	default:
+		return Credential{}, fmt.Errorf("invalid scope %q (valid options: read, control)", scope)
+	}
+}
+
+// readCredentialsFile reads a secrets file with one credential per line:
+//
+//	token <scope> <token>
+//	basic <scope> <username> <password>
+//
+// Blank lines and lines starting with "#" are ignored. Invalid lines are
+// logged and skipped so one typo does not lock out every client.
+func (l *loader) readCredentialsFile(path string) ([]Credential, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var credentials []Credential
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var cred Credential
+		var err error
+		fields := strings.Fields(line)
+		switch {
+		case fields[0] == "token" && len(fields) == 3:
+			cred, err = newCredential(fields[1], "", fields[2])
+		case fields[0] == "basic" && len(fields) == 4:
+			cred, err = newCredential(fields[1], fields[2], fields[3])
+		default:
+			err = fmt.Errorf("expected \"token <scope> <token>\" or \"basic <scope> <username> <password>\"")
+		}
+		if err != nil {
+			l.invalid("Invalid auth file line", "ignoring", "path", path, "line", lineNum, "error", err)
+			continue
+		}
+		credentials = append(credentials, cred)
+	}
+
+	return credentials, scanner.Err()
+}
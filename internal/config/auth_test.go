@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScope_Allows(t *testing.T) {
+	if !ScopeControl.Allows(ScopeRead) {
+		t.Error("expected control scope to allow read")
+	}
+	if ScopeRead.Allows(ScopeControl) {
+		t.Error("expected read scope not to allow control")
+	}
+}
+
+func TestLoadCredentials_Env(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("HEALTH_AUTH_TOKENS", "control:abc123, read:def456, admin:bogus, nocolon"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_AUTH_USERS", "read:grafana:p@ss:word,control:alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := newLoader(nil, false).loadCredentials()
+
+	expected := []Credential{
+		{Scope: ScopeControl, Secret: "abc123"},
+		{Scope: ScopeRead, Secret: "def456"},
+		{Scope: ScopeRead, Username: "grafana", Secret: "p@ss:word"},
+	}
+	if len(credentials) != len(expected) {
+		t.Fatalf("expected %d credentials, got %+v", len(expected), credentials)
+	}
+	for i, cred := range credentials {
+		if cred != expected[i] {
+			t.Errorf("credential %d: expected %+v, got %+v", i, expected[i], cred)
+		}
+	}
+}
+
+func TestLoadCredentials_File(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	path := filepath.Join(t.TempDir(), "auth")
+	content := "# Torarr credentials\n" +
+		"token control s3cr3t\n" +
+		"\n" +
+		"basic READ prometheus scrape-pass\n" +
+		"token superuser nope\n" +
+		"basic control missing-password\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_AUTH_FILE", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_ADMIN_TOKEN", "legacy"); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := newLoader(nil, false).loadCredentials()
+
+	expected := []Credential{
+		{Scope: ScopeControl, Secret: "legacy"},
+		{Scope: ScopeControl, Secret: "s3cr3t"},
+		{Scope: ScopeRead, Username: "prometheus", Secret: "scrape-pass"},
+	}
+	if len(credentials) != len(expected) {
+		t.Fatalf("expected %d credentials, got %+v", len(expected), credentials)
+	}
+	for i, cred := range credentials {
+		if cred != expected[i] {
+			t.Errorf("credential %d: expected %+v, got %+v", i, expected[i], cred)
+		}
+	}
+}
+
+func TestLoadCredentials_MissingFile(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("HEALTH_AUTH_FILE", filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Fatal(err)
+	}
+
+	if credentials := newLoader(nil, false).loadCredentials(); len(credentials) != 0 {
+		t.Errorf("expected no credentials, got %+v", credentials)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+// defaultTransportPlugins are the pluggable transport clients installed in
+// the image. Tor only launches a plugin when a configured bridge uses it.
+func defaultTransportPlugins() []string {
+	return []string{
+		"obfs4,webtunnel exec /usr/bin/lyrebird",
+		"snowflake exec /usr/bin/snowflake-client",
+	}
+}
+
+// loadBridges validates TOR_TRANSPORT_PLUGINS and TOR_BRIDGES and resolves
+// TOR_USE_BRIDGES, which defaults to on whenever bridges are configured.
+// Invalid bridge lines are logged without their arguments and skipped.
+func (l *loader) loadBridges(cfg *Config) {
+	transports := make(map[string]bool)
+	plugins := make([]string, 0, len(cfg.TorTransportPlugins))
+	for _, plugin := range cfg.TorTransportPlugins {
+		if err := torrc.ValidatePlugin(plugin); err != nil {
+			l.invalid("Invalid TOR_TRANSPORT_PLUGINS entry", "ignoring",
+				"plugin", plugin,
+				"error", err,
+			)
+			continue
+		}
+		plugins = append(plugins, plugin)
+		for _, transport := range torrc.PluginTransports(plugin) {
+			transports[transport] = true
+		}
+	}
+	cfg.TorTransportPlugins = plugins
+
+	valid := make([]string, 0, len(cfg.TorBridges))
+	for i, line := range cfg.TorBridges {
+		bridge, err := torrc.ParseBridge(line)
+		if err != nil {
+			l.invalid("Invalid TOR_BRIDGES entry", "ignoring", "line", i+1, "error", err)
+			continue
+		}
+		if bridge.Transport != "" && !transports[bridge.Transport] {
+			l.invalid("No transport plugin for bridge", "ignoring",
+				"bridge", bridge.Label(),
+				"transport", bridge.Transport,
+			)
+			continue
+		}
+		valid = append(valid, line)
+	}
+	cfg.TorBridges = valid
+
+	cfg.TorUseBridges = l.getBool("TOR_USE_BRIDGES", len(cfg.TorBridges) > 0)
+	if cfg.TorUseBridges && len(cfg.TorBridges) == 0 {
+		l.invalid("TOR_USE_BRIDGES is enabled without any bridges", "connecting directly")
+		cfg.TorUseBridges = false
+	}
+}
+
+// Bridges returns the parsed bridges Tor is configured to use, or nil when
+// bridges are disabled.
+func (c *Config) Bridges() []torrc.Bridge {
+	if !c.TorUseBridges {
+		return nil
+	}
+	bridges := make([]torrc.Bridge, 0, len(c.TorBridges))
+	for _, line := range c.TorBridges {
+		if bridge, err := torrc.ParseBridge(line); err == nil {
+			bridges = append(bridges, bridge)
+		}
+	}
+	return bridges
+}
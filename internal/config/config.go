@@ -1,38 +1,200 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/eslutz/torarr/internal/logging"
+	"github.com/eslutz/torarr/internal/schedule"
+	"github.com/eslutz/torarr/internal/torrc"
 )
 
+// Config is the resolved health server configuration. The config tag holds
+// the setting's key, the lowercase form of its environment variable, and
+// marks secrets that are redacted when the configuration is printed.
 type Config struct {
-	TorControlAddress       string
-	TorControlPassword      string
-	HealthPort              string
-	HealthExternalTimeout   int
-	HealthExternalEndpoints []string
-	LogLevel                string
-	WebhookURL              string
-	WebhookTemplate         string
-	WebhookEvents           []string
-	WebhookTimeout          time.Duration
+	TorControlAddress       string          `config:"tor_control_address"`
+	TorControlPassword      string          `config:"tor_control_password,secret"`
+	TorControlAuth          string          `config:"tor_control_auth"`
+	TorControlCookieFile    string          `config:"tor_control_cookie_file"`
+	TorDataDirectory        string          `config:"tor_data_directory"`
+	TorExitNodes            string          `config:"tor_exit_nodes"`
+	TorBinary               string          `config:"tor_binary"`
+	TorrcFile               string          `config:"torrc_file"`
+	TorrcGenerate           bool            `config:"torrc_generate"`
+	TorSocksPort            string          `config:"tor_socks_port"`
+	TorSocksIsolation       []string        `config:"tor_socks_isolation"`
+	TorSocksListeners       []SocksListener `config:"tor_socks_listeners"`
+	TorExcludeNodes         string          `config:"tor_exclude_nodes"`
+	TorExcludeExitNodes     string          `config:"tor_exclude_exit_nodes"`
+	TorNumEntryGuards       int             `config:"tor_num_entry_guards"`
+	TorBandwidthRate        string          `config:"tor_bandwidth_rate"`
+	TorBandwidthBurst       string          `config:"tor_bandwidth_burst"`
+	TorAccountingMax        string          `config:"tor_accounting_max"`
+	TorAccountingStart      string          `config:"tor_accounting_start"`
+	TorUseBridges           bool            `config:"tor_use_bridges"`
+	TorBridges              []string        `config:"tor_bridges,secret"`
+	TorTransportPlugins     []string        `config:"tor_transport_plugins"`
+	HealthPort              string          `config:"health_port"`
+	HealthProbesAddress     string          `config:"health_probes_address"`
+	HealthMetricsAddress    string          `config:"health_metrics_address"`
+	HealthAdminAddress      string          `config:"health_admin_address"`
+	HealthExternalTimeout   int             `config:"health_external_timeout"`
+	HealthExternalEndpoints []string        `config:"health_external_endpoints"`
+	HealthCheckInterval     time.Duration   `config:"health_check_interval"`
+	HealthExternalInterval  time.Duration   `config:"health_external_interval"`
+	TrafficHistory          time.Duration   `config:"traffic_history"`
+	AuthCredentials         []Credential    `config:"auth_credentials"`
+	AuthRequireRead         bool            `config:"health_auth_require_read"`
+	TLSCertFile             string          `config:"health_tls_cert_file"`
+	TLSKeyFile              string          `config:"health_tls_key_file"`
+	TLSClientCAFile         string          `config:"health_tls_client_ca_file"`
+	TLSClientAuth           string          `config:"health_tls_client_auth"`
+	TLSReloadInterval       time.Duration   `config:"health_tls_reload_interval"`
+	LogLevel                string          `config:"log_level"`
+	LogFormat               string          `config:"log_format"`
+	TorLogRelay             bool            `config:"tor_log_relay"`
+	WebhookURL              string          `config:"webhook_url,secret"`
+	WebhookTemplate         string          `config:"webhook_template"`
+	WebhookEvents           []string        `config:"webhook_events"`
+	WebhookTimeout          time.Duration   `config:"webhook_timeout"`
+	CircuitRenewInterval    time.Duration   `config:"circuit_renew_interval"`
+	CircuitRenewJitter      time.Duration   `config:"circuit_renew_jitter"`
+	CircuitRenewCron        string          `config:"circuit_renew_cron"`
+
+	AutoRenewEnabled          bool          `config:"auto_renew_enabled"`
+	AutoRenewFailureThreshold int           `config:"auto_renew_failure_threshold"`
+	AutoRenewMaxAttempts      int           `config:"auto_renew_max_attempts"`
+	AutoRenewRecheckDelay     time.Duration `config:"auto_renew_recheck_delay"`
+}
+
+// ValidationError lists the invalid settings that strict mode refused to
+// replace with defaults.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		msgs[i] = problem.Error()
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(msgs, "; "))
+}
+
+// Load resolves the configuration from environment variables and the YAML
+// file named by CONFIG_FILE, with environment variables taking precedence.
+// Invalid values are logged and replaced with defaults unless CONFIG_STRICT
+// is enabled, in which case Load returns a *ValidationError instead.
+func Load() (*Config, error) {
+	return load(false)
+}
+
+// LoadStrict is Load with strict mode always enabled. Reloads use it so a
+// typo in a running deployment is rejected instead of becoming a default.
+func LoadStrict() (*Config, error) {
+	return load(true)
+}
+
+func load(strict bool) (*Config, error) {
+	file, err := loadConfigFile(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLoader(file, strict)
+	if l.getBool("CONFIG_STRICT", false) {
+		l.strict = true
+	}
+
+	cfg := l.load()
+	if l.strict && len(l.problems) > 0 {
+		return nil, &ValidationError{Problems: l.problems}
+	}
+	return cfg, nil
+}
+
+// Inspect resolves the configuration like Load, reading the config file at
+// path instead of CONFIG_FILE, and returns every invalid value alongside
+// the configuration with defaults applied. Nothing is logged.
+func Inspect(path string) (*Config, []error, error) {
+	file, err := loadConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := newLoader(file, true)
+	l.getBool("CONFIG_STRICT", false)
+
+	cfg := l.load()
+	return cfg, l.problems, nil
+}
+
+func loadConfigFile(path string) (map[string]string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+	return readConfigFile(path)
 }
 
-func Load() *Config {
+func (l *loader) load() *Config {
 	cfg := &Config{
-		TorControlAddress:       getEnv("TOR_CONTROL_ADDRESS", "127.0.0.1:9051"),
-		TorControlPassword:      os.Getenv("TOR_CONTROL_PASSWORD"),
-		HealthPort:              getEnv("HEALTH_PORT", "9091"),
-		HealthExternalTimeout:   getEnvAsInt("HEALTH_EXTERNAL_TIMEOUT", 15),
-		HealthExternalEndpoints: parseEndpoints(getEnv("HEALTH_EXTERNAL_ENDPOINTS", "")),
-		LogLevel:                strings.ToUpper(getEnv("LOG_LEVEL", "INFO")),
-		WebhookURL:              getEnv("WEBHOOK_URL", ""),
-		WebhookTemplate:         strings.ToLower(getEnv("WEBHOOK_TEMPLATE", "")),
-		WebhookEvents:           parseEndpoints(getEnv("WEBHOOK_EVENTS", "")),
-		WebhookTimeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		TorControlAddress:       l.getString("TOR_CONTROL_ADDRESS", "127.0.0.1:9051"),
+		TorControlPassword:      l.lookup("TOR_CONTROL_PASSWORD"),
+		TorControlAuth:          strings.ToLower(l.getString("TOR_CONTROL_AUTH", "auto")),
+		TorControlCookieFile:    l.getString("TOR_CONTROL_COOKIE_FILE", ""),
+		TorDataDirectory:        l.getString("TOR_DATA_DIRECTORY", "/var/lib/tor"),
+		TorExitNodes:            l.getString("TOR_EXIT_NODES", ""),
+		TorBinary:               l.getString("TOR_BINARY", "tor"),
+		TorrcFile:               l.getString("TORRC_FILE", "/etc/tor/torrc"),
+		TorrcGenerate:           l.getBool("TORRC_GENERATE", false),
+		TorSocksPort:            l.getString("TOR_SOCKS_PORT", "0.0.0.0:9050"),
+		TorSocksIsolation:       parseEndpoints(l.getString("TOR_SOCKS_ISOLATION", "")),
+		TorExcludeNodes:         l.getString("TOR_EXCLUDE_NODES", ""),
+		TorExcludeExitNodes:     l.getString("TOR_EXCLUDE_EXIT_NODES", ""),
+		TorNumEntryGuards:       l.getInt("TOR_NUM_ENTRY_GUARDS", 0),
+		TorBandwidthRate:        l.getString("TOR_BANDWIDTH_RATE", ""),
+		TorBandwidthBurst:       l.getString("TOR_BANDWIDTH_BURST", ""),
+		TorAccountingMax:        l.getString("TOR_ACCOUNTING_MAX", ""),
+		TorAccountingStart:      l.getString("TOR_ACCOUNTING_START", ""),
+		TorBridges:              parseLines(l.getString("TOR_BRIDGES", "")),
+		TorTransportPlugins:     parseLines(l.getString("TOR_TRANSPORT_PLUGINS", "")),
+		HealthPort:              l.getString("HEALTH_PORT", "9091"),
+		HealthProbesAddress:     l.getString("HEALTH_PROBES_ADDRESS", ""),
+		HealthMetricsAddress:    l.getString("HEALTH_METRICS_ADDRESS", ""),
+		HealthAdminAddress:      l.getString("HEALTH_ADMIN_ADDRESS", ""),
+		HealthExternalTimeout:   l.getInt("HEALTH_EXTERNAL_TIMEOUT", 15),
+		HealthExternalEndpoints: parseEndpoints(l.getString("HEALTH_EXTERNAL_ENDPOINTS", "")),
+		HealthCheckInterval:     l.getDuration("HEALTH_CHECK_INTERVAL", 15*time.Second),
+		HealthExternalInterval:  l.getDuration("HEALTH_EXTERNAL_INTERVAL", time.Minute),
+		TrafficHistory:          l.getDuration("TRAFFIC_HISTORY", time.Hour),
+		AuthCredentials:         l.loadCredentials(),
+		AuthRequireRead:         l.getBool("HEALTH_AUTH_REQUIRE_READ", false),
+		TLSCertFile:             l.getString("HEALTH_TLS_CERT_FILE", ""),
+		TLSKeyFile:              l.getString("HEALTH_TLS_KEY_FILE", ""),
+		TLSClientCAFile:         l.getString("HEALTH_TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:           strings.ToLower(l.getString("HEALTH_TLS_CLIENT_AUTH", "")),
+		TLSReloadInterval:       l.getDuration("HEALTH_TLS_RELOAD_INTERVAL", 30*time.Second),
+		LogLevel:                strings.ToUpper(l.getString("LOG_LEVEL", "INFO")),
+		LogFormat:               strings.ToLower(l.getString("LOG_FORMAT", logging.FormatJSON)),
+		TorLogRelay:             l.getBool("TOR_LOG_RELAY", false),
+		WebhookURL:              l.getString("WEBHOOK_URL", ""),
+		WebhookTemplate:         strings.ToLower(l.getString("WEBHOOK_TEMPLATE", "")),
+		WebhookEvents:           parseEndpoints(l.getString("WEBHOOK_EVENTS", "")),
+		WebhookTimeout:          l.getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		CircuitRenewInterval:    l.getDuration("CIRCUIT_RENEW_INTERVAL", 0),
+		CircuitRenewJitter:      l.getDuration("CIRCUIT_RENEW_JITTER", 0),
+		CircuitRenewCron:        l.getString("CIRCUIT_RENEW_CRON", ""),
+
+		AutoRenewEnabled:          l.getBool("AUTO_RENEW_ENABLED", false),
+		AutoRenewFailureThreshold: l.getInt("AUTO_RENEW_FAILURE_THRESHOLD", 3),
+		AutoRenewMaxAttempts:      l.getInt("AUTO_RENEW_MAX_ATTEMPTS", 3),
+		AutoRenewRecheckDelay:     l.getDuration("AUTO_RENEW_RECHECK_DELAY", 15*time.Second),
 	}
 
 	if len(cfg.HealthExternalEndpoints) == 0 {
@@ -44,25 +206,26 @@ func Load() *Config {
 	} else {
 		// Validate webhook events against allowed set
 		validEvents := map[string]struct{}{
-			"circuit_renewed":  {},
-			"bootstrap_failed": {},
-			"health_changed":   {},
+			"circuit_renewed":          {},
+			"bootstrap_failed":         {},
+			"health_changed":           {},
+			"accounting_limit_reached": {},
 		}
 		filteredEvents := make([]string, 0, len(cfg.WebhookEvents))
 		for _, evt := range cfg.WebhookEvents {
 			if _, ok := validEvents[evt]; ok {
 				filteredEvents = append(filteredEvents, evt)
 			} else {
-				slog.Warn("Invalid webhook event configured; ignoring",
+				l.invalid("Invalid webhook event configured", "ignoring",
 					"event", evt,
-					"valid_options", []string{"circuit_renewed", "bootstrap_failed", "health_changed"},
+					"valid_options", []string{"circuit_renewed", "bootstrap_failed", "health_changed", "accounting_limit_reached"},
 				)
 			}
 		}
 		if len(filteredEvents) == 0 {
 			if len(cfg.WebhookEvents) > 0 {
 				slog.Warn("All configured webhook events were invalid; falling back to defaults",
-					"valid_options", []string{"circuit_renewed", "bootstrap_failed", "health_changed"},
+					"valid_options", []string{"circuit_renewed", "bootstrap_failed", "health_changed", "accounting_limit_reached"},
 				)
 			}
 			cfg.WebhookEvents = defaultWebhookEvents()
@@ -71,6 +234,267 @@ func Load() *Config {
 		}
 	}
 
+	if !slices.Contains(logging.Levels, cfg.LogLevel) {
+		l.invalid("Invalid log level", "defaulting to INFO",
+			"level", cfg.LogLevel,
+			"valid_options", logging.Levels,
+		)
+		cfg.LogLevel = "INFO"
+	}
+
+	if !slices.Contains(logging.Formats, cfg.LogFormat) {
+		l.invalid("Invalid log format", "defaulting to json",
+			"format", cfg.LogFormat,
+			"valid_options", logging.Formats,
+		)
+		cfg.LogFormat = logging.FormatJSON
+	}
+
+	validAuthMethods := []string{"auto", "null", "password", "cookie", "safecookie"}
+	if !slices.Contains(validAuthMethods, cfg.TorControlAuth) {
+		l.invalid("Invalid Tor control auth method", "defaulting to auto",
+			"method", cfg.TorControlAuth,
+			"valid_options", validAuthMethods,
+		)
+		cfg.TorControlAuth = "auto"
+	}
+
+	if err := torrc.ValidateAddress(cfg.TorSocksPort); err != nil {
+		l.invalid("Invalid Tor SOCKS port", "defaulting to 0.0.0.0:9050",
+			"address", cfg.TorSocksPort,
+			"error", err,
+		)
+		cfg.TorSocksPort = "0.0.0.0:9050"
+	}
+
+	for _, set := range []struct {
+		env   string
+		value *string
+	}{
+		{"TOR_EXIT_NODES", &cfg.TorExitNodes},
+		{"TOR_EXCLUDE_NODES", &cfg.TorExcludeNodes},
+		{"TOR_EXCLUDE_EXIT_NODES", &cfg.TorExcludeExitNodes},
+	} {
+		if err := torrc.ValidateRouterSet(*set.value); err != nil {
+			l.invalid("Invalid Tor node list", "ignoring",
+				"variable", set.env,
+				"value", *set.value,
+				"error", err,
+			)
+			*set.value = ""
+		}
+	}
+
+	validIsolation := make([]string, 0, len(cfg.TorSocksIsolation))
+	for _, flag := range cfg.TorSocksIsolation {
+		if torrc.ValidFlag(flag) {
+			validIsolation = append(validIsolation, flag)
+		} else {
+			l.invalid("Invalid SOCKS isolation flag", "ignoring",
+				"flag", flag,
+				"valid_options", torrc.IsolationFlags,
+			)
+		}
+	}
+	cfg.TorSocksIsolation = validIsolation
+	l.loadSocksListeners(cfg)
+
+	if cfg.TorNumEntryGuards < 0 {
+		l.invalid("Negative number of entry guards", "using Tor's default",
+			"guards", cfg.TorNumEntryGuards,
+		)
+		cfg.TorNumEntryGuards = 0
+	}
+
+	for _, bandwidth := range []struct {
+		env   string
+		value *string
+	}{
+		{"TOR_BANDWIDTH_RATE", &cfg.TorBandwidthRate},
+		{"TOR_BANDWIDTH_BURST", &cfg.TorBandwidthBurst},
+	} {
+		if _, err := torrc.ParseBandwidth(*bandwidth.value); err != nil {
+			l.invalid("Invalid Tor bandwidth limit", "using Tor's default",
+				"variable", bandwidth.env,
+				"value", *bandwidth.value,
+				"error", err,
+			)
+			*bandwidth.value = ""
+		}
+	}
+	rate, _ := torrc.ParseBandwidth(cfg.TorBandwidthRate)
+	burst, _ := torrc.ParseBandwidth(cfg.TorBandwidthBurst)
+	if rate > 0 && burst > 0 && burst < rate {
+		l.invalid("Tor bandwidth burst is lower than the rate", "using Tor's default burst",
+			"rate", cfg.TorBandwidthRate,
+			"burst", cfg.TorBandwidthBurst,
+		)
+		cfg.TorBandwidthBurst = ""
+	}
+
+	if _, err := torrc.ParseBandwidth(cfg.TorAccountingMax); err != nil {
+		l.invalid("Invalid Tor accounting limit", "disabling accounting",
+			"value", cfg.TorAccountingMax,
+			"error", err,
+		)
+		cfg.TorAccountingMax = ""
+	}
+	if err := torrc.ValidateAccountingStart(cfg.TorAccountingStart); err != nil {
+		l.invalid("Invalid Tor accounting start", "using Tor's default",
+			"value", cfg.TorAccountingStart,
+			"error", err,
+		)
+		cfg.TorAccountingStart = ""
+	}
+
+	if len(cfg.TorTransportPlugins) == 0 {
+		cfg.TorTransportPlugins = defaultTransportPlugins()
+	}
+	l.loadBridges(cfg)
+
+	if cfg.CircuitRenewInterval < 0 {
+		l.invalid("Negative circuit renew interval", "disabling interval renewal",
+			"interval", cfg.CircuitRenewInterval,
+		)
+		cfg.CircuitRenewInterval = 0
+	}
+
+	if cfg.CircuitRenewJitter < 0 {
+		l.invalid("Negative circuit renew jitter", "disabling jitter",
+			"jitter", cfg.CircuitRenewJitter,
+		)
+		cfg.CircuitRenewJitter = 0
+	}
+
+	if cfg.CircuitRenewCron != "" {
+		if _, err := schedule.ParseCron(cfg.CircuitRenewCron); err != nil {
+			l.invalid("Invalid circuit renew cron expression", "disabling cron renewal",
+				"cron", cfg.CircuitRenewCron,
+				"error", err,
+			)
+			cfg.CircuitRenewCron = ""
+		}
+	}
+
+	// Different hosts on one port cannot all bind, so only the first is kept
+	claimed := []string{":" + cfg.HealthPort}
+	for _, listen := range []struct {
+		env     string
+		address *string
+	}{
+		{"HEALTH_PROBES_ADDRESS", &cfg.HealthProbesAddress},
+		{"HEALTH_METRICS_ADDRESS", &cfg.HealthMetricsAddress},
+		{"HEALTH_ADMIN_ADDRESS", &cfg.HealthAdminAddress},
+	} {
+		if *listen.address == "" {
+			continue
+		}
+		if err := validateListenAddress(*listen.address); err != nil {
+			l.invalid("Invalid listen address", "serving on HEALTH_PORT instead",
+				"variable", listen.env,
+				"address", *listen.address,
+				"error", err,
+			)
+			*listen.address = ""
+			continue
+		}
+		if other, ok := conflictingListenAddress(*listen.address, claimed); ok {
+			l.invalid("Listen address shares a port with another listener", "serving on HEALTH_PORT instead",
+				"variable", listen.env,
+				"address", *listen.address,
+				"conflict", other,
+			)
+			*listen.address = ""
+			continue
+		}
+		claimed = append(claimed, *listen.address)
+	}
+
+	if cfg.HealthCheckInterval <= 0 {
+		l.invalid("Invalid health check interval", "defaulting to 15s",
+			"interval", cfg.HealthCheckInterval,
+		)
+		cfg.HealthCheckInterval = 15 * time.Second
+	}
+
+	if cfg.HealthExternalInterval <= 0 {
+		l.invalid("Invalid external check interval", "defaulting to 1m",
+			"interval", cfg.HealthExternalInterval,
+		)
+		cfg.HealthExternalInterval = time.Minute
+	}
+
+	// One sample is kept per second, so the history is capped at a day
+	if cfg.TrafficHistory < time.Minute || cfg.TrafficHistory > 24*time.Hour {
+		l.invalid("Traffic history must be between 1m and 24h", "defaulting to 1h",
+			"history", cfg.TrafficHistory,
+		)
+		cfg.TrafficHistory = time.Hour
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		l.invalid("TLS requires both a certificate and a key", "disabling TLS",
+			"cert_file", cfg.TLSCertFile,
+			"key_file", cfg.TLSKeyFile,
+		)
+		cfg.TLSCertFile = ""
+		cfg.TLSKeyFile = ""
+	}
+
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		l.invalid("Client certificate verification requires TLS", "ignoring client CA",
+			"client_ca_file", cfg.TLSClientCAFile,
+		)
+		cfg.TLSClientCAFile = ""
+	}
+
+	validClientAuth := []string{"none", "optional", "require"}
+	switch {
+	case cfg.TLSClientCAFile == "":
+		if cfg.TLSClientAuth != "" && cfg.TLSClientAuth != "none" {
+			l.invalid("TLS client auth requires a client CA", "disabling client auth",
+				"client_auth", cfg.TLSClientAuth,
+			)
+		}
+		cfg.TLSClientAuth = "none"
+	case cfg.TLSClientAuth == "":
+		cfg.TLSClientAuth = "require"
+	case !slices.Contains(validClientAuth, cfg.TLSClientAuth):
+		l.invalid("Invalid TLS client auth mode", "defaulting to require",
+			"client_auth", cfg.TLSClientAuth,
+			"valid_options", validClientAuth,
+		)
+		cfg.TLSClientAuth = "require"
+	}
+
+	if cfg.TLSReloadInterval <= 0 {
+		l.invalid("Invalid TLS reload interval", "defaulting to 30s",
+			"interval", cfg.TLSReloadInterval,
+		)
+		cfg.TLSReloadInterval = 30 * time.Second
+	}
+
+	if cfg.AutoRenewFailureThreshold < 1 {
+		l.invalid("Invalid auto renew failure threshold", "defaulting to 3",
+			"threshold", cfg.AutoRenewFailureThreshold,
+		)
+		cfg.AutoRenewFailureThreshold = 3
+	}
+
+	if cfg.AutoRenewMaxAttempts < 1 {
+		l.invalid("Invalid auto renew max attempts", "defaulting to 3",
+			"max_attempts", cfg.AutoRenewMaxAttempts,
+		)
+		cfg.AutoRenewMaxAttempts = 3
+	}
+
+	if cfg.AutoRenewRecheckDelay < 0 {
+		l.invalid("Negative auto renew recheck delay", "defaulting to 15s",
+			"delay", cfg.AutoRenewRecheckDelay,
+		)
+		cfg.AutoRenewRecheckDelay = 15 * time.Second
+	}
+
 	// Validate and set webhook template only when webhook URL is configured
 	if cfg.WebhookURL != "" {
 		if cfg.WebhookTemplate == "" {
@@ -85,7 +509,7 @@ func Load() *Config {
 			}
 		}
 		if !isValid {
-			slog.Warn("Invalid webhook template, defaulting to JSON",
+			l.invalid("Invalid webhook template", "defaulting to JSON",
 				"template", cfg.WebhookTemplate,
 				"valid_options", validTemplates,
 			)
@@ -93,61 +517,20 @@ func Load() *Config {
 		}
 	}
 
-	return cfg
-}
-
-func getEnv(key, defaultValue string) string {
-	value := strings.TrimSpace(os.Getenv(key))
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}
-
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := strings.TrimSpace(os.Getenv(key))
-	if valueStr == "" {
-		return defaultValue
+	for _, key := range l.unknownKeys() {
+		l.invalid("Unknown config file key", "ignoring", "key", key)
 	}
 
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		slog.Warn("Invalid configuration value",
-			"key", key,
-			"value", valueStr,
-			"default", defaultValue,
-			"error", err,
-		)
-		return defaultValue
-	}
-	return value
-}
-
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	valueStr := strings.TrimSpace(os.Getenv(key))
-	if valueStr == "" {
-		return defaultValue
-	}
-
-	value, err := time.ParseDuration(valueStr)
-	if err != nil {
-		slog.Warn("Invalid duration configuration value",
-			"key", key,
-			"value", valueStr,
-			"default", defaultValue,
-			"error", err,
-		)
-		return defaultValue
-	}
-	return value
+	return cfg
 }
 
+// parseEndpoints splits a list separated by commas or newlines.
 func parseEndpoints(raw string) []string {
 	if raw == "" {
 		return nil
 	}
 
-	parts := strings.Split(raw, ",")
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' })
 	var endpoints []string
 	for _, part := range parts {
 		endpoint := strings.TrimSpace(part)
@@ -158,6 +541,18 @@ func parseEndpoints(raw string) []string {
 	return endpoints
 }
 
+// parseLines splits a list separated by newlines only, for entries such as
+// bridge lines that contain commas themselves.
+func parseLines(raw string) []string {
+	var lines []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 func defaultExternalEndpoints() []string {
 	return []string{
 		"https://check.torproject.org/api/ip",
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -10,7 +13,7 @@ func TestLoad_Defaults(t *testing.T) {
 	// Clear any environment variables
 	clearEnv()
 
-	cfg := Load()
+	cfg := mustLoad(t)
 
 	if cfg.TorControlAddress != "127.0.0.1:9051" {
 		t.Errorf("expected TorControlAddress to be '127.0.0.1:9051', got '%s'", cfg.TorControlAddress)
@@ -20,6 +23,22 @@ func TestLoad_Defaults(t *testing.T) {
 		t.Errorf("expected HealthPort to be '9091', got '%s'", cfg.HealthPort)
 	}
 
+	if cfg.TorControlAuth != "auto" {
+		t.Errorf("expected TorControlAuth to be 'auto', got '%s'", cfg.TorControlAuth)
+	}
+
+	if cfg.TorControlCookieFile != "" {
+		t.Errorf("expected TorControlCookieFile to be empty, got '%s'", cfg.TorControlCookieFile)
+	}
+
+	if cfg.TorDataDirectory != "/var/lib/tor" {
+		t.Errorf("expected TorDataDirectory to be '/var/lib/tor', got '%s'", cfg.TorDataDirectory)
+	}
+
+	if cfg.TorBinary != "tor" || cfg.TorrcFile != "/etc/tor/torrc" || cfg.TorExitNodes != "" {
+		t.Errorf("unexpected Tor process defaults: binary=%q torrc=%q exit_nodes=%q", cfg.TorBinary, cfg.TorrcFile, cfg.TorExitNodes)
+	}
+
 	if cfg.HealthExternalTimeout != 15 {
 		t.Errorf("expected HealthExternalTimeout to be 15, got %d", cfg.HealthExternalTimeout)
 	}
@@ -28,10 +47,22 @@ func TestLoad_Defaults(t *testing.T) {
 		t.Errorf("expected LogLevel to be 'INFO', got '%s'", cfg.LogLevel)
 	}
 
+	if cfg.LogFormat != "json" {
+		t.Errorf("expected LogFormat to be 'json', got '%s'", cfg.LogFormat)
+	}
+
+	if cfg.TorLogRelay {
+		t.Error("expected TorLogRelay to be disabled by default")
+	}
+
 	if len(cfg.HealthExternalEndpoints) == 0 {
 		t.Error("expected default external endpoints to be set")
 	}
 
+	if len(cfg.AuthCredentials) != 0 || cfg.AuthRequireRead {
+		t.Errorf("expected no auth credentials by default, got %d (require read=%v)", len(cfg.AuthCredentials), cfg.AuthRequireRead)
+	}
+
 	// Check webhook defaults
 	if cfg.WebhookURL != "" {
 		t.Errorf("expected WebhookURL to be empty, got '%s'", cfg.WebhookURL)
@@ -50,6 +81,11 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.WebhookTimeout != 10*time.Second {
 		t.Errorf("expected WebhookTimeout to be 10s, got %v", cfg.WebhookTimeout)
 	}
+
+	if cfg.CircuitRenewInterval != 0 || cfg.CircuitRenewJitter != 0 || cfg.CircuitRenewCron != "" {
+		t.Errorf("expected scheduled renewal to be disabled by default, got interval=%v jitter=%v cron=%q",
+			cfg.CircuitRenewInterval, cfg.CircuitRenewJitter, cfg.CircuitRenewCron)
+	}
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -61,6 +97,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	if err := os.Setenv("TOR_CONTROL_PASSWORD", "secret123"); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.Setenv("TOR_EXIT_NODES", "{us},{ca}"); err != nil {
+		t.Fatal(err)
+	}
 	if err := os.Setenv("HEALTH_PORT", "9000"); err != nil {
 		t.Fatal(err)
 	}
@@ -70,9 +109,18 @@ func TestLoad_CustomValues(t *testing.T) {
 	if err := os.Setenv("HEALTH_EXTERNAL_ENDPOINTS", "https://example.com/api,https://test.com/check"); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.Setenv("HEALTH_ADMIN_TOKEN", "admin-token"); err != nil {
+		t.Fatal(err)
+	}
 	if err := os.Setenv("LOG_LEVEL", "debug"); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.Setenv("LOG_FORMAT", "Text"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("TOR_LOG_RELAY", "true"); err != nil {
+		t.Fatal(err)
+	}
 	if err := os.Setenv("WEBHOOK_URL", "https://hooks.example.com/webhook"); err != nil {
 		t.Fatal(err)
 	}
@@ -88,7 +136,7 @@ func TestLoad_CustomValues(t *testing.T) {
 
 	defer clearEnv()
 
-	cfg := Load()
+	cfg := mustLoad(t)
 
 	if cfg.TorControlAddress != "localhost:9999" {
 		t.Errorf("expected TorControlAddress to be 'localhost:9999', got '%s'", cfg.TorControlAddress)
@@ -98,6 +146,10 @@ func TestLoad_CustomValues(t *testing.T) {
 		t.Errorf("expected TorControlPassword to be 'secret123', got '%s'", cfg.TorControlPassword)
 	}
 
+	if cfg.TorExitNodes != "{us},{ca}" {
+		t.Errorf("expected TorExitNodes to be '{us},{ca}', got '%s'", cfg.TorExitNodes)
+	}
+
 	if cfg.HealthPort != "9000" {
 		t.Errorf("expected HealthPort to be '9000', got '%s'", cfg.HealthPort)
 	}
@@ -110,10 +162,22 @@ func TestLoad_CustomValues(t *testing.T) {
 		t.Errorf("expected LogLevel to be 'DEBUG', got '%s'", cfg.LogLevel)
 	}
 
+	if cfg.LogFormat != "text" {
+		t.Errorf("expected LogFormat to be 'text', got '%s'", cfg.LogFormat)
+	}
+
+	if !cfg.TorLogRelay {
+		t.Error("expected TorLogRelay to be enabled")
+	}
+
 	if len(cfg.HealthExternalEndpoints) != 2 {
 		t.Errorf("expected 2 external endpoints, got %d", len(cfg.HealthExternalEndpoints))
 	}
 
+	if len(cfg.AuthCredentials) != 1 || cfg.AuthCredentials[0] != (Credential{Scope: ScopeControl, Secret: "admin-token"}) {
+		t.Errorf("expected HEALTH_ADMIN_TOKEN to become a control token, got %+v", cfg.AuthCredentials)
+	}
+
 	// Check webhook custom values
 	if cfg.WebhookURL != "https://hooks.example.com/webhook" {
 		t.Errorf("expected WebhookURL to be 'https://hooks.example.com/webhook', got '%s'", cfg.WebhookURL)
@@ -132,54 +196,472 @@ func TestLoad_CustomValues(t *testing.T) {
 	}
 }
 
-func TestGetEnvAsDuration_ValidValue(t *testing.T) {
+func TestLoad_TorControlAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Cookie", value: "cookie", expected: "cookie"},
+		{name: "Mixed case", value: "SafeCookie", expected: "safecookie"},
+		{name: "Invalid falls back to auto", value: "kerberos", expected: "auto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv()
+			defer clearEnv()
+
+			if err := os.Setenv("TOR_CONTROL_AUTH", tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Setenv("TOR_CONTROL_COOKIE_FILE", "/run/tor/control.authcookie"); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := mustLoad(t)
+
+			if cfg.TorControlAuth != tt.expected {
+				t.Errorf("expected TorControlAuth to be '%s', got '%s'", tt.expected, cfg.TorControlAuth)
+			}
+			if cfg.TorControlCookieFile != "/run/tor/control.authcookie" {
+				t.Errorf("expected TorControlCookieFile to be set, got '%s'", cfg.TorControlCookieFile)
+			}
+		})
+	}
+}
+
+func TestLoad_LogLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Lower case", value: "warn", expected: "WARN"},
+		{name: "Offset falls back to INFO", value: "INFO+2", expected: "INFO"},
+		{name: "Negative offset falls back to INFO", value: "debug-4", expected: "INFO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv()
+			defer clearEnv()
+
+			if err := os.Setenv("LOG_LEVEL", tt.value); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := mustLoad(t)
+
+			if cfg.LogLevel != tt.expected {
+				t.Errorf("expected LogLevel to be '%s', got '%s'", tt.expected, cfg.LogLevel)
+			}
+		})
+	}
+}
+
+func TestLoaderGetDuration_ValidValue(t *testing.T) {
 	clearEnv()
 	if err := os.Setenv("TEST_DURATION", "5m30s"); err != nil {
 		t.Fatal(err)
 	}
 	defer clearEnv()
 
-	result := getEnvAsDuration("TEST_DURATION", 10*time.Second)
+	result := newLoader(nil, false).getDuration("TEST_DURATION", 10*time.Second)
 	expected := 5*time.Minute + 30*time.Second
 	if result != expected {
 		t.Errorf("expected duration %v, got %v", expected, result)
 	}
 }
 
-func TestGetEnvAsDuration_InvalidValue(t *testing.T) {
+func TestLoaderGetDuration_InvalidValue(t *testing.T) {
 	clearEnv()
 	if err := os.Setenv("TEST_DURATION", "not_a_duration"); err != nil {
 		t.Fatal(err)
 	}
 	defer clearEnv()
 
-	result := getEnvAsDuration("TEST_DURATION", 10*time.Second)
+	result := newLoader(nil, false).getDuration("TEST_DURATION", 10*time.Second)
 	if result != 10*time.Second {
 		t.Errorf("expected default value 10s for invalid input, got %v", result)
 	}
 }
 
-func TestGetEnvAsDuration_EmptyValue(t *testing.T) {
+func TestLoaderGetDuration_EmptyValue(t *testing.T) {
 	clearEnv()
-	result := getEnvAsDuration("NONEXISTENT_DURATION", 15*time.Second)
+	result := newLoader(nil, false).getDuration("NONEXISTENT_DURATION", 15*time.Second)
 	if result != 15*time.Second {
 		t.Errorf("expected default value 15s for empty env var, got %v", result)
 	}
 }
 
-func TestGetEnvAsInt_InvalidValue(t *testing.T) {
+func TestLoaderGetInt_InvalidValue(t *testing.T) {
 	clearEnv()
 	if err := os.Setenv("TEST_INT", "not_a_number"); err != nil {
 		t.Fatal(err)
 	}
 	defer clearEnv()
 
-	result := getEnvAsInt("TEST_INT", 42)
+	result := newLoader(nil, false).getInt("TEST_INT", 42)
 	if result != 42 {
 		t.Errorf("expected default value 42 for invalid input, got %d", result)
 	}
 }
 
+func TestLoaderGetBool(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{value: "", expected: true},
+		{value: "false", expected: false},
+		{value: "0", expected: false},
+		{value: "TRUE", expected: true},
+		{value: "not_a_bool", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			clearEnv()
+			if err := os.Setenv("TEST_BOOL", tt.value); err != nil {
+				t.Fatal(err)
+			}
+			defer clearEnv()
+
+			if result := newLoader(nil, false).getBool("TEST_BOOL", true); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoad_AutoRenew(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := mustLoad(t)
+	if cfg.AutoRenewEnabled {
+		t.Error("expected automatic renewal to be disabled by default")
+	}
+	if cfg.AutoRenewFailureThreshold != 3 ||
+		cfg.AutoRenewMaxAttempts != 3 || cfg.AutoRenewRecheckDelay != 15*time.Second {
+		t.Errorf("unexpected auto renew defaults: %+v", cfg)
+	}
+
+	if err := os.Setenv("AUTO_RENEW_ENABLED", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("AUTO_RENEW_FAILURE_THRESHOLD", "5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("AUTO_RENEW_MAX_ATTEMPTS", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = mustLoad(t)
+	if !cfg.AutoRenewEnabled {
+		t.Error("expected automatic renewal to be enabled")
+	}
+	if cfg.AutoRenewFailureThreshold != 5 {
+		t.Errorf("expected AutoRenewFailureThreshold to be 5, got %d", cfg.AutoRenewFailureThreshold)
+	}
+	if cfg.AutoRenewMaxAttempts != 3 {
+		t.Errorf("expected invalid AutoRenewMaxAttempts to fall back to 3, got %d", cfg.AutoRenewMaxAttempts)
+	}
+}
+
+func TestLoad_MonitorIntervals(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := mustLoad(t)
+	if cfg.HealthCheckInterval != 15*time.Second {
+		t.Errorf("expected HealthCheckInterval to be 15s, got %v", cfg.HealthCheckInterval)
+	}
+	if cfg.HealthExternalInterval != time.Minute {
+		t.Errorf("expected HealthExternalInterval to be 1m, got %v", cfg.HealthExternalInterval)
+	}
+	if cfg.TrafficHistory != time.Hour {
+		t.Errorf("expected TrafficHistory to be 1h, got %v", cfg.TrafficHistory)
+	}
+
+	if err := os.Setenv("HEALTH_CHECK_INTERVAL", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_EXTERNAL_INTERVAL", "0s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("TRAFFIC_HISTORY", "48h"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = mustLoad(t)
+	if cfg.HealthCheckInterval != 5*time.Second {
+		t.Errorf("expected HealthCheckInterval to be 5s, got %v", cfg.HealthCheckInterval)
+	}
+	if cfg.HealthExternalInterval != time.Minute {
+		t.Errorf("expected invalid HealthExternalInterval to fall back to 1m, got %v", cfg.HealthExternalInterval)
+	}
+	if cfg.TrafficHistory != time.Hour {
+		t.Errorf("expected TrafficHistory over a day to fall back to 1h, got %v", cfg.TrafficHistory)
+	}
+}
+
+func TestLoad_TorrcSettings(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := mustLoad(t)
+	if cfg.TorrcGenerate {
+		t.Error("expected torrc generation to be disabled by default")
+	}
+	if cfg.TorSocksPort != "0.0.0.0:9050" || len(cfg.TorSocksIsolation) != 0 {
+		t.Errorf("unexpected SOCKS defaults: port=%q isolation=%v", cfg.TorSocksPort, cfg.TorSocksIsolation)
+	}
+
+	for key, value := range map[string]string{
+		"TORRC_GENERATE":         "true",
+		"TOR_SOCKS_PORT":         "127.0.0.1:9150",
+		"TOR_SOCKS_ISOLATION":    "IsolateDestAddr,IsolateEverything,SessionGroup=3",
+		"TOR_EXCLUDE_NODES":      "{ru},{by}",
+		"TOR_EXCLUDE_EXIT_NODES": "{??}, {cn}",
+		"TOR_NUM_ENTRY_GUARDS":   "2",
+		"TOR_BANDWIDTH_RATE":     "2 MBytes",
+		"TOR_BANDWIDTH_BURST":    "1 MBytes",
+		"TOR_ACCOUNTING_MAX":     "100 GBytes",
+		"TOR_ACCOUNTING_START":   "month 32 00:00",
+	} {
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg = mustLoad(t)
+	if !cfg.TorrcGenerate || cfg.TorSocksPort != "127.0.0.1:9150" {
+		t.Errorf("expected generation on 127.0.0.1:9150, got generate=%v port=%q", cfg.TorrcGenerate, cfg.TorSocksPort)
+	}
+	if !slices.Equal(cfg.TorSocksIsolation, []string{"IsolateDestAddr", "SessionGroup=3"}) {
+		t.Errorf("expected unknown isolation flags to be dropped, got %v", cfg.TorSocksIsolation)
+	}
+	if cfg.TorExcludeNodes != "{ru},{by}" {
+		t.Errorf("expected TorExcludeNodes to be '{ru},{by}', got %q", cfg.TorExcludeNodes)
+	}
+	if cfg.TorExcludeExitNodes != "" {
+		t.Errorf("expected node list with whitespace to be ignored, got %q", cfg.TorExcludeExitNodes)
+	}
+	if cfg.TorNumEntryGuards != 2 {
+		t.Errorf("expected TorNumEntryGuards to be 2, got %d", cfg.TorNumEntryGuards)
+	}
+	if cfg.TorBandwidthRate != "2 MBytes" || cfg.TorBandwidthBurst != "" {
+		t.Errorf("expected burst below the rate to be dropped, got rate=%q burst=%q", cfg.TorBandwidthRate, cfg.TorBandwidthBurst)
+	}
+	if cfg.TorAccountingMax != "100 GBytes" || cfg.TorAccountingStart != "" {
+		t.Errorf("expected an invalid accounting start to be dropped, got max=%q start=%q", cfg.TorAccountingMax, cfg.TorAccountingStart)
+	}
+
+	if err := os.Setenv("TOR_SOCKS_PORT", "socks"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("TOR_BANDWIDTH_RATE", "fast"); err != nil {
+		t.Fatal(err)
+	}
+	cfg = mustLoad(t)
+	if cfg.TorSocksPort != "0.0.0.0:9050" || cfg.TorBandwidthRate != "" {
+		t.Errorf("expected invalid values to fall back, got port=%q rate=%q", cfg.TorSocksPort, cfg.TorBandwidthRate)
+	}
+}
+
+func TestLoad_Bridges(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := mustLoad(t)
+	if cfg.TorUseBridges || len(cfg.TorBridges) != 0 {
+		t.Errorf("expected bridges to be off by default, got use=%v bridges=%v", cfg.TorUseBridges, cfg.TorBridges)
+	}
+	if len(cfg.TorTransportPlugins) != 2 {
+		t.Errorf("expected the default transport plugins, got %v", cfg.TorTransportPlugins)
+	}
+
+	if err := os.Setenv("TOR_BRIDGES", strings.Join([]string{
+		"obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0",
+		"  ",
+		"meek 192.0.2.2:443",
+		"obfs4 192.0.2.5",
+		"webtunnel [2001:db8::1]:443 url=https://example.com/path ver=0.0.1",
+	}, "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = mustLoad(t)
+	if !cfg.TorUseBridges {
+		t.Error("expected configured bridges to enable UseBridges")
+	}
+	if len(cfg.TorBridges) != 2 || !strings.HasPrefix(cfg.TorBridges[1], "webtunnel ") {
+		t.Errorf("expected bridges without a plugin or address to be dropped, got %q", cfg.TorBridges)
+	}
+	if bridges := cfg.Bridges(); len(bridges) != 2 || bridges[0].Label() != "obfs4 192.0.2.1:443" {
+		t.Errorf("unexpected parsed bridges: %+v", bridges)
+	}
+
+	if err := os.Setenv("TOR_USE_BRIDGES", "false"); err != nil {
+		t.Fatal(err)
+	}
+	cfg = mustLoad(t)
+	if cfg.TorUseBridges || len(cfg.TorBridges) != 2 || cfg.Bridges() != nil {
+		t.Errorf("expected bridges to be kept but unused, got use=%v bridges=%d", cfg.TorUseBridges, len(cfg.TorBridges))
+	}
+
+	clearEnv()
+	for key, value := range map[string]string{
+		"TOR_USE_BRIDGES":       "true",
+		"TOR_TRANSPORT_PLUGINS": "meek exec /usr/bin/meek-client\nbroken",
+	} {
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg = mustLoad(t)
+	if cfg.TorUseBridges {
+		t.Error("expected UseBridges without bridges to be disabled")
+	}
+	if !slices.Equal(cfg.TorTransportPlugins, []string{"meek exec /usr/bin/meek-client"}) {
+		t.Errorf("expected invalid plugin lines to be dropped, got %v", cfg.TorTransportPlugins)
+	}
+}
+
+func TestLoad_SocksListeners(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := mustLoad(t)
+	if len(cfg.TorSocksListeners) != 0 {
+		t.Errorf("expected no named SOCKS listeners by default, got %v", cfg.TorSocksListeners)
+	}
+	if listeners := cfg.SocksListeners(); len(listeners) != 1 || listeners[0].Name != DefaultSocksListener || listeners[0].Address != "0.0.0.0:9050" {
+		t.Errorf("expected only the default listener, got %+v", listeners)
+	}
+
+	if err := os.Setenv("TOR_SOCKS_LISTENERS", strings.Join([]string{
+		"Sonarr=0.0.0.0:9060 IsolateDestAddr IsolateSOCKSAuth",
+		"radarr=9061",
+		"default=9062",
+		"prowlarr=9063 IsolateEverything",
+		"lidarr",
+		"readarr=127.0.0.1:9050",
+		"radarr=9064",
+	}, "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = mustLoad(t)
+	want := []SocksListener{
+		{Name: "sonarr", Address: "0.0.0.0:9060", Flags: []string{"IsolateDestAddr", "IsolateSOCKSAuth"}},
+		{Name: "radarr", Address: "9061", Flags: []string{}},
+	}
+	if !reflect.DeepEqual(cfg.TorSocksListeners, want) {
+		t.Errorf("expected invalid, reserved and duplicate listeners to be dropped, got %+v", cfg.TorSocksListeners)
+	}
+	if listeners := cfg.SocksListeners(); len(listeners) != 3 || listeners[1].Name != "sonarr" {
+		t.Errorf("expected the default listener first, got %+v", listeners)
+	}
+	if got := cfg.TorSocksListeners[0].String(); got != "sonarr=0.0.0.0:9060 IsolateDestAddr IsolateSOCKSAuth" {
+		t.Errorf("unexpected listener string %q", got)
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantCert       string
+		wantClientCA   string
+		wantClientAuth string
+	}{
+		{
+			name:           "disabled by default",
+			env:            map[string]string{},
+			wantClientAuth: "none",
+		},
+		{
+			name:           "cert without key disables TLS",
+			env:            map[string]string{"HEALTH_TLS_CERT_FILE": "/tls/cert.pem"},
+			wantClientAuth: "none",
+		},
+		{
+			name: "client CA defaults to require",
+			env: map[string]string{
+				"HEALTH_TLS_CERT_FILE":      "/tls/cert.pem",
+				"HEALTH_TLS_KEY_FILE":       "/tls/key.pem",
+				"HEALTH_TLS_CLIENT_CA_FILE": "/tls/ca.pem",
+			},
+			wantCert:       "/tls/cert.pem",
+			wantClientCA:   "/tls/ca.pem",
+			wantClientAuth: "require",
+		},
+		{
+			name: "optional client auth",
+			env: map[string]string{
+				"HEALTH_TLS_CERT_FILE":      "/tls/cert.pem",
+				"HEALTH_TLS_KEY_FILE":       "/tls/key.pem",
+				"HEALTH_TLS_CLIENT_CA_FILE": "/tls/ca.pem",
+				"HEALTH_TLS_CLIENT_AUTH":    "OPTIONAL",
+			},
+			wantCert:       "/tls/cert.pem",
+			wantClientCA:   "/tls/ca.pem",
+			wantClientAuth: "optional",
+		},
+		{
+			name: "invalid client auth falls back to require",
+			env: map[string]string{
+				"HEALTH_TLS_CERT_FILE":      "/tls/cert.pem",
+				"HEALTH_TLS_KEY_FILE":       "/tls/key.pem",
+				"HEALTH_TLS_CLIENT_CA_FILE": "/tls/ca.pem",
+				"HEALTH_TLS_CLIENT_AUTH":    "sometimes",
+			},
+			wantCert:       "/tls/cert.pem",
+			wantClientCA:   "/tls/ca.pem",
+			wantClientAuth: "require",
+		},
+		{
+			name: "client auth without CA is disabled",
+			env: map[string]string{
+				"HEALTH_TLS_CERT_FILE":   "/tls/cert.pem",
+				"HEALTH_TLS_KEY_FILE":    "/tls/key.pem",
+				"HEALTH_TLS_CLIENT_AUTH": "require",
+			},
+			wantCert:       "/tls/cert.pem",
+			wantClientAuth: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv()
+			defer clearEnv()
+			for k, v := range tt.env {
+				if err := os.Setenv(k, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg := mustLoad(t)
+			if cfg.TLSCertFile != tt.wantCert {
+				t.Errorf("expected TLSCertFile %q, got %q", tt.wantCert, cfg.TLSCertFile)
+			}
+			if cfg.TLSClientCAFile != tt.wantClientCA {
+				t.Errorf("expected TLSClientCAFile %q, got %q", tt.wantClientCA, cfg.TLSClientCAFile)
+			}
+			if cfg.TLSClientAuth != tt.wantClientAuth {
+				t.Errorf("expected TLSClientAuth %q, got %q", tt.wantClientAuth, cfg.TLSClientAuth)
+			}
+			if cfg.TLSReloadInterval != 30*time.Second {
+				t.Errorf("expected TLSReloadInterval to be 30s, got %v", cfg.TLSReloadInterval)
+			}
+		})
+	}
+}
+
 func TestParseEndpoints_EmptyString(t *testing.T) {
 	result := parseEndpoints("")
 	if result != nil {
@@ -201,6 +683,24 @@ func TestParseEndpoints_WithSpaces(t *testing.T) {
 	}
 }
 
+func TestParseEndpoints_Newlines(t *testing.T) {
+	result := parseEndpoints("https://a.com\nhttps://b.com,https://c.com\n")
+	expected := []string{"https://a.com", "https://b.com", "https://c.com"}
+	if !slices.Equal(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	result := parseLines(" a,b \n\n c\n")
+	if !slices.Equal(result, []string{"a,b", "c"}) {
+		t.Errorf("expected lines split on newlines only, got %q", result)
+	}
+	if parseLines("") != nil {
+		t.Error("expected nil for empty string")
+	}
+}
+
 func TestParseEndpoints_WithEmptyParts(t *testing.T) {
 	result := parseEndpoints("https://a.com,,,https://b.com,")
 	if len(result) != 2 {
@@ -246,17 +746,122 @@ func TestDefaultWebhookEvents(t *testing.T) {
 	}
 }
 
+func mustLoad(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return cfg
+}
+
 func clearEnv() {
+	_ = os.Unsetenv("CONFIG_FILE")
+	_ = os.Unsetenv("CONFIG_STRICT")
 	_ = os.Unsetenv("TOR_CONTROL_ADDRESS")
 	_ = os.Unsetenv("TOR_CONTROL_PASSWORD")
+	_ = os.Unsetenv("TOR_CONTROL_AUTH")
+	_ = os.Unsetenv("TOR_CONTROL_COOKIE_FILE")
+	_ = os.Unsetenv("TOR_DATA_DIRECTORY")
+	_ = os.Unsetenv("TOR_EXIT_NODES")
+	_ = os.Unsetenv("TOR_BINARY")
+	_ = os.Unsetenv("TORRC_FILE")
+	_ = os.Unsetenv("TORRC_GENERATE")
+	_ = os.Unsetenv("TOR_SOCKS_PORT")
+	_ = os.Unsetenv("TOR_SOCKS_LISTENERS")
+	_ = os.Unsetenv("TOR_SOCKS_ISOLATION")
+	_ = os.Unsetenv("TOR_EXCLUDE_NODES")
+	_ = os.Unsetenv("TOR_EXCLUDE_EXIT_NODES")
+	_ = os.Unsetenv("TOR_NUM_ENTRY_GUARDS")
+	_ = os.Unsetenv("TOR_BANDWIDTH_RATE")
+	_ = os.Unsetenv("TOR_BANDWIDTH_BURST")
+	_ = os.Unsetenv("TOR_ACCOUNTING_MAX")
+	_ = os.Unsetenv("TOR_ACCOUNTING_START")
+	_ = os.Unsetenv("TOR_USE_BRIDGES")
+	_ = os.Unsetenv("TOR_BRIDGES")
+	_ = os.Unsetenv("TOR_TRANSPORT_PLUGINS")
 	_ = os.Unsetenv("HEALTH_PORT")
+	_ = os.Unsetenv("HEALTH_PROBES_ADDRESS")
+	_ = os.Unsetenv("HEALTH_METRICS_ADDRESS")
+	_ = os.Unsetenv("HEALTH_ADMIN_ADDRESS")
 	_ = os.Unsetenv("HEALTH_EXTERNAL_TIMEOUT")
 	_ = os.Unsetenv("HEALTH_EXTERNAL_ENDPOINTS")
+	_ = os.Unsetenv("HEALTH_ADMIN_TOKEN")
+	_ = os.Unsetenv("HEALTH_AUTH_TOKENS")
+	_ = os.Unsetenv("HEALTH_AUTH_USERS")
+	_ = os.Unsetenv("HEALTH_AUTH_FILE")
+	_ = os.Unsetenv("HEALTH_AUTH_REQUIRE_READ")
+	_ = os.Unsetenv("HEALTH_TLS_CERT_FILE")
+	_ = os.Unsetenv("HEALTH_TLS_KEY_FILE")
+	_ = os.Unsetenv("HEALTH_TLS_CLIENT_CA_FILE")
+	_ = os.Unsetenv("HEALTH_TLS_CLIENT_AUTH")
+	_ = os.Unsetenv("HEALTH_TLS_RELOAD_INTERVAL")
 	_ = os.Unsetenv("LOG_LEVEL")
+	_ = os.Unsetenv("LOG_FORMAT")
+	_ = os.Unsetenv("TOR_LOG_RELAY")
 	_ = os.Unsetenv("WEBHOOK_URL")
 	_ = os.Unsetenv("WEBHOOK_TEMPLATE")
 	_ = os.Unsetenv("WEBHOOK_EVENTS")
 	_ = os.Unsetenv("WEBHOOK_TIMEOUT")
+	_ = os.Unsetenv("CIRCUIT_RENEW_INTERVAL")
+	_ = os.Unsetenv("CIRCUIT_RENEW_JITTER")
+	_ = os.Unsetenv("CIRCUIT_RENEW_CRON")
+	_ = os.Unsetenv("AUTO_RENEW_ENABLED")
+	_ = os.Unsetenv("HEALTH_CHECK_INTERVAL")
+	_ = os.Unsetenv("HEALTH_EXTERNAL_INTERVAL")
+	_ = os.Unsetenv("TRAFFIC_HISTORY")
+	_ = os.Unsetenv("AUTO_RENEW_FAILURE_THRESHOLD")
+	_ = os.Unsetenv("AUTO_RENEW_MAX_ATTEMPTS")
+	_ = os.Unsetenv("AUTO_RENEW_RECHECK_DELAY")
 	_ = os.Unsetenv("TEST_INT")
+	_ = os.Unsetenv("TEST_BOOL")
 	_ = os.Unsetenv("TEST_DURATION")
 }
+
+func TestLoad_CircuitRenewSchedule(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("CIRCUIT_RENEW_INTERVAL", "30m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("CIRCUIT_RENEW_JITTER", "5m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("CIRCUIT_RENEW_CRON", "0 */6 * * *"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+
+	if cfg.CircuitRenewInterval != 30*time.Minute {
+		t.Errorf("expected CircuitRenewInterval to be 30m, got %v", cfg.CircuitRenewInterval)
+	}
+	if cfg.CircuitRenewJitter != 5*time.Minute {
+		t.Errorf("expected CircuitRenewJitter to be 5m, got %v", cfg.CircuitRenewJitter)
+	}
+	if cfg.CircuitRenewCron != "0 */6 * * *" {
+		t.Errorf("expected CircuitRenewCron to be '0 */6 * * *', got '%s'", cfg.CircuitRenewCron)
+	}
+}
+
+func TestLoad_InvalidCircuitRenewSchedule(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("CIRCUIT_RENEW_INTERVAL", "-1m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("CIRCUIT_RENEW_CRON", "every hour"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+
+	if cfg.CircuitRenewInterval != 0 {
+		t.Errorf("expected negative interval to be disabled, got %v", cfg.CircuitRenewInterval)
+	}
+	if cfg.CircuitRenewCron != "" {
+		t.Errorf("expected invalid cron to be disabled, got '%s'", cfg.CircuitRenewCron)
+	}
+}
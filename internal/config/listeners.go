@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// RouteGroup names a set of health server routes that can be bound to its
+// own listen address.
+type RouteGroup string
+
+const (
+	RouteGroupProbes  RouteGroup = "probes"  // /ping, /health, /ready
+	RouteGroupMetrics RouteGroup = "metrics" // /metrics
+	RouteGroupAdmin   RouteGroup = "admin"   // Diagnostics and control API
+)
+
+// Listener is an address the health server listens on and the route groups
+// served there.
+type Listener struct {
+	Address string
+	Groups  []RouteGroup
+}
+
+// Listeners returns one Listener per distinct address, comparing addresses
+// by their normalized form so ":9091" and "0.0.0.0:9091" share a listener.
+// Groups without their own address share the HEALTH_PORT listener, which is
+// always first.
+func (c *Config) Listeners() []Listener {
+	defaultAddress := ":" + c.HealthPort
+	groups := []struct {
+		group   RouteGroup
+		address string
+	}{
+		{RouteGroupProbes, c.HealthProbesAddress},
+		{RouteGroupMetrics, c.HealthMetricsAddress},
+		{RouteGroupAdmin, c.HealthAdminAddress},
+	}
+
+	listeners := []Listener{{Address: defaultAddress}}
+	for _, g := range groups {
+		address := g.address
+		if address == "" {
+			address = defaultAddress
+		}
+
+		i := 0
+		for i < len(listeners) && !sameListenAddress(listeners[i].Address, address) {
+			i++
+		}
+		if i == len(listeners) {
+			listeners = append(listeners, Listener{Address: address})
+		}
+		listeners[i].Groups = append(listeners[i].Groups, g.group)
+	}
+
+	// Drop the default listener when every group has moved elsewhere.
+	if len(listeners[0].Groups) == 0 {
+		listeners = listeners[1:]
+	}
+
+	return listeners
+}
+
+// validateListenAddress checks that address is a host:port pair with a
+// usable port. The host may be empty to listen on all interfaces.
+func validateListenAddress(address string) error {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// normalizeListenAddress returns address with the host in a canonical form:
+// empty for all interfaces and 127.0.0.1 for localhost. Addresses that do
+// not parse are returned unchanged.
+func normalizeListenAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	switch host {
+	case "0.0.0.0", "::":
+		host = ""
+	case "localhost":
+		host = "127.0.0.1"
+	}
+	if n, err := strconv.Atoi(port); err == nil {
+		port = strconv.Itoa(n)
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// sameListenAddress reports whether a and b bind the same host and port.
+func sameListenAddress(a, b string) bool {
+	return normalizeListenAddress(a) == normalizeListenAddress(b)
+}
+
+// conflictingListenAddress returns the entry in claimed that uses the same
+// port as address on a different host, which would fail to bind.
+func conflictingListenAddress(address string, claimed []string) (string, bool) {
+	_, port, err := net.SplitHostPort(normalizeListenAddress(address))
+	if err != nil {
+		return "", false
+	}
+	for _, other := range claimed {
+		_, otherPort, err := net.SplitHostPort(normalizeListenAddress(other))
+		if err == nil && otherPort == port && !sameListenAddress(address, other) {
+			return other, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_Listeners(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []Listener
+	}{
+		{
+			name: "shared listener",
+			cfg:  Config{HealthPort: "9091"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes, RouteGroupMetrics, RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "admin on loopback",
+			cfg:  Config{HealthPort: "9091", HealthAdminAddress: "127.0.0.1:9092"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes, RouteGroupMetrics}},
+				{Address: "127.0.0.1:9092", Groups: []RouteGroup{RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "metrics and admin share an address",
+			cfg:  Config{HealthPort: "9091", HealthMetricsAddress: "127.0.0.1:9092", HealthAdminAddress: "127.0.0.1:9092"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes}},
+				{Address: "127.0.0.1:9092", Groups: []RouteGroup{RouteGroupMetrics, RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "explicit default address",
+			cfg:  Config{HealthPort: "9091", HealthProbesAddress: ":9091", HealthAdminAddress: "127.0.0.1:9092"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes, RouteGroupMetrics}},
+				{Address: "127.0.0.1:9092", Groups: []RouteGroup{RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "wildcard host spelled out",
+			cfg:  Config{HealthPort: "9091", HealthProbesAddress: "0.0.0.0:9091", HealthMetricsAddress: "[::]:9091"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes, RouteGroupMetrics, RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "localhost and loopback share a listener",
+			cfg:  Config{HealthPort: "9091", HealthMetricsAddress: "localhost:9092", HealthAdminAddress: "127.0.0.1:9092"},
+			expected: []Listener{
+				{Address: ":9091", Groups: []RouteGroup{RouteGroupProbes}},
+				{Address: "localhost:9092", Groups: []RouteGroup{RouteGroupMetrics, RouteGroupAdmin}},
+			},
+		},
+		{
+			name: "every group moved",
+			cfg: Config{
+				HealthPort:           "9091",
+				HealthProbesAddress:  ":8080",
+				HealthMetricsAddress: ":9100",
+				HealthAdminAddress:   "127.0.0.1:9092",
+			},
+			expected: []Listener{
+				{Address: ":8080", Groups: []RouteGroup{RouteGroupProbes}},
+				{Address: ":9100", Groups: []RouteGroup{RouteGroupMetrics}},
+				{Address: "127.0.0.1:9092", Groups: []RouteGroup{RouteGroupAdmin}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listeners := tt.cfg.Listeners()
+			if !reflect.DeepEqual(listeners, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, listeners)
+			}
+		})
+	}
+}
+
+func TestLoad_ListenAddresses(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("HEALTH_ADMIN_ADDRESS", "127.0.0.1:9092"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_METRICS_ADDRESS", "9100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_PROBES_ADDRESS", ":99999"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+
+	if cfg.HealthAdminAddress != "127.0.0.1:9092" {
+		t.Errorf("expected HealthAdminAddress to be 127.0.0.1:9092, got %q", cfg.HealthAdminAddress)
+	}
+	if cfg.HealthMetricsAddress != "" {
+		t.Errorf("expected address without a port to be ignored, got %q", cfg.HealthMetricsAddress)
+	}
+	if cfg.HealthProbesAddress != "" {
+		t.Errorf("expected out of range port to be ignored, got %q", cfg.HealthProbesAddress)
+	}
+}
+
+func TestConflictingListenAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		claimed  []string
+		conflict string
+	}{
+		{name: "distinct ports", address: "127.0.0.1:9092", claimed: []string{":9091"}},
+		{name: "same wildcard", address: "0.0.0.0:9091", claimed: []string{":9091"}},
+		{name: "localhost and loopback", address: "localhost:9092", claimed: []string{":9091", "127.0.0.1:9092"}},
+		{name: "loopback on the wildcard port", address: "127.0.0.1:9091", claimed: []string{":9091"}, conflict: ":9091"},
+		{name: "two hosts on one port", address: "10.0.0.1:9092", claimed: []string{":9091", "127.0.0.1:9092"}, conflict: "127.0.0.1:9092"},
+		{name: "leading zero port", address: "127.0.0.1:09091", claimed: []string{":9091"}, conflict: ":9091"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict, ok := conflictingListenAddress(tt.address, tt.claimed)
+			if ok != (tt.conflict != "") || conflict != tt.conflict {
+				t.Errorf("expected conflict %q, got %q (%v)", tt.conflict, conflict, ok)
+			}
+		})
+	}
+}
+
+func TestLoad_ListenAddressPortConflict(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("HEALTH_METRICS_ADDRESS", "127.0.0.1:9092"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_ADMIN_ADDRESS", "10.0.0.1:9092"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("HEALTH_PROBES_ADDRESS", "localhost:9091"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+
+	if cfg.HealthMetricsAddress != "127.0.0.1:9092" {
+		t.Errorf("expected the first address on a port to be kept, got %q", cfg.HealthMetricsAddress)
+	}
+	if cfg.HealthAdminAddress != "" {
+		t.Errorf("expected a second host on the same port to be ignored, got %q", cfg.HealthAdminAddress)
+	}
+	if cfg.HealthProbesAddress != "" {
+		t.Errorf("expected an address on the HEALTH_PORT port to be ignored, got %q", cfg.HealthProbesAddress)
+	}
+
+	if err := os.Setenv("CONFIG_STRICT", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("expected strict mode to reject conflicting listen addresses")
+	}
+}
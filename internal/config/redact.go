@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+const redacted = "<redacted>"
+
+// MarshalRedacted renders the configuration as YAML keyed like the config
+// file, with passwords, tokens, webhook URLs and bridge lines replaced by a placeholder.
+func (c *Config) MarshalRedacted() ([]byte, error) {
+	var out yaml.MapSlice
+
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, options, _ := strings.Cut(t.Field(i).Tag.Get("config"), ",")
+		if key == "" {
+			continue
+		}
+		secret := options == "secret"
+
+		var value interface{}
+		switch field := v.Field(i).Interface().(type) {
+		case string:
+			value = field
+			if secret && field != "" {
+				value = redacted
+			}
+		case []string:
+			value = field
+			if secret {
+				// Keep the count visible so an empty list stays distinguishable
+				items := make([]string, len(field))
+				for j := range items {
+					items[j] = redacted
+				}
+				value = items
+			}
+		case time.Duration:
+			value = field.String()
+		case []SocksListener:
+			listeners := make([]string, len(field))
+			for j, listener := range field {
+				listeners[j] = listener.String()
+			}
+			value = listeners
+		case []Credential:
+			creds := make([]string, len(field))
+			for j, cred := range field {
+				creds[j] = cred.redacted()
+			}
+			value = creds
+		default:
+			value = field
+		}
+		out = append(out, yaml.MapItem{Key: key, Value: value})
+	}
+
+	return yaml.Marshal(out)
+}
+
+// redacted describes the credential without its secret.
+func (c Credential) redacted() string {
+	if c.Username != "" {
+		return fmt.Sprintf("%s basic %s:%s", c.Scope, c.Username, redacted)
+	}
+	return fmt.Sprintf("%s token %s", c.Scope, redacted)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+// DefaultSocksListener names the listener set by TOR_SOCKS_PORT and
+// TOR_SOCKS_ISOLATION.
+const DefaultSocksListener = "default"
+
+// validListenerName keeps listener names usable as metric labels and query
+// parameters.
+var validListenerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// SocksListener is a named SOCKS port, so each application can use its own
+// isolated circuits and be monitored separately.
+type SocksListener struct {
+	Name    string
+	Address string   // host:port or port
+	Flags   []string // SocksPort isolation flags
+}
+
+// String formats the listener as a TOR_SOCKS_LISTENERS entry.
+func (s SocksListener) String() string {
+	return s.Name + "=" + strings.Join(append([]string{s.Address}, s.Flags...), " ")
+}
+
+// SocksPort returns the torrc SocksPort for the listener.
+func (s SocksListener) SocksPort() torrc.SocksPort {
+	return torrc.SocksPort{Address: s.Address, Flags: s.Flags}
+}
+
+// parseSocksListener parses "name=address [flag ...]".
+func parseSocksListener(entry string) (SocksListener, error) {
+	name, rest, found := strings.Cut(entry, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	fields := strings.Fields(rest)
+	if !found || len(fields) == 0 {
+		return SocksListener{}, errors.New("expected name=address [flags]")
+	}
+	if !validListenerName.MatchString(name) {
+		return SocksListener{}, fmt.Errorf("invalid name %q: use lowercase letters, digits, '-' or '_'", name)
+	}
+	if name == DefaultSocksListener {
+		return SocksListener{}, fmt.Errorf("name %q is reserved for TOR_SOCKS_PORT", name)
+	}
+	if err := torrc.ValidateAddress(fields[0]); err != nil {
+		return SocksListener{}, fmt.Errorf("invalid address %q: %v", fields[0], err)
+	}
+	for _, flag := range fields[1:] {
+		if !torrc.ValidFlag(flag) {
+			return SocksListener{}, fmt.Errorf("invalid isolation flag %q (valid options: %s, SessionGroup=N)",
+				flag, strings.Join(torrc.IsolationFlags, ", "))
+		}
+	}
+
+	return SocksListener{Name: name, Address: fields[0], Flags: fields[1:]}, nil
+}
+
+// loadSocksListeners parses TOR_SOCKS_LISTENERS. Entries that are invalid,
+// or reuse a name or a port, are logged and skipped.
+func (l *loader) loadSocksListeners(cfg *Config) {
+	names := map[string]bool{DefaultSocksListener: true}
+	ports := map[string]bool{torrc.AddressPort(cfg.TorSocksPort): true}
+
+	for _, entry := range parseEndpoints(l.getString("TOR_SOCKS_LISTENERS", "")) {
+		listener, err := parseSocksListener(entry)
+		if err != nil {
+			l.invalid("Invalid TOR_SOCKS_LISTENERS entry", "ignoring", "entry", entry, "error", err)
+			continue
+		}
+		if names[listener.Name] {
+			l.invalid("Duplicate SOCKS listener name", "ignoring", "listener", listener.Name)
+			continue
+		}
+		if port := torrc.AddressPort(listener.Address); ports[port] {
+			l.invalid("SOCKS listener port already in use", "ignoring",
+				"listener", listener.Name,
+				"port", port,
+			)
+			continue
+		}
+
+		names[listener.Name] = true
+		ports[torrc.AddressPort(listener.Address)] = true
+		cfg.TorSocksListeners = append(cfg.TorSocksListeners, listener)
+	}
+}
+
+// SocksListeners returns the default listener followed by the named ones.
+func (c *Config) SocksListeners() []SocksListener {
+	listeners := []SocksListener{{
+		Name:    DefaultSocksListener,
+		Address: c.TorSocksPort,
+		Flags:   c.TorSocksIsolation,
+	}}
+	return append(listeners, c.TorSocksListeners...)
+}
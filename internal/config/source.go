@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// loader resolves each setting from the environment, then the config file,
+// then its default, and records every invalid value it falls back from.
+type loader struct {
+	file     map[string]string // Config file values keyed by variable name
+	used     map[string]bool   // Variable names looked up so far
+	strict   bool              // Collect problems without logging them
+	problems []error
+}
+
+func newLoader(file map[string]string, strict bool) *loader {
+	return &loader{file: file, used: make(map[string]bool), strict: strict}
+}
+
+// readConfigFile parses a YAML config file. Keys are environment variable
+// names, matched case-insensitively; lists are joined with newlines, which
+// every list setting accepts as a separator.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		str, err := fileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config file key %q: %w", key, err)
+		}
+		values[strings.ToUpper(key)] = str
+	}
+	return values, nil
+}
+
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			if _, isList := item.([]interface{}); isList {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, "\n"), nil
+	default:
+		return "", fmt.Errorf("expected a scalar or a list, got %T", value)
+	}
+}
+
+// lookup returns the raw value of key, preferring the environment.
+func (l *loader) lookup(key string) string {
+	l.used[key] = true
+	if value := os.Getenv(key); strings.TrimSpace(value) != "" {
+		return value
+	}
+	return l.file[key]
+}
+
+// unknownKeys returns config file keys that no setting looked up.
+func (l *loader) unknownKeys() []string {
+	var unknown []string
+	for key := range l.file {
+		if !l.used[key] {
+			unknown = append(unknown, strings.ToLower(key))
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// invalid records a configuration problem. Outside strict mode it is also
+// logged together with the fallback that was applied.
+func (l *loader) invalid(problem, fallback string, args ...any) {
+	var details []string
+	for i := 0; i+1 < len(args); i += 2 {
+		details = append(details, fmt.Sprintf("%v=%v", args[i], args[i+1]))
+	}
+	if len(details) > 0 {
+		l.problems = append(l.problems, fmt.Errorf("%s (%s)", problem, strings.Join(details, ", ")))
+	} else {
+		l.problems = append(l.problems, fmt.Errorf("%s", problem))
+	}
+
+	if !l.strict {
+		slog.Warn(problem+", "+fallback, args...)
+	}
+}
+
+func (l *loader) getString(key, defaultValue string) string {
+	value := strings.TrimSpace(l.lookup(key))
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) getInt(key string, defaultValue int) int {
+	valueStr := l.getString(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		l.invalid("Invalid configuration value", "using default",
+			"key", key,
+			"value", valueStr,
+			"default", defaultValue,
+			"error", err,
+		)
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) getBool(key string, defaultValue bool) bool {
+	valueStr := l.getString(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		l.invalid("Invalid boolean configuration value", "using default",
+			"key", key,
+			"value", valueStr,
+			"default", defaultValue,
+			"error", err,
+		)
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) getDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := l.getString(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		l.invalid("Invalid duration configuration value", "using default",
+			"key", key,
+			"value", valueStr,
+			"default", defaultValue,
+			"error", err,
+		)
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "torarr.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	path := writeConfigFile(t, `
+health_port: 9191
+HEALTH_CHECK_INTERVAL: 30s
+health_external_endpoints:
+  - https://check.torproject.org/api/ip
+  - https://ipinfo.io/json
+auto_renew_enabled: true
+log_level: debug
+`)
+	if err := os.Setenv("CONFIG_FILE", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("LOG_LEVEL", "warn"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+
+	if cfg.HealthPort != "9191" {
+		t.Errorf("expected HealthPort from file to be 9191, got %q", cfg.HealthPort)
+	}
+	if cfg.HealthCheckInterval != 30*time.Second {
+		t.Errorf("expected HealthCheckInterval from file to be 30s, got %v", cfg.HealthCheckInterval)
+	}
+	expected := []string{"https://check.torproject.org/api/ip", "https://ipinfo.io/json"}
+	if !reflect.DeepEqual(cfg.HealthExternalEndpoints, expected) {
+		t.Errorf("expected HealthExternalEndpoints %v, got %v", expected, cfg.HealthExternalEndpoints)
+	}
+	if !cfg.AutoRenewEnabled {
+		t.Error("expected AutoRenewEnabled from file to be true")
+	}
+	if cfg.LogLevel != "WARN" {
+		t.Errorf("expected LOG_LEVEL env to override file, got %q", cfg.LogLevel)
+	}
+	if cfg.TorControlAddress != "127.0.0.1:9051" {
+		t.Errorf("expected unset TorControlAddress to keep its default, got %q", cfg.TorControlAddress)
+	}
+}
+
+func TestLoad_ConfigFileBridges(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	// Snowflake bridge lines contain commas, so file lists must not be
+	// joined into a comma-separated string.
+	path := writeConfigFile(t, `
+tor_bridges:
+  - snowflake 192.0.2.3:80 2B280B23E1107BB62ABFC40DDCC8824814F80A72 fronts=a.example,b.example ice=stun:a.example:3478,stun:b.example:3478
+  - obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0
+webhook_events:
+  - circuit_renewed
+  - health_changed
+`)
+	if err := os.Setenv("CONFIG_FILE", path); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := mustLoad(t)
+	if len(cfg.TorBridges) != 2 || !strings.Contains(cfg.TorBridges[0], "ice=stun:a.example:3478,stun:b.example:3478") {
+		t.Errorf("expected two intact bridge lines, got %q", cfg.TorBridges)
+	}
+	if !cfg.TorUseBridges {
+		t.Error("expected bridges from the file to be used")
+	}
+	if !reflect.DeepEqual(cfg.WebhookEvents, []string{"circuit_renewed", "health_changed"}) {
+		t.Errorf("expected file list to split into webhook events, got %v", cfg.WebhookEvents)
+	}
+}
+
+func TestLoad_ConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "invalid yaml", content: "health_port: [9091", wantErr: "failed to parse config file"},
+		{name: "duplicate key", content: "health_port: 1\nhealth_port: 2\n", wantErr: "failed to parse config file"},
+		{name: "nested map", content: "health:\n  port: 9091\n", wantErr: `config file key "health"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv()
+			defer clearEnv()
+
+			if err := os.Setenv("CONFIG_FILE", writeConfigFile(t, tt.content)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		clearEnv()
+		defer clearEnv()
+
+		if err := os.Setenv("CONFIG_FILE", "/nonexistent/torarr.yml"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+			t.Errorf("expected read error, got %v", err)
+		}
+	})
+}
+
+func TestLoad_StrictMode(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	path := writeConfigFile(t, `
+config_strict: true
+health_check_interval: soon
+health_prot: 9091
+`)
+	if err := os.Setenv("CONFIG_FILE", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("TOR_CONTROL_AUTH", "kerberos"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if cfg != nil {
+		t.Errorf("expected no config in strict mode, got %+v", cfg)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(validationErr.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", validationErr.Problems)
+	}
+	for _, want := range []string{"key=HEALTH_CHECK_INTERVAL", "Invalid Tor control auth method", "key=health_prot"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+
+	// The same configuration only warns outside strict mode.
+	if err := os.Setenv("CONFIG_STRICT", "false"); err != nil {
+		t.Fatal(err)
+	}
+	cfg = mustLoad(t)
+	if cfg.HealthCheckInterval != 15*time.Second {
+		t.Errorf("expected invalid HealthCheckInterval to fall back to 15s, got %v", cfg.HealthCheckInterval)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	path := writeConfigFile(t, "webhook_timeout: soon\nauto_renew_max_attempts: 0\n")
+
+	cfg, problems, err := Inspect(path)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("expected 2 problems, got %v", problems)
+	}
+	if cfg.WebhookTimeout != 10*time.Second || cfg.AutoRenewMaxAttempts != 3 {
+		t.Errorf("expected defaults to be applied, got timeout %v and max attempts %d", cfg.WebhookTimeout, cfg.AutoRenewMaxAttempts)
+	}
+}
+
+func TestConfig_MarshalRedacted(t *testing.T) {
+	cfg := &Config{
+		TorControlPassword:  "hunter2",
+		HealthPort:          "9091",
+		HealthCheckInterval: 15 * time.Second,
+		WebhookURL:          "https://discord.com/api/webhooks/123/secret-token",
+		WebhookEvents:       []string{"circuit_renewed"},
+		AuthCredentials: []Credential{
+			{Scope: ScopeControl, Secret: "abc123"},
+			{Scope: ScopeRead, Username: "grafana", Secret: "p@ss"},
+		},
+		TorBridges:        []string{"obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=private-cert iat-mode=0"},
+		TorSocksListeners: []SocksListener{{Name: "sonarr", Address: "9060", Flags: []string{"IsolateDestAddr"}}},
+		AutoRenewEnabled:  true,
+	}
+
+	out, err := cfg.MarshalRedacted()
+	if err != nil {
+		t.Fatalf("MarshalRedacted() error = %v", err)
+	}
+	dump := string(out)
+
+	for _, secret := range []string{"hunter2", "secret-token", "abc123", "p@ss", "private-cert"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("expected %q to be redacted:\n%s", secret, dump)
+		}
+	}
+	for _, want := range []string{
+		"tor_control_password: <redacted>",
+		`health_port: "9091"`,
+		"health_check_interval: 15s",
+		"webhook_url: <redacted>",
+		"- circuit_renewed",
+		"- control token <redacted>",
+		"- read basic grafana:<redacted>",
+		"tor_bridges:\n- <redacted>",
+		"tor_socks_listeners:\n- sonarr=9060 IsolateDestAddr",
+		"auto_renew_enabled: true",
+		`tor_control_cookie_file: ""`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, dump)
+		}
+	}
+}
+
+func TestLoadStrict(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if err := os.Setenv("LOG_LEVEL", "verbose"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg := mustLoad(t); cfg.LogLevel != "INFO" {
+		t.Errorf("expected invalid LOG_LEVEL to fall back to INFO, got %q", cfg.LogLevel)
+	}
+
+	_, err := LoadStrict()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), "Invalid log level") {
+		t.Errorf("expected LoadStrict to reject invalid LOG_LEVEL, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/config"
+)
+
+// authorize guards a route with the given scope. Read routes stay open unless
+// HEALTH_AUTH_REQUIRE_READ is set; control routes are disabled entirely until
+// a control credential is configured.
+func (h *Handler) authorize(scope config.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var credentials []config.Credential
+		requireRead := false
+		if cfg := h.currentConfig(); cfg != nil {
+			credentials = cfg.AuthCredentials
+			requireRead = cfg.AuthRequireRead
+		}
+
+		if scope == config.ScopeRead && !requireRead {
+			next(w, r)
+			return
+		}
+
+		if !hasScope(credentials, scope) {
+			writeAuthError(w, http.StatusForbidden, "no credentials configured for the "+string(scope)+" scope")
+			return
+		}
+
+		cred, ok := authenticate(credentials, r)
+		if !ok {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="torarr"`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="torarr"`)
+			writeAuthError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		if !cred.Scope.Allows(scope) {
+			writeAuthError(w, http.StatusForbidden, "credential does not grant the "+string(scope)+" scope")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// authenticate matches a bearer token or basic auth username and password
+// against the configured credentials. Every credential is compared so the
+// response time does not reveal which one matched.
+func authenticate(credentials []config.Credential, r *http.Request) (config.Credential, bool) {
+	token, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	username, password, isBasic := r.BasicAuth()
+	if !isBearer && !isBasic {
+		return config.Credential{}, false
+	}
+
+	var match config.Credential
+	found := false
+	for _, cred := range credentials {
+		var ok bool
+		if cred.Username == "" {
+			ok = isBearer && secretEqual(token, cred.Secret)
+		} else {
+			ok = isBasic && secretEqual(username, cred.Username) && secretEqual(password, cred.Secret)
+		}
+		// Prefer the broadest scope if the same secret is listed twice
+		if ok && (!found || cred.Scope == config.ScopeControl) {
+			match = cred
+			found = true
+		}
+	}
+
+	return match, found
+}
+
+func hasScope(credentials []config.Credential, scope config.Scope) bool {
+	for _, cred := range credentials {
+		if cred.Scope.Allows(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func secretEqual(provided, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
+
+func writeAuthError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		slog.Error("Failed to encode auth error response", "error", err)
+	}
+}
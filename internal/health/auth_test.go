@@ -0,0 +1,71 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/config"
+)
+
+func TestAuthorize(t *testing.T) {
+	credentials := []config.Credential{
+		{Scope: config.ScopeControl, Secret: "control-token"},
+		{Scope: config.ScopeRead, Secret: "read-token"},
+		{Scope: config.ScopeRead, Username: "grafana", Secret: "grafana-pass"},
+	}
+
+	tests := []struct {
+		name        string
+		credentials []config.Credential
+		requireRead bool
+		scope       config.Scope
+		bearer      string
+		username    string
+		password    string
+		wantStatus  int
+	}{
+		{name: "Read open by default", credentials: credentials, scope: config.ScopeRead, wantStatus: http.StatusOK},
+		{name: "Read requires credential", credentials: credentials, requireRead: true, scope: config.ScopeRead, wantStatus: http.StatusUnauthorized},
+		{name: "Read with read token", credentials: credentials, requireRead: true, scope: config.ScopeRead, bearer: "read-token", wantStatus: http.StatusOK},
+		{name: "Read with control token", credentials: credentials, requireRead: true, scope: config.ScopeRead, bearer: "control-token", wantStatus: http.StatusOK},
+		{name: "Read with basic auth", credentials: credentials, requireRead: true, scope: config.ScopeRead, username: "grafana", password: "grafana-pass", wantStatus: http.StatusOK},
+		{name: "Basic auth wrong password", credentials: credentials, requireRead: true, scope: config.ScopeRead, username: "grafana", password: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "Control disabled without credentials", scope: config.ScopeControl, bearer: "anything", wantStatus: http.StatusForbidden},
+		{name: "Control missing header", credentials: credentials, scope: config.ScopeControl, wantStatus: http.StatusUnauthorized},
+		{name: "Control wrong token", credentials: credentials, scope: config.ScopeControl, bearer: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "Control with read token", credentials: credentials, scope: config.ScopeControl, bearer: "read-token", wantStatus: http.StatusForbidden},
+		{name: "Control with control token", credentials: credentials, scope: config.ScopeControl, bearer: "control-token", wantStatus: http.StatusOK},
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &Handler{config: &config.Config{
+				AuthCredentials: tt.credentials,
+				AuthRequireRead: tt.requireRead,
+			}}
+
+			req := httptest.NewRequest(http.MethodGet, "/renew", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			w := httptest.NewRecorder()
+
+			handler.authorize(tt.scope, next)(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && len(w.Header().Values("WWW-Authenticate")) != 2 {
+				t.Errorf("expected Bearer and Basic challenges, got %v", w.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
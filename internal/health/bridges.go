@@ -0,0 +1,158 @@
+package health
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+// BridgeStatus reports whether Tor can reach a configured bridge, combining
+// its entry guard status with the latest bootstrap warning naming it.
+type BridgeStatus struct {
+	Bridge      string     `json:"bridge"`
+	Transport   string     `json:"transport,omitempty"`
+	Address     string     `json:"address"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	Status      string     `json:"status"` // Entry guard status, or "unknown" before Tor has tried it
+	Reachable   bool       `json:"reachable"`
+	Warning     string     `json:"warning,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	WarnedAt    *time.Time `json:"warned_at,omitempty"`
+}
+
+// bridgeWarning is the latest bootstrap warning Tor reported for a bridge.
+type bridgeWarning struct {
+	bridge  torrc.Bridge
+	warning string
+	reason  string
+	at      time.Time
+}
+
+// configuredBridges returns the bridges Tor is configured to use, if any.
+func (h *Handler) configuredBridges() []torrc.Bridge {
+	cfg := h.currentConfig()
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Bridges()
+}
+
+// recordBootstrapWarning remembers a STATUS_CLIENT WARN BOOTSTRAP event if
+// its HOSTADDR or HOSTID names a configured bridge.
+func (h *Handler) recordBootstrapWarning(status tor.StatusEvent) {
+	hostID := strings.TrimPrefix(status.Args["HOSTID"], "$")
+	hostID, _, _ = strings.Cut(hostID, "~")
+	hostID, _, _ = strings.Cut(hostID, "=")
+	hostAddr := status.Args["HOSTADDR"]
+
+	for _, bridge := range h.configuredBridges() {
+		if bridge.Address != hostAddr && (bridge.Fingerprint == "" || !strings.EqualFold(bridge.Fingerprint, hostID)) {
+			continue
+		}
+
+		warning := bridgeWarning{
+			bridge:  bridge,
+			warning: status.Args["WARNING"],
+			reason:  status.Args["REASON"],
+			at:      time.Now(),
+		}
+		h.bridgeMu.Lock()
+		if h.bridgeWarnings == nil {
+			h.bridgeWarnings = make(map[string]bridgeWarning)
+		}
+		h.bridgeWarnings[bridge.Address] = warning
+		h.lastBridgeWarning = &warning
+		h.bridgeMu.Unlock()
+
+		slog.Warn("Tor cannot reach bridge",
+			"bridge", bridge.Label(),
+			"warning", warning.warning,
+			"reason", warning.reason,
+			"count", status.Args["COUNT"],
+		)
+		return
+	}
+}
+
+// clearBridgeWarning forgets the warning attached to bootstrap failures once
+// Tor has bootstrapped. Per-bridge warnings stay visible on /status.
+func (h *Handler) clearBridgeWarning() {
+	h.bridgeMu.Lock()
+	h.lastBridgeWarning = nil
+	h.bridgeMu.Unlock()
+}
+
+// bridgeStatuses reports each bridge's reachability. Bridges are matched to
+// entry guards by fingerprint, so bridges configured without one stay
+// "unknown" unless Tor has warned about them.
+func (h *Handler) bridgeStatuses(bridges []torrc.Bridge, guards []tor.EntryGuard) []BridgeStatus {
+	h.bridgeMu.Lock()
+	defer h.bridgeMu.Unlock()
+
+	statuses := make([]BridgeStatus, 0, len(bridges))
+	for _, bridge := range bridges {
+		status := BridgeStatus{
+			Bridge:      bridge.Label(),
+			Transport:   bridge.Transport,
+			Address:     bridge.Address,
+			Fingerprint: bridge.Fingerprint,
+			Status:      "unknown",
+		}
+		for _, guard := range guards {
+			if bridge.Fingerprint != "" && guard.Fingerprint == bridge.Fingerprint {
+				status.Status = guard.Status
+				status.Reachable = guard.Up()
+				break
+			}
+		}
+		if warning, ok := h.bridgeWarnings[bridge.Address]; ok {
+			status.Warning = warning.warning
+			status.Reason = warning.reason
+			status.WarnedAt = &warning.at
+			if status.Status == "unknown" {
+				status.Status = "down"
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// collectBridges queries Tor's entry guards when bridges are in use. Guard
+// status is best effort; bridges are still listed if the query fails.
+func (h *Handler) collectBridges() []BridgeStatus {
+	bridges := h.configuredBridges()
+	if len(bridges) == 0 {
+		return nil
+	}
+
+	guards, err := h.torClient.GetEntryGuards()
+	if err != nil {
+		slog.Debug("Failed to query Tor entry guards", "error", err)
+	}
+	statuses := h.bridgeStatuses(bridges, guards)
+
+	if h.metrics != nil {
+		h.metrics.observeBridges(statuses)
+	}
+	return statuses
+}
+
+// withBridgeWarning labels a bootstrap failure with the bridge named in
+// Tor's latest bootstrap warning, so alerts point at the failing bridge.
+func (h *Handler) withBridgeWarning(details notify.Details) notify.Details {
+	h.bridgeMu.Lock()
+	defer h.bridgeMu.Unlock()
+
+	if h.lastBridgeWarning != nil {
+		details.Bridge = h.lastBridgeWarning.bridge.Label()
+		if details.Reason == "" {
+			details.Reason = h.lastBridgeWarning.reason
+		}
+	}
+	return details
+}
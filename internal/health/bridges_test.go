@@ -0,0 +1,130 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+const (
+	upBridge      = "obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0"
+	blockedBridge = "obfs4 192.0.2.2:443 89ABCDEF0123456789ABCDEF0123456789ABCDEF cert=BBBB iat-mode=0"
+)
+
+func bridgeConfig() *config.Config {
+	return &config.Config{
+		TorUseBridges:       true,
+		TorBridges:          []string{upBridge, blockedBridge},
+		TorTransportPlugins: []string{"obfs4 exec /usr/bin/lyrebird"},
+	}
+}
+
+// bootstrapWarning is the STATUS_CLIENT event Tor sends when it cannot
+// connect to blockedBridge.
+var bootstrapWarning = tor.Event{
+	Type: tor.EventStatusClient,
+	Data: `WARN BOOTSTRAP PROGRESS=10 TAG=conn_done SUMMARY="Connected to a relay" WARNING="Connection refused" ` +
+		`REASON=CONNECTREFUSED COUNT=3 RECOMMENDATION=ignore HOSTID="$89ABCDEF0123456789ABCDEF0123456789ABCDEF" HOSTADDR="192.0.2.2:443"`,
+}
+
+func TestStatus_ReportsBridges(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		switch cmd {
+		case "GETINFO version status/bootstrap-phase status/circuit-established traffic/read traffic/written circuit-status":
+			return "250-version=0.4.8.10\r\n" +
+				"250-status/bootstrap-phase=WARN BOOTSTRAP PROGRESS=10 TAG=conn_done SUMMARY=\"Connected to a relay\"\r\n" +
+				"250-status/circuit-established=0\r\n" +
+				"250-traffic/read=0\r\n" +
+				"250-traffic/written=0\r\n" +
+				"250 OK\r\n"
+		case "GETINFO entry-guards":
+			return "250+entry-guards=\r\n" +
+				"$0123456789ABCDEF0123456789ABCDEF01234567~bridge1 up\r\n" +
+				".\r\n250 OK\r\n"
+		}
+		return "552 Unrecognized key\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client, config: bridgeConfig()}
+	handler.handleTorEvent(bootstrapWarning)
+
+	w := httptest.NewRecorder()
+	handler.Status(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Bridges []BridgeStatus `json:"bridges"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Bridges) != 2 {
+		t.Fatalf("expected 2 bridges, got %+v", response.Bridges)
+	}
+
+	up := response.Bridges[0]
+	if up.Bridge != "obfs4 192.0.2.1:443" || up.Status != "up" || !up.Reachable || up.Warning != "" {
+		t.Errorf("unexpected reachable bridge: %+v", up)
+	}
+	blocked := response.Bridges[1]
+	if blocked.Status != "down" || blocked.Reachable || blocked.Reason != "CONNECTREFUSED" ||
+		blocked.Warning != "Connection refused" || blocked.WarnedAt == nil {
+		t.Errorf("unexpected blocked bridge: %+v", blocked)
+	}
+}
+
+func TestStatus_OmitsBridgesWhenUnused(t *testing.T) {
+	handler := &Handler{config: &config.Config{TorBridges: []string{upBridge}}}
+	if statuses := handler.collectBridges(); statuses != nil {
+		t.Errorf("expected no bridge statuses with UseBridges off, got %+v", statuses)
+	}
+}
+
+func TestEmit_LabelsBootstrapFailureWithBridge(t *testing.T) {
+	handler := &Handler{config: bridgeConfig(), events: newEventBroker()}
+	handler.handleTorEvent(bootstrapWarning)
+
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	handler.emit(notify.EventBootstrapFailed, "Tor bootstrap incomplete", notify.Details{})
+	failed := <-ch
+	if failed.Details.Bridge != "obfs4 192.0.2.2:443" || failed.Details.Reason != "CONNECTREFUSED" {
+		t.Errorf("expected the blocked bridge in the details, got %+v", failed.Details)
+	}
+
+	// Other events are not labelled
+	handler.emit(notify.EventCircuitRenewed, "Tor circuit renewed", notify.Details{})
+	if renewed := <-ch; renewed.Details.Bridge != "" {
+		t.Errorf("expected no bridge on circuit_renewed, got %q", renewed.Details.Bridge)
+	}
+
+	// Completing bootstrap clears the label
+	handler.handleTorEvent(tor.Event{Type: tor.EventStatusClient, Data: `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`})
+	<-ch // bootstrap_progress
+	<-ch // health_changed
+	handler.emit(notify.EventBootstrapFailed, "Tor bootstrap failed", notify.Details{})
+	if failed := <-ch; failed.Details.Bridge != "" {
+		t.Errorf("expected the label to be cleared after bootstrap, got %q", failed.Details.Bridge)
+	}
+}
+
+func TestRecordBootstrapWarning_IgnoresOtherRelays(t *testing.T) {
+	handler := &Handler{config: bridgeConfig()}
+	handler.handleTorEvent(tor.Event{
+		Type: tor.EventStatusClient,
+		Data: `WARN BOOTSTRAP PROGRESS=10 TAG=conn_done WARNING="No route to host" REASON=NOROUTE HOSTID="$AAAA" HOSTADDR="198.51.100.1:9001"`,
+	})
+	if handler.lastBridgeWarning != nil || len(handler.bridgeWarnings) != 0 {
+		t.Errorf("expected warnings about other relays to be ignored, got %+v", handler.bridgeWarnings)
+	}
+}
@@ -0,0 +1,214 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// extendRequest is the body of POST /circuits/{id}/extend.
+type extendRequest struct {
+	Path []string `json:"path"` // Relays to extend through; empty lets Tor pick
+}
+
+// attachRequest is the body of POST /streams/{id}/attach.
+type attachRequest struct {
+	CircuitID string `json:"circuit_id"` // "0" lets Tor pick a circuit
+}
+
+// Circuit closes a circuit (DELETE /circuits/{id}), closes every circuit
+// through an exit relay (DELETE /circuits/exit/{fingerprint}) or extends a
+// circuit (POST /circuits/{id}/extend, with id 0 building a new one).
+func (h *Handler) Circuit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/circuits/"), "/")
+	switch {
+	case id == "exit" && r.Method == http.MethodDelete:
+		h.closeExitCircuits(w, action)
+	case action == "" && r.Method == http.MethodDelete:
+		h.closeCircuit(w, r, id)
+	case action == "extend" && r.Method == http.MethodPost:
+		h.extendCircuit(w, r, id)
+	case action == "" || action == "extend":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		h.writeCircuitError(w, http.StatusNotFound, fmt.Errorf("unknown circuit action %q", action))
+	}
+}
+
+func (h *Handler) closeCircuit(w http.ResponseWriter, r *http.Request, id string) {
+	if !tor.ValidID(id) {
+		h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid circuit id %q", id))
+		return
+	}
+
+	if err := h.torClient.CloseCircuit(id, r.URL.Query().Get("if_unused") == "true"); err != nil {
+		h.writeCircuitError(w, controlErrorStatus(err), err)
+		return
+	}
+	h.observeCircuitClosed("circuit", id, "")
+
+	h.writeCircuitResponse(w, http.StatusOK, map[string]interface{}{
+		"status": "OK",
+		"closed": []string{id},
+	})
+}
+
+// closeExitCircuits closes every circuit whose exit is the given relay, so
+// a misbehaving exit can be dropped without a NEWNYM affecting every app.
+func (h *Handler) closeExitCircuits(w http.ResponseWriter, fingerprint string) {
+	if !tor.ValidFingerprint(fingerprint) {
+		h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid relay fingerprint %q", fingerprint))
+		return
+	}
+	fingerprint = strings.ToUpper(strings.TrimPrefix(fingerprint, "$"))
+
+	circuits, err := h.torClient.GetCircuits()
+	if err != nil {
+		h.writeCircuitError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	closed := []string{}
+	for _, circuit := range tor.ThroughExit(circuits, fingerprint) {
+		if err := h.torClient.CloseCircuit(circuit.ID, false); err != nil {
+			// The circuit may have closed on its own since it was listed
+			if errors.Is(err, tor.ErrUnknownCircuit) {
+				continue
+			}
+			h.writeCircuitError(w, http.StatusBadGateway, err)
+			return
+		}
+		closed = append(closed, circuit.ID)
+		h.observeCircuitClosed("exit", circuit.ID, fingerprint)
+	}
+
+	slog.Info("Closed circuits through exit relay", "exit", fingerprint, "closed", len(closed))
+	h.writeCircuitResponse(w, http.StatusOK, map[string]interface{}{
+		"status": "OK",
+		"exit":   fingerprint,
+		"closed": closed,
+	})
+}
+
+func (h *Handler) extendCircuit(w http.ResponseWriter, r *http.Request, id string) {
+	var req extendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !tor.ValidID(id) {
+		h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid circuit id %q", id))
+		return
+	}
+	for _, relay := range req.Path {
+		if !tor.ValidRelay(relay) {
+			h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid relay %q", relay))
+			return
+		}
+	}
+
+	circuitID, err := h.torClient.ExtendCircuit(id, req.Path)
+	if err != nil {
+		h.writeCircuitError(w, controlErrorStatus(err), err)
+		return
+	}
+
+	h.writeCircuitResponse(w, http.StatusOK, map[string]interface{}{
+		"status":     "OK",
+		"circuit_id": circuitID,
+	})
+}
+
+// Stream closes a stream (DELETE /streams/{id}) or attaches a detached
+// stream to a circuit (POST /streams/{id}/attach).
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/streams/"), "/")
+	if !tor.ValidID(id) {
+		h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid stream id %q", id))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		if err := h.torClient.CloseStream(id, tor.StreamReasonMisc); err != nil {
+			h.writeCircuitError(w, controlErrorStatus(err), err)
+			return
+		}
+		h.writeCircuitResponse(w, http.StatusOK, map[string]interface{}{
+			"status": "OK",
+			"closed": []string{id},
+		})
+	case action == "attach" && r.Method == http.MethodPost:
+		var req attachRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		if !tor.ValidID(req.CircuitID) {
+			h.writeCircuitError(w, http.StatusBadRequest, fmt.Errorf("invalid circuit id %q", req.CircuitID))
+			return
+		}
+		if err := h.torClient.AttachStream(id, req.CircuitID); err != nil {
+			h.writeCircuitError(w, controlErrorStatus(err), err)
+			return
+		}
+		h.writeCircuitResponse(w, http.StatusOK, map[string]interface{}{
+			"status":     "OK",
+			"stream_id":  id,
+			"circuit_id": req.CircuitID,
+		})
+	case action == "" || action == "attach":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		h.writeCircuitError(w, http.StatusNotFound, fmt.Errorf("unknown stream action %q", action))
+	}
+}
+
+// observeCircuitClosed records a circuit closed through the API in metrics
+// and on /events.
+func (h *Handler) observeCircuitClosed(target, id, exit string) {
+	if h.metrics != nil {
+		h.metrics.circuitsClosed.WithLabelValues(target).Inc()
+	}
+	h.publish(notify.EventCircuitClosed, "Tor circuit closed", notify.Details{
+		Trigger:   target,
+		CircuitID: id,
+		Exit:      exit,
+	})
+}
+
+// controlErrorStatus maps a failed circuit or stream command to 404 when
+// Tor does not know the ID, and 502 otherwise.
+func controlErrorStatus(err error) int {
+	if errors.Is(err, tor.ErrUnknownCircuit) || errors.Is(err, tor.ErrUnknownStream) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, tor.ErrRejected) {
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+func (h *Handler) writeCircuitResponse(w http.ResponseWriter, status int, response map[string]interface{}) {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("Failed to encode circuit response", "error", err)
+	}
+}
+
+func (h *Handler) writeCircuitError(w http.ResponseWriter, status int, err error) {
+	h.writeCircuitResponse(w, status, map[string]interface{}{
+		"status": "ERROR",
+		"error":  err.Error(),
+	})
+}
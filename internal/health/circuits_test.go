@@ -0,0 +1,183 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+var (
+	testExit  = strings.Repeat("B", 40)
+	testGuard = strings.Repeat("A", 40)
+
+	testUnknownRelay = "$" + strings.Repeat("D", 40)
+)
+
+// startCircuitTor runs a fake Tor with three circuits, two of them through
+// testExit, and records the control commands it receives. Circuit 3 closes
+// on its own before it can be closed through the API.
+func startCircuitTor(t *testing.T) (*tor.Client, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var commands []string
+
+	addr := startFakeTor(t, func(cmd string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if cmd != "GETINFO circuit-status" {
+			commands = append(commands, cmd)
+		}
+
+		switch {
+		case cmd == "GETINFO circuit-status":
+			return "250+circuit-status=\r\n" +
+				"1 BUILT $" + testGuard + "~guard,$" + testExit + "~exit PURPOSE=GENERAL\r\n" +
+				"2 BUILT $" + testGuard + "~guard,$CCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC~other PURPOSE=GENERAL\r\n" +
+				"3 BUILT $" + testGuard + "~guard,$" + testExit + "~exit PURPOSE=GENERAL\r\n" +
+				".\r\n250 OK\r\n"
+		case cmd == "CLOSECIRCUIT 3", cmd == "CLOSECIRCUIT 99", cmd == "CLOSESTREAM 99 1":
+			return "552 Unknown ID\r\n"
+		case strings.HasPrefix(cmd, "EXTENDCIRCUIT 99"):
+			return "552 Unknown circuit \"99\"\r\n"
+		case strings.HasPrefix(cmd, "EXTENDCIRCUIT ") && strings.Contains(cmd, testUnknownRelay):
+			return "552 No such router \"" + testUnknownRelay + "\"\r\n"
+		case strings.HasPrefix(cmd, "EXTENDCIRCUIT "):
+			return "250 EXTENDED 12\r\n"
+		}
+		return "250 OK\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	t.Cleanup(func() { _ = client.Close() })
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(commands)
+	}
+}
+
+func serveCircuits(handler *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/circuits/", handler.Circuit)
+	mux.HandleFunc("/streams/", handler.Stream)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestCircuit_CloseThroughExit(t *testing.T) {
+	client, commands := startCircuitTor(t)
+	handler := &Handler{torClient: client, events: newEventBroker()}
+
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	w := serveCircuits(handler, http.MethodDelete, "/circuits/exit/$"+strings.ToLower(testExit), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Exit   string   `json:"exit"`
+		Closed []string `json:"closed"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Exit != testExit || !slices.Equal(response.Closed, []string{"1"}) {
+		t.Errorf("expected only circuit 1 to be reported closed, got %+v", response)
+	}
+	if expected := []string{"CLOSECIRCUIT 1", "CLOSECIRCUIT 3"}; !slices.Equal(commands(), expected) {
+		t.Errorf("expected %q, got %q", expected, commands())
+	}
+
+	select {
+	case payload := <-ch:
+		if payload.Event != notify.EventCircuitClosed || payload.Details.CircuitID != "1" || payload.Details.Exit != testExit {
+			t.Errorf("unexpected event: %+v", payload)
+		}
+	default:
+		t.Error("expected a circuit_closed event")
+	}
+}
+
+func TestCircuit_Actions(t *testing.T) {
+	client, commands := startCircuitTor(t)
+	handler := &Handler{torClient: client}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		want    int
+		command string
+	}{
+		{name: "close circuit", method: http.MethodDelete, path: "/circuits/2", want: http.StatusOK, command: "CLOSECIRCUIT 2"},
+		{name: "close if unused", method: http.MethodDelete, path: "/circuits/2?if_unused=true", want: http.StatusOK, command: "CLOSECIRCUIT 2 IfUnused"},
+		{name: "unknown circuit", method: http.MethodDelete, path: "/circuits/99", want: http.StatusNotFound},
+		{name: "build circuit", method: http.MethodPost, path: "/circuits/0/extend", body: `{"path":["$` + testGuard + `","$` + testExit + `~exit"]}`, want: http.StatusOK, command: "EXTENDCIRCUIT 0 $" + testGuard + ",$" + testExit + "~exit"},
+		{name: "extend unknown circuit", method: http.MethodPost, path: "/circuits/99/extend", body: `{"path":["$` + testExit + `"]}`, want: http.StatusNotFound},
+		{name: "extend through unknown relay", method: http.MethodPost, path: "/circuits/0/extend", body: `{"path":["` + testUnknownRelay + `"]}`, want: http.StatusBadRequest},
+		{name: "close stream", method: http.MethodDelete, path: "/streams/7", want: http.StatusOK, command: "CLOSESTREAM 7 1"},
+		{name: "unknown stream", method: http.MethodDelete, path: "/streams/99", want: http.StatusNotFound},
+		{name: "attach stream", method: http.MethodPost, path: "/streams/7/attach", body: `{"circuit_id":"12"}`, want: http.StatusOK, command: "ATTACHSTREAM 7 12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(commands())
+			if w := serveCircuits(handler, tt.method, tt.path, tt.body); w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.command == "" {
+				return
+			}
+			if sent := commands()[before:]; !slices.Equal(sent, []string{tt.command}) {
+				t.Errorf("expected %q, got %q", tt.command, sent)
+			}
+		})
+	}
+}
+
+func TestCircuit_InvalidRequests(t *testing.T) {
+	client, commands := startCircuitTor(t)
+	handler := &Handler{torClient: client}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "invalid circuit id", method: http.MethodDelete, path: "/circuits/1%20IfUnused", want: http.StatusBadRequest},
+		{name: "invalid exit", method: http.MethodDelete, path: "/circuits/exit/guard", want: http.StatusBadRequest},
+		{name: "invalid relay", method: http.MethodPost, path: "/circuits/0/extend", body: `{"path":["bad relay"]}`, want: http.StatusBadRequest},
+		{name: "malformed body", method: http.MethodPost, path: "/circuits/0/extend", body: `{`, want: http.StatusBadRequest},
+		{name: "invalid stream id", method: http.MethodDelete, path: "/streams/-1", want: http.StatusBadRequest},
+		{name: "invalid attach circuit", method: http.MethodPost, path: "/streams/7/attach", body: `{"circuit_id":""}`, want: http.StatusBadRequest},
+		{name: "unknown action", method: http.MethodPost, path: "/circuits/1/detach", want: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: "/circuits/1", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveCircuits(handler, tt.method, tt.path, tt.body); w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+	if cmds := commands(); len(cmds) != 0 {
+		t.Errorf("expected invalid requests not to reach Tor, got %q", cmds)
+	}
+}
@@ -0,0 +1,179 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/pkg/version"
+)
+
+// eventBuffer is the number of events queued per /events client before
+// further events are dropped for that client.
+const eventBuffer = 32
+
+// eventHeartbeatInterval keeps idle /events connections open through proxies.
+const eventHeartbeatInterval = 15 * time.Second
+
+// eventBroker fans events out to /events subscribers. Slow subscribers miss
+// events rather than blocking the publisher.
+type eventBroker struct {
+	mu        sync.Mutex
+	subs      map[chan notify.Payload]struct{}
+	done      chan struct{} // Closed when the server shuts down
+	closeOnce sync.Once
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subs: make(map[chan notify.Payload]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// close ends every open /events stream.
+func (b *eventBroker) close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+func (b *eventBroker) subscribe() chan notify.Payload {
+	ch := make(chan notify.Payload, eventBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan notify.Payload) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+func (b *eventBroker) publish(payload notify.Payload) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- payload:
+		default:
+			slog.Debug("Dropping event for slow /events client", "event", payload.Event)
+		}
+	}
+}
+
+// emit publishes an event on /events and sends it as a webhook if configured.
+func (h *Handler) emit(event notify.Event, message string, details notify.Details) {
+	if event == notify.EventBootstrapFailed {
+		details = h.withBridgeWarning(details)
+	}
+	h.publish(event, message, details)
+	h.sendWebhook(event, message, details)
+}
+
+// publish sends an event to /events subscribers only.
+func (h *Handler) publish(event notify.Event, message string, details notify.Details) {
+	h.events.publish(notify.Payload{
+		Event:     event,
+		Timestamp: time.Now(),
+		Message:   message,
+		Details:   details,
+		Version:   version.Version,
+		Commit:    version.Commit,
+	})
+}
+
+// CloseEvents ends open /events streams. Register it with
+// http.Server.RegisterOnShutdown, since Shutdown otherwise waits for them.
+func (h *Handler) CloseEvents() {
+	if h.events != nil {
+		h.events.close()
+	}
+}
+
+// Events streams Tor and health events as Server-Sent Events. Each event is
+// named after its notify.Event and carries the same JSON payload as the json
+// webhook template. An optional "events" query parameter limits the stream
+// to a comma-separated list of event names.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var filter map[notify.Event]struct{}
+	if raw := r.URL.Query().Get("events"); raw != "" {
+		filter = make(map[notify.Event]struct{})
+		for _, name := range strings.Split(raw, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				filter[notify.Event(name)] = struct{}{}
+			}
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise end the stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Debug("Failed to clear write deadline for event stream", "error", err)
+	}
+
+	ch := h.events.subscribe()
+	defer h.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("Event stream does not support flushing", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.events.done:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case payload := <-ch:
+			if filter != nil {
+				if _, ok := filter[payload.Event]; !ok {
+					continue
+				}
+			}
+
+			data, err := json.Marshal(payload)
+			if err != nil {
+				slog.Error("Failed to encode event", "event", payload.Event, "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", payload.Event, data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// readEvent reads the next named Server-Sent Event, skipping comments.
+func readEvent(t *testing.T, reader *bufio.Reader) (string, notify.Payload) {
+	t.Helper()
+
+	var name string
+	var payload notify.Payload
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &payload); err != nil {
+				t.Fatalf("failed to decode event data: %v", err)
+			}
+		case line == "" && name != "":
+			return name, payload
+		}
+	}
+}
+
+func TestEvents_StreamsPublishedEvents(t *testing.T) {
+	handler := &Handler{events: newEventBroker()}
+	server := httptest.NewServer(handler.instrument("/events", handler.Events))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?events=health_changed,circuit_built", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected Content-Type 'text/event-stream', got '%s'", contentType)
+	}
+
+	// Filtered out, so the first event read must be health_changed
+	handler.publish(notify.EventReadinessChecked, "Tor egress check passed", notify.Details{Healthy: true})
+	handler.checkHealthStateChange(false)
+	handler.checkHealthStateChange(true)
+
+	name, payload := readEvent(t, bufio.NewReader(res.Body))
+	if name != string(notify.EventHealthChanged) || payload.Event != notify.EventHealthChanged {
+		t.Errorf("expected health_changed event, got %q (%+v)", name, payload)
+	}
+	if !payload.Details.Healthy {
+		t.Error("expected healthy transition")
+	}
+}
+
+func TestEvents_Unavailable(t *testing.T) {
+	handler := &Handler{}
+
+	w := httptest.NewRecorder()
+	handler.Events(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestEventBroker_DropsForSlowSubscriber(t *testing.T) {
+	broker := newEventBroker()
+	ch := broker.subscribe()
+	defer broker.unsubscribe(ch)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < eventBuffer*2; i++ {
+			broker.publish(notify.Payload{Event: notify.EventCircuitBuilt})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected publish not to block on a full subscriber")
+	}
+	if len(ch) != eventBuffer {
+		t.Errorf("expected %d buffered events, got %d", eventBuffer, len(ch))
+	}
+}
+
+func TestHandleTorEvent_PublishesCircuitEvents(t *testing.T) {
+	handler := &Handler{events: newEventBroker()}
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	handler.handleTorEvent(tor.Event{Type: tor.EventCirc, Data: "7 BUILT $AAAA~guard,$BBBB~middle,$CCCC~exit PURPOSE=GENERAL"})
+	handler.handleTorEvent(tor.Event{Type: tor.EventCirc, Data: "8 EXTENDED $AAAA~guard PURPOSE=GENERAL"})
+	handler.handleTorEvent(tor.Event{Type: tor.EventCirc, Data: "9 FAILED $AAAA~guard PURPOSE=GENERAL REASON=TIMEOUT"})
+	handler.handleTorEvent(tor.Event{
+		Type: tor.EventStatusClient,
+		Data: `NOTICE BOOTSTRAP PROGRESS=75 TAG=enough_dirinfo SUMMARY="Loaded enough directory info to build circuits"`,
+	})
+
+	built := <-ch
+	if built.Event != notify.EventCircuitBuilt || built.Details.CircuitID != "7" || built.Details.Exit != "CCCC" {
+		t.Errorf("unexpected built event: %+v", built)
+	}
+
+	failed := <-ch
+	if failed.Event != notify.EventCircuitFailed || failed.Details.CircuitID != "9" || failed.Details.Reason != "TIMEOUT" {
+		t.Errorf("unexpected failed event: %+v", failed)
+	}
+
+	progress := <-ch
+	if progress.Event != notify.EventBootstrapProgress || progress.Details.Bootstrap == nil || *progress.Details.Bootstrap != 75 {
+		t.Errorf("unexpected bootstrap event: %+v", progress)
+	}
+
+	select {
+	case extra := <-ch:
+		t.Errorf("unexpected extra event: %+v", extra)
+	default:
+	}
+}
+
+func TestEvents_ClosedOnShutdown(t *testing.T) {
+	handler := &Handler{events: newEventBroker()}
+	server := httptest.NewUnstartedServer(handler.instrument("/events", handler.Events))
+	server.Config.RegisterOnShutdown(handler.CloseEvents)
+	server.Start()
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Config.Shutdown(ctx); err != nil {
+		t.Errorf("expected shutdown not to wait for the event stream, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"fmt"
+
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// ExitInfo identifies the exit relay currently used for general traffic.
+type ExitInfo struct {
+	Fingerprint string `json:"fingerprint"`
+	Nickname    string `json:"nickname,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	Country     string `json:"country,omitempty"`
+}
+
+// resolveExit finds the exit of the most recently created general-purpose
+// circuit and resolves its address and country using Tor's own consensus and
+// GeoIP database.
+func (h *Handler) resolveExit() (*ExitInfo, error) {
+	circuits, err := h.torClient.GetCircuits()
+	if err != nil {
+		return nil, err
+	}
+
+	circuit, ok := latestExitCircuit(circuits)
+	if !ok {
+		return nil, fmt.Errorf("no built general-purpose circuit")
+	}
+
+	exit, _ := circuit.Exit()
+	info := &ExitInfo{
+		Fingerprint: exit.Fingerprint,
+		Nickname:    exit.Nickname,
+	}
+
+	relay, err := h.torClient.GetRelay(exit.Fingerprint)
+	if err != nil {
+		return info, fmt.Errorf("failed to look up exit relay %s: %w", exit.Fingerprint, err)
+	}
+	info.Nickname = relay.Nickname
+	info.IP = relay.Address
+
+	country, err := h.torClient.GetCountry(relay.Address)
+	if err != nil {
+		return info, fmt.Errorf("failed to geolocate exit relay %s: %w", relay.Address, err)
+	}
+	info.Country = country
+
+	return info, nil
+}
+
+// latestExitCircuit picks the newest BUILT circuit that carries user traffic.
+// Circuits without TIME_CREATED fall back to listing order.
+func latestExitCircuit(circuits []tor.Circuit) (tor.Circuit, bool) {
+	var latest tor.Circuit
+	found := false
+
+	for _, circuit := range circuits {
+		if circuit.Status != "BUILT" || len(circuit.Path) == 0 {
+			continue
+		}
+		if circuit.Purpose != "" && circuit.Purpose != "GENERAL" && circuit.Purpose != "CONFLUX_LINKED" {
+			continue
+		}
+		if !found || !circuit.TimeCreated.Before(latest.TimeCreated) {
+			latest = circuit
+			found = true
+		}
+	}
+
+	return latest, found
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+func TestLatestExitCircuit(t *testing.T) {
+	older := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Minute)
+
+	circuits := []tor.Circuit{
+		{ID: "1", Status: "BUILT", Purpose: "GENERAL", TimeCreated: older, Path: []tor.Relay{{Fingerprint: "A"}, {Fingerprint: "OLD"}}},
+		{ID: "2", Status: "BUILT", Purpose: "GENERAL", TimeCreated: newer, Path: []tor.Relay{{Fingerprint: "A"}, {Fingerprint: "NEW"}}},
+		{ID: "3", Status: "BUILT", Purpose: "HS_CLIENT_REND", TimeCreated: newer.Add(time.Minute), Path: []tor.Relay{{Fingerprint: "HS"}}},
+		{ID: "4", Status: "EXTENDED", Purpose: "GENERAL", TimeCreated: newer.Add(time.Hour), Path: []tor.Relay{{Fingerprint: "PENDING"}}},
+		{ID: "5", Status: "BUILT", Purpose: "GENERAL", TimeCreated: newer.Add(time.Hour)},
+	}
+
+	circuit, ok := latestExitCircuit(circuits)
+	if !ok {
+		t.Fatal("expected an exit circuit to be found")
+	}
+	if circuit.ID != "2" {
+		t.Errorf("expected circuit 2, got %s", circuit.ID)
+	}
+}
+
+func TestLatestExitCircuit_None(t *testing.T) {
+	circuits := []tor.Circuit{
+		{ID: "1", Status: "LAUNCHED", Purpose: "GENERAL"},
+	}
+
+	if _, ok := latestExitCircuit(circuits); ok {
+		t.Error("expected no exit circuit")
+	}
+}
+
+func TestStatus_IncludesExit(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		switch cmd {
+		case "GETINFO version status/bootstrap-phase status/circuit-established traffic/read traffic/written circuit-status":
+			return "250-version=0.4.8.10\r\n" +
+				"250-status/bootstrap-phase=NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY=\"Done\"\r\n" +
+				"250-status/circuit-established=1\r\n" +
+				"250-traffic/read=10\r\n" +
+				"250-traffic/written=20\r\n" +
+				"250 OK\r\n"
+		case "GETINFO circuit-status":
+			return "250+circuit-status=\r\n" +
+				"1 BUILT $AAAA~guard,$BBBB~middle,$CCCC~exitnick PURPOSE=GENERAL TIME_CREATED=2024-05-01T12:30:45.000000\r\n" +
+				".\r\n250 OK\r\n"
+		case "GETINFO ns/id/CCCC":
+			return "250+ns/id/CCCC=\r\n" +
+				"r exitnick qvU+3sBs Kyp5yXIu 2024-05-01 11:22:33 185.220.101.5 443 0\r\n" +
+				".\r\n250 OK\r\n"
+		case "GETINFO ip-to-country/185.220.101.5":
+			return "250-ip-to-country/185.220.101.5=de\r\n250 OK\r\n"
+		}
+		return "552 Unrecognized key\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+
+	handler.Status(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Exit *ExitInfo `json:"exit"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	expected := ExitInfo{Fingerprint: "CCCC", Nickname: "exitnick", IP: "185.220.101.5", Country: "de"}
+	if response.Exit == nil || *response.Exit != expected {
+		t.Errorf("expected exit %+v, got %+v", expected, response.Exit)
+	}
+}
@@ -3,14 +3,21 @@ package health
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/eslutz/torarr/internal/config"
 	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/onion"
 	"github.com/eslutz/torarr/internal/tor"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -18,39 +25,105 @@ import (
 type Handler struct {
 	torClient        *tor.Client
 	readinessChecker *ExternalChecker
+	listenerCheckers map[string]*ExternalChecker // Egress checks per named SOCKS listener
 	config           *config.Config
 	metrics          *metrics
 	webhook          *notify.Webhook
 	webhookEvents    []string
+	events           *eventBroker
+	onions           *onion.Manager
 	previousHealthy  *bool      // Tracks previous health state for change detection
-	healthMu         sync.Mutex // Protects previousHealthy from concurrent access
+	bootstrapHealthy bool       // Latest bootstrap health reported by Tor
+	egressFailed     bool       // Whether automatic renewal gave up on Tor egress
+	healthMu         sync.Mutex // Protects previousHealthy, bootstrapHealthy and egressFailed
+	lastRenewal      time.Time  // When NEWNYM was last sent, for rate limiting
+	renewMu          sync.Mutex // Serializes renewals and protects lastRenewal
+
+	statusCache    *statusSnapshot    // Latest status collected by the monitor
+	readinessCache *readinessSnapshot // Latest egress check run by the monitor
+	cacheMu        sync.RWMutex       // Protects statusCache and readinessCache
+
+	traffic                *trafficHistory // Per-second traffic from BW events
+	limitsApplied          bool            // Whether traffic limits were set since Tor last (re)started
+	accountingLimitReached bool            // Whether Tor was hibernating at the last accounting check
+	trafficMu              sync.Mutex      // Protects limitsApplied and accountingLimitReached
+
+	bridgeWarnings    map[string]bridgeWarning // Latest bootstrap warning per bridge address
+	lastBridgeWarning *bridgeWarning           // Most recent warning since Tor last bootstrapped
+	bridgeMu          sync.Mutex               // Protects bridgeWarnings and lastBridgeWarning
+
+	settingsMu sync.RWMutex                   // Protects config, webhook, webhookEvents and the checkers across reloads
+	reloadMu   sync.Mutex                     // Serializes reloads
+	loadConfig func() (*config.Config, error) // Reads the configuration applied by Reload
 }
 
 func NewHandler(cfg *config.Config) *Handler {
-	torClient := tor.NewClient(cfg.TorControlAddress, cfg.TorControlPassword)
+	torClient := tor.NewClientWithAuth(cfg.TorControlAddress, tor.Auth{
+		Method:        tor.AuthMethod(cfg.TorControlAuth),
+		Password:      cfg.TorControlPassword,
+		CookieFile:    cfg.TorControlCookieFile,
+		DataDirectory: cfg.TorDataDirectory,
+	})
 	metrics := newMetrics()
 
-	readinessChecker := NewExternalChecker(
+	return &Handler{
+		torClient:        torClient,
+		readinessChecker: newReadinessChecker(cfg),
+		listenerCheckers: newListenerCheckers(cfg),
+		config:           cfg,
+		metrics:          metrics,
+		webhook:          newWebhook(cfg),
+		webhookEvents:    cfg.WebhookEvents,
+		events:           newEventBroker(),
+		traffic:          newTrafficHistory(cfg.TrafficHistory),
+		onions:           onion.NewManager(torClient, cfg.TorDataDirectory),
+		loadConfig:       config.LoadStrict,
+	}
+}
+
+func newReadinessChecker(cfg *config.Config) *ExternalChecker {
+	return NewExternalChecker(
 		cfg.HealthExternalEndpoints,
 		time.Duration(cfg.HealthExternalTimeout)*time.Second,
-		"socks5://127.0.0.1:9050",
+		socksProxyURL(cfg.TorSocksPort),
 	)
+}
 
-	// Initialize webhook if URL is configured
-	var webhook *notify.Webhook
-	if cfg.WebhookURL != "" {
-		template := notify.Template(cfg.WebhookTemplate)
-		webhook = notify.NewWebhook(cfg.WebhookURL, template)
+// socksProxyURL returns the URL for reaching Tor's SOCKS port from inside the
+// container. Wildcard or missing hosts are reached on the loopback address.
+func socksProxyURL(address string) string {
+	if address == "" {
+		address = "9050"
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = "", address
 	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "socks5://" + net.JoinHostPort(host, port)
+}
 
-	return &Handler{
-		torClient:        torClient,
-		readinessChecker: readinessChecker,
-		config:           cfg,
-		metrics:          metrics,
-		webhook:          webhook,
-		webhookEvents:    cfg.WebhookEvents,
+// newWebhook returns nil when no webhook URL is configured.
+func newWebhook(cfg *config.Config) *notify.Webhook {
+	if cfg.WebhookURL == "" {
+		return nil
 	}
+	return notify.NewWebhook(cfg.WebhookURL, notify.Template(cfg.WebhookTemplate))
+}
+
+// currentConfig returns the configuration most recently applied by Reload.
+func (h *Handler) currentConfig() *config.Config {
+	h.settingsMu.RLock()
+	defer h.settingsMu.RUnlock()
+	return h.config
+}
+
+func (h *Handler) checker() *ExternalChecker {
+	h.settingsMu.RLock()
+	defer h.settingsMu.RUnlock()
+	return h.readinessChecker
 }
 
 func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
@@ -63,119 +136,103 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Health reports Tor bootstrap readiness from the monitor's cached status.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	status, err := h.torClient.GetStatus()
-	if err != nil {
-		if h.metrics != nil {
-			h.metrics.torReady.Set(0)
-		}
-
-		// Check for health state change first to avoid duplicate notifications
-		stateChanged := h.checkHealthStateChange(false)
-
-		// Send EventBootstrapFailed webhook only if state didn't change
-		// (EventHealthChanged already sent if state changed)
-		if !stateChanged {
-			h.sendWebhook(notify.EventBootstrapFailed, "Tor bootstrap failed", notify.Details{
-				Error: err.Error(),
-			})
-		}
-
-		w.WriteHeader(http.StatusServiceUnavailable)
-		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "NOT_READY",
-			"error":  "tor not ready",
-		}); err != nil {
-			slog.Error("Failed to encode health response", "error", err)
-		}
-		return
-	}
-
-	if status.BootstrapPhase < 100 {
-		if h.metrics != nil {
-			h.metrics.observeTorStatus(status)
-		}
-
-		// Check for health state change first to avoid duplicate notifications
-		stateChanged := h.checkHealthStateChange(false)
-
-		// Send EventBootstrapFailed webhook only if state didn't change
-		// (EventHealthChanged already sent if state changed)
-		if !stateChanged {
-			h.sendWebhook(notify.EventBootstrapFailed, "Tor bootstrap incomplete", notify.Details{
-				Bootstrap: &status.BootstrapPhase,
-				Circuits:  status.NumCircuits,
-			})
-		}
+	snapshot := h.cachedStatus()
 
+	if snapshot.err != nil || snapshot.status.BootstrapPhase < 100 {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "NOT_READY",
-			"error":  "tor not ready",
+			"status":      "NOT_READY",
+			"error":       "tor not ready",
+			"checked_at":  snapshot.checkedAt,
+			"age_seconds": ageSeconds(snapshot.checkedAt),
 		}); err != nil {
 			slog.Error("Failed to encode health response", "error", err)
 		}
 		return
 	}
 
-	if h.metrics != nil {
-		h.metrics.observeTorStatus(status)
-	}
-
-	// Check for health state change to healthy
-	h.checkHealthStateChange(true)
-
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status": "READY",
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "READY",
+		"checked_at":  snapshot.checkedAt,
+		"age_seconds": ageSeconds(snapshot.checkedAt),
 	}); err != nil {
 		slog.Error("Failed to encode health response", "error", err)
 	}
 }
 
-// Ready checks whether Tor egress is functioning by hitting external endpoints through the SOCKS proxy.
+// readyResponse is the cached egress check plus how old it is.
+type readyResponse struct {
+	*ExternalCheckResult
+	AgeSeconds float64 `json:"age_seconds"`
+}
+
+// Ready reports whether Tor egress is functioning, using the monitor's most
+// recent check of the external endpoints through the SOCKS proxy. With
+// ?listener=<name> it reports the check through that named SOCKS listener.
 func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	result := h.readinessChecker.Check()
+	snapshot := h.cachedReadiness()
+	result := snapshot.result
+	if name := r.URL.Query().Get("listener"); name != "" && name != config.DefaultSocksListener {
+		listenerResult, ok := snapshot.listeners[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if err := json.NewEncoder(w).Encode(map[string]string{
+				"status": "ERROR",
+				"error":  fmt.Sprintf("unknown SOCKS listener %q", name),
+			}); err != nil {
+				slog.Error("Failed to encode ready response", "error", err)
+			}
+			return
+		}
+		result = listenerResult
+	}
 
-	if h.metrics != nil {
-		h.metrics.observeExternalCheck(result.Endpoint, result.Success, result.IsTor)
+	response := readyResponse{
+		ExternalCheckResult: result,
+		AgeSeconds:          ageSeconds(snapshot.checkedAt),
 	}
 
-	if !result.Success || !result.IsTor {
+	if !readinessOK(result) {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		if err := json.NewEncoder(w).Encode(result); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			slog.Error("Failed to encode ready response", "error", err)
 		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(result); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		slog.Error("Failed to encode ready response", "error", err)
 	}
 }
 
+// Status serves a diagnostics snapshot from the monitor's cached status.
 func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	status, err := h.torClient.GetStatus()
-	if err != nil {
+	snapshot := h.cachedStatus()
+	if snapshot.err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "ERROR",
-			"error":  err.Error(),
+			"status":      "ERROR",
+			"error":       snapshot.err.Error(),
+			"checked_at":  snapshot.checkedAt,
+			"age_seconds": ageSeconds(snapshot.checkedAt),
 		}); err != nil {
 			slog.Error("Failed to encode status response", "error", err)
 		}
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+	status := snapshot.status
+	response := map[string]interface{}{
 		"status":              "OK",
 		"version":             status.Version,
 		"bootstrap_phase":     status.BootstrapPhase,
@@ -185,43 +242,227 @@ func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 			"bytes_read":    status.Traffic.BytesRead,
 			"bytes_written": status.Traffic.BytesWritten,
 		},
-	}); err != nil {
+		"checked_at":  snapshot.checkedAt,
+		"age_seconds": ageSeconds(snapshot.checkedAt),
+	}
+	if snapshot.exit != nil {
+		response["exit"] = snapshot.exit
+	}
+	if snapshot.bridges != nil {
+		response["bridges"] = snapshot.bridges
+	}
+	if snapshot.onions != nil {
+		response["onions"] = snapshot.onions
+	}
+	if snapshot.socksListeners != nil {
+		response["socks_listeners"] = snapshot.socksListeners
+	}
+	if snapshot.accounting != nil {
+		response["accounting"] = snapshot.accounting
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		slog.Error("Failed to encode status response", "error", err)
 	}
+}
+
+// Circuits lists the circuits Tor currently has open or in progress.
+func (h *Handler) Circuits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	circuits, err := h.torClient.GetCircuits()
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ERROR",
+			"error":  err.Error(),
+		}); err != nil {
+			slog.Error("Failed to encode circuits response", "error", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "OK",
+		"num_circuits": tor.CountBuilt(circuits),
+		"circuits":     circuits,
+	}); err != nil {
+		slog.Error("Failed to encode circuits response", "error", err)
+	}
+}
+
+// Streams lists the streams Tor is carrying, such as SOCKS connections from the *arr apps.
+func (h *Handler) Streams(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	streams, err := h.torClient.GetStreams()
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ERROR",
+			"error":  err.Error(),
+		}); err != nil {
+			slog.Error("Failed to encode streams response", "error", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "OK",
+		"num_streams": len(streams),
+		"by_status":   tor.CountStreamsByStatus(streams),
+		"streams":     streams,
+	}); err != nil {
+		slog.Error("Failed to encode streams response", "error", err)
+	}
+}
+
+// exitConfigRequest is a partial update to the exit policy; omitted fields
+// keep their current value.
+type exitConfigRequest struct {
+	ExitNodes        *[]string `json:"exit_nodes"`
+	ExcludeExitNodes *[]string `json:"exclude_exit_nodes"`
+	StrictNodes      *bool     `json:"strict_nodes"`
+	Save             bool      `json:"save"`
+}
+
+// ExitConfig reads (GET) or changes (PUT) the exit country policy at runtime.
+// Only the supplied fields are validated and set, so router set entries from
+// a custom torrc survive a change to another field. Setting "save" persists
+// the running configuration to torrc with SAVECONF, which is refused with
+// TORRC_GENERATE because the next start would overwrite it.
+func (h *Handler) ExitConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	policy, err := h.torClient.GetExitPolicy()
+	if err != nil {
+		h.writeExitConfigError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	saved := false
+	if r.Method == http.MethodPut {
+		var req exitConfigRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeExitConfigError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+
+		update := tor.ExitPolicyUpdate{StrictNodes: req.StrictNodes}
+		if req.ExitNodes != nil {
+			exitNodes := normalizeCountries(*req.ExitNodes)
+			update.ExitNodes = &exitNodes
+		}
+		if req.ExcludeExitNodes != nil {
+			excludeExitNodes := normalizeCountries(*req.ExcludeExitNodes)
+			update.ExcludeExitNodes = &excludeExitNodes
+		}
+
+		if err := update.Validate(); err != nil {
+			h.writeExitConfigError(w, http.StatusBadRequest, err)
+			return
+		}
+		if cfg := h.currentConfig(); req.Save && cfg != nil && cfg.TorrcGenerate {
+			h.writeExitConfigError(w, http.StatusConflict,
+				errors.New("save is not supported with TORRC_GENERATE, which overwrites torrc on every start; set TOR_EXIT_NODES instead"))
+			return
+		}
+
+		if err := h.torClient.UpdateExitPolicy(update); err != nil {
+			h.writeExitConfigError(w, http.StatusBadGateway, err)
+			return
+		}
+		if update.ExitNodes != nil {
+			policy.ExitNodes = *update.ExitNodes
+		}
+		if update.ExcludeExitNodes != nil {
+			policy.ExcludeExitNodes = *update.ExcludeExitNodes
+		}
+		if update.StrictNodes != nil {
+			policy.StrictNodes = *update.StrictNodes
+		}
+
+		if req.Save {
+			if err := h.torClient.SaveConf(); err != nil {
+				h.writeExitConfigError(w, http.StatusBadGateway, err)
+				return
+			}
+			saved = true
+		}
 
-	if h.metrics != nil {
-		h.metrics.observeTorStatus(status)
+		slog.Info("Exit policy updated",
+			"exit_nodes", policy.ExitNodes,
+			"exclude_exit_nodes", policy.ExcludeExitNodes,
+			"strict_nodes", policy.StrictNodes,
+			"saved", saved,
+		)
+	}
+
+	response := map[string]interface{}{
+		"status":             "OK",
+		"exit_nodes":         policy.ExitNodes,
+		"exclude_exit_nodes": policy.ExcludeExitNodes,
+		"strict_nodes":       policy.StrictNodes,
+	}
+	if r.Method == http.MethodPut {
+		response["saved"] = saved
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("Failed to encode exit config response", "error", err)
+	}
+}
+
+func (h *Handler) writeExitConfigError(w http.ResponseWriter, status int, err error) {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ERROR",
+		"error":  err.Error(),
+	}); err != nil {
+		slog.Error("Failed to encode exit config response", "error", err)
 	}
 }
 
+// normalizeCountries accepts "US" and "{us}" as well as "us".
+func normalizeCountries(codes []string) []string {
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		code = strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}")
+		normalized = append(normalized, strings.ToLower(code))
+	}
+	return normalized
+}
+
 func (h *Handler) Renew(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	if err := h.torClient.Signal("NEWNYM"); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err := h.renewCircuit(RenewTriggerManual); err != nil {
+		var rateLimited *RateLimitedError
+		if errors.As(err, &rateLimited) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		if err := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); err != nil {
 			slog.Error("Failed to encode renew error response", "error", err)
 		}
 		return
 	}
 
-	// Build details for webhook notification using current Tor status, if available
-	if status, err := h.torClient.GetStatus(); err != nil {
-		// If we can't retrieve status, skip sending a potentially misleading webhook
-		slog.Warn("Failed to get Tor status after NEWNYM; skipping circuit renewal webhook", "error", err)
-	} else {
-		details := notify.Details{
-			Circuits: status.NumCircuits,
-			Healthy:  status.CircuitEstablished,
-		}
-
-		// Send webhook notification if configured
-		h.sendWebhook(notify.EventCircuitRenewed, "Tor circuit renewal requested", details)
-	}
-
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(map[string]string{"status": "OK", "message": "Signal NEWNYM sent"}); err != nil {
 		slog.Error("Failed to encode renew response", "error", err)
@@ -232,13 +473,41 @@ func (h *Handler) Close() error {
 	return h.torClient.Close()
 }
 
+// SetupRoutes registers every endpoint. Probes stay open for kubelet and
+// Docker healthchecks, diagnostics need the read scope when
+// HEALTH_AUTH_REQUIRE_READ is set, and anything that changes Tor's state
+// needs the control scope.
 func (h *Handler) SetupRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/ping", h.instrument("/ping", h.Ping))
-	mux.HandleFunc("/health", h.instrument("/health", h.Health))
-	mux.HandleFunc("/ready", h.instrument("/ready", h.Ready))
-	mux.HandleFunc("/status", h.instrument("/status", h.Status))
-	mux.HandleFunc("/renew", h.instrument("/renew", h.Renew))
-	mux.Handle("/metrics", promhttp.Handler())
+	h.SetupGroupRoutes(mux, config.RouteGroupProbes)
+	h.SetupGroupRoutes(mux, config.RouteGroupMetrics)
+	h.SetupGroupRoutes(mux, config.RouteGroupAdmin)
+}
+
+// SetupGroupRoutes registers the routes of a single group, so groups can be
+// served on separate listeners.
+func (h *Handler) SetupGroupRoutes(mux *http.ServeMux, group config.RouteGroup) {
+	switch group {
+	case config.RouteGroupProbes:
+		mux.HandleFunc("/ping", h.instrument("/ping", h.Ping))
+		mux.HandleFunc("/health", h.instrument("/health", h.Health))
+		mux.HandleFunc("/ready", h.instrument("/ready", h.Ready))
+	case config.RouteGroupMetrics:
+		mux.Handle("/metrics", h.authorize(config.ScopeRead, promhttp.Handler().ServeHTTP))
+	case config.RouteGroupAdmin:
+		mux.HandleFunc("/status", h.instrument("/status", h.authorize(config.ScopeRead, h.Status)))
+		mux.HandleFunc("/circuits", h.instrument("/circuits", h.authorize(config.ScopeRead, h.Circuits)))
+		mux.HandleFunc("/circuits/", h.instrument("/circuits/", h.authorize(config.ScopeControl, h.Circuit)))
+		mux.HandleFunc("/streams", h.instrument("/streams", h.authorize(config.ScopeRead, h.Streams)))
+		mux.HandleFunc("/streams/", h.instrument("/streams/", h.authorize(config.ScopeControl, h.Stream)))
+		mux.HandleFunc("/traffic", h.instrument("/traffic", h.authorize(config.ScopeRead, h.Traffic)))
+		mux.HandleFunc("/events", h.instrument("/events", h.authorize(config.ScopeRead, h.Events)))
+		mux.HandleFunc("/renew", h.instrument("/renew", h.authorize(config.ScopeControl, h.Renew)))
+		mux.HandleFunc("/onions", h.instrument("/onions", h.authorize(config.ScopeControl, h.Onions)))
+		mux.HandleFunc("/onions/", h.instrument("/onions/", h.authorize(config.ScopeControl, h.Onion)))
+		mux.HandleFunc("/config/exit", h.instrument("/config/exit", h.authorize(config.ScopeControl, h.ExitConfig)))
+		mux.HandleFunc("/admin/reload", h.instrument("/admin/reload", h.authorize(config.ScopeControl, h.AdminReload)))
+		mux.HandleFunc("/admin/log-level", h.instrument("/admin/log-level", h.authorize(config.ScopeControl, h.LogLevel)))
+	}
 }
 
 func (h *Handler) instrument(path string, next http.HandlerFunc) http.HandlerFunc {
@@ -271,14 +540,24 @@ func (r *statusRecorder) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController so
+// streaming handlers can flush through the recorder.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // sendWebhook sends a webhook notification if enabled and the event is configured
 func (h *Handler) sendWebhook(event notify.Event, message string, details notify.Details) {
-	if h.webhook == nil {
+	h.settingsMu.RLock()
+	webhook, webhookEvents, cfg := h.webhook, h.webhookEvents, h.config
+	h.settingsMu.RUnlock()
+
+	if webhook == nil {
 		return
 	}
 
 	// Check if this event is enabled in configuration
-	if !slices.Contains(h.webhookEvents, string(event)) {
+	if !slices.Contains(webhookEvents, string(event)) {
 		return
 	}
 
@@ -301,11 +580,11 @@ func (h *Handler) sendWebhook(event notify.Event, message string, details notify
 			}
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), h.config.WebhookTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.WebhookTimeout)
 		defer cancel()
 
 		start := time.Now()
-		err := h.webhook.Send(ctx, payload)
+		err := webhook.Send(ctx, payload)
 		duration := time.Since(start)
 
 		success := err == nil
@@ -328,12 +607,36 @@ func (h *Handler) sendWebhook(event notify.Event, message string, details notify
 	}()
 }
 
-// checkHealthStateChange detects health state transitions and sends EventHealthChanged webhook
-// Returns true if the state changed, false otherwise
-func (h *Handler) checkHealthStateChange(currentlyHealthy bool) bool {
+// checkHealthStateChange records Tor's bootstrap health and sends EventHealthChanged webhook
+// if overall health changed. Returns true if the state changed, false otherwise
+func (h *Handler) checkHealthStateChange(bootstrapped bool) bool {
 	h.healthMu.Lock()
 	defer h.healthMu.Unlock()
 
+	h.bootstrapHealthy = bootstrapped
+	return h.updateHealthLocked()
+}
+
+// checkEgressStateChange records whether Tor egress works, as decided by
+// automatic renewal giving up or a later egress check passing. It is kept
+// apart from bootstrap health so a bootstrapped Tor does not report healthy
+// while egress is still failing. Returns true if overall health changed.
+func (h *Handler) checkEgressStateChange(egressOK bool) bool {
+	h.healthMu.Lock()
+	defer h.healthMu.Unlock()
+
+	if h.egressFailed == !egressOK {
+		return false
+	}
+	h.egressFailed = !egressOK
+	return h.updateHealthLocked()
+}
+
+// updateHealthLocked compares overall health, bootstrapped with working
+// egress, against the previous state. healthMu must be held.
+func (h *Handler) updateHealthLocked() bool {
+	currentlyHealthy := h.bootstrapHealthy && !h.egressFailed
+
 	if h.previousHealthy == nil {
 		// First call - initialize state
 		h.previousHealthy = &currentlyHealthy
@@ -349,7 +652,7 @@ func (h *Handler) checkHealthStateChange(currentlyHealthy bool) bool {
 			message = "Tor health status changed to unhealthy"
 		}
 
-		h.sendWebhook(notify.EventHealthChanged, message, notify.Details{
+		h.emit(notify.EventHealthChanged, message, notify.Details{
 			Healthy: currentlyHealthy,
 		})
 
@@ -1,12 +1,19 @@
 package health
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/logging"
 	"github.com/eslutz/torarr/internal/tor"
 )
 
@@ -194,7 +201,7 @@ func TestNewHandler(t *testing.T) {
 	handler.SetupRoutes(mux)
 
 	// Test that all routes are registered by attempting to call them
-	routes := []string{"/ping", "/health", "/ready", "/status", "/metrics"}
+	routes := []string{"/ping", "/health", "/ready", "/status", "/circuits", "/streams", "/metrics"}
 
 	for _, route := range routes {
 		req := httptest.NewRequest(http.MethodGet, route, nil)
@@ -209,6 +216,29 @@ func TestNewHandler(t *testing.T) {
 	}
 }
 
+func TestSetupGroupRoutes(t *testing.T) {
+	handler := &Handler{config: &config.Config{}}
+	groups := map[config.RouteGroup][]string{
+		config.RouteGroupProbes:  {"/ping", "/health", "/ready"},
+		config.RouteGroupMetrics: {"/metrics"},
+		config.RouteGroupAdmin:   {"/status", "/circuits", "/streams", "/events", "/renew", "/config/exit", "/admin/reload", "/admin/log-level"},
+	}
+
+	for group := range groups {
+		mux := http.NewServeMux()
+		handler.SetupGroupRoutes(mux, group)
+
+		for other, otherRoutes := range groups {
+			for _, route := range otherRoutes {
+				_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, route, nil))
+				if registered := pattern != ""; registered != (other == group) {
+					t.Errorf("group %s: route %s registered = %v", group, route, registered)
+				}
+			}
+		}
+	}
+}
+
 func TestClose_WithNilTorClient(t *testing.T) {
 	handler := &Handler{
 		torClient: nil,
@@ -232,3 +262,371 @@ if err != nil {
 t.Errorf("expected no error, got %v", err)
 }
 }
+
+func TestHandleTorEvent_BootstrapTransitions(t *testing.T) {
+	handler := &Handler{}
+
+	handler.handleTorEvent(tor.Event{
+		Type: tor.EventStatusClient,
+		Data: `NOTICE BOOTSTRAP PROGRESS=50 TAG=loading_descriptors SUMMARY="Loading relay descriptors"`,
+	})
+	if handler.previousHealthy == nil || *handler.previousHealthy {
+		t.Fatal("expected partial bootstrap to record unhealthy state")
+	}
+
+	handler.handleTorEvent(tor.Event{
+		Type: tor.EventStatusClient,
+		Data: `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`,
+	})
+	if !*handler.previousHealthy {
+		t.Error("expected completed bootstrap to record healthy state")
+	}
+
+	// Non status events and unrelated actions leave state untouched
+	handler.handleTorEvent(tor.Event{Type: tor.EventCirc, Data: "1 FAILED"})
+	handler.handleTorEvent(tor.Event{Type: tor.EventStatusClient, Data: "NOTICE CIRCUIT_NOT_ESTABLISHED REASON=CLOCK_JUMPED"})
+	if !*handler.previousHealthy {
+		t.Error("expected state to remain healthy")
+	}
+}
+
+func TestSocksProxyURL(t *testing.T) {
+	tests := map[string]string{
+		"":               "socks5://127.0.0.1:9050",
+		"0.0.0.0:9050":   "socks5://127.0.0.1:9050",
+		"[::]:9150":      "socks5://127.0.0.1:9150",
+		"9060":           "socks5://127.0.0.1:9060",
+		"10.0.0.5:9050":  "socks5://10.0.0.5:9050",
+		"localhost:9050": "socks5://localhost:9050",
+	}
+	for address, expected := range tests {
+		if got := socksProxyURL(address); got != expected {
+			t.Errorf("socksProxyURL(%q) = %q, want %q", address, got, expected)
+		}
+	}
+}
+
+func TestHandleTorEvent_RelaysLogMessages(t *testing.T) {
+	var buf bytes.Buffer
+	logHandler, err := logging.NewHandler(&buf, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(logHandler))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	handler := &Handler{}
+	handler.handleTorEvent(tor.Event{Type: tor.EventWarn, Data: "Problem bootstrapping. Stuck at 10%."})
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "WARN" {
+		t.Errorf("expected level WARN, got %v", entry["level"])
+	}
+	if entry["severity"] != "warn" {
+		t.Errorf("expected severity warn, got %v", entry["severity"])
+	}
+	if entry["tor_message"] != "Problem bootstrapping. Stuck at 10%." {
+		t.Errorf("expected Tor message to be relayed, got %v", entry["tor_message"])
+	}
+}
+
+// startFakeTor runs a minimal Tor control port that offers NULL authentication
+// and delegates every other command to respond. It returns the listen address.
+func startFakeTor(t *testing.T, respond func(cmd string) string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.TrimRight(line, "\r\n")
+
+					var resp string
+					switch {
+					case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+						resp = "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250 OK\r\n"
+					case cmd == "AUTHENTICATE", strings.HasPrefix(cmd, "SETEVENTS"):
+						resp = "250 OK\r\n"
+					default:
+						resp = respond(cmd)
+					}
+					if _, err := conn.Write([]byte(resp)); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestCircuits_Success(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO circuit-status" {
+			return "250+circuit-status=\r\n" +
+				"1 BUILT $AAAA~guard,$BBBB~middle,$CCCC~exit PURPOSE=GENERAL TIME_CREATED=2024-05-01T12:30:45.000000\r\n" +
+				"2 LAUNCHED PURPOSE=GENERAL\r\n" +
+				".\r\n250 OK\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	req := httptest.NewRequest(http.MethodGet, "/circuits", nil)
+	w := httptest.NewRecorder()
+
+	handler.Circuits(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		NumCircuits int           `json:"num_circuits"`
+		Circuits    []tor.Circuit `json:"circuits"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.NumCircuits != 1 {
+		t.Errorf("expected 1 built circuit, got %d", response.NumCircuits)
+	}
+	if len(response.Circuits) != 2 {
+		t.Fatalf("expected 2 circuits, got %d", len(response.Circuits))
+	}
+	if exit, ok := response.Circuits[0].Exit(); !ok || exit.Nickname != "exit" {
+		t.Errorf("expected exit hop 'exit', got %+v", exit)
+	}
+}
+
+func TestCircuits_TorUnavailable(t *testing.T) {
+	handler := &Handler{torClient: tor.NewClient("127.0.0.1:1", "")}
+
+	req := httptest.NewRequest(http.MethodGet, "/circuits", nil)
+	w := httptest.NewRecorder()
+
+	handler.Circuits(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestStreams_Success(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO stream-status" {
+			return "250+stream-status=\r\n" +
+				"10 SUCCEEDED 4 indexer.example.com:443\r\n" +
+				"11 SENTCONNECT 4 api.example.org:443\r\n" +
+				"12 SUCCEEDED 5 203.0.113.7:80\r\n" +
+				".\r\n250 OK\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	req := httptest.NewRequest(http.MethodGet, "/streams", nil)
+	w := httptest.NewRecorder()
+
+	handler.Streams(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		NumStreams int            `json:"num_streams"`
+		ByStatus   map[string]int `json:"by_status"`
+		Streams    []tor.Stream   `json:"streams"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.NumStreams != 3 || len(response.Streams) != 3 {
+		t.Errorf("expected 3 streams, got %d (%d listed)", response.NumStreams, len(response.Streams))
+	}
+	if response.ByStatus["SUCCEEDED"] != 2 || response.ByStatus["SENTCONNECT"] != 1 {
+		t.Errorf("unexpected status counts: %v", response.ByStatus)
+	}
+	if response.Streams[0].Target != "indexer.example.com:443" || response.Streams[0].CircuitID != "4" {
+		t.Errorf("unexpected first stream: %+v", response.Streams[0])
+	}
+}
+
+func TestStreams_TorUnavailable(t *testing.T) {
+	handler := &Handler{torClient: tor.NewClient("127.0.0.1:1", "")}
+
+	req := httptest.NewRequest(http.MethodGet, "/streams", nil)
+	w := httptest.NewRecorder()
+
+	handler.Streams(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestExitConfig_Get(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		if cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes" {
+			return "250-ExitNodes={us},{ca}\r\n250-ExcludeExitNodes\r\n250 StrictNodes=1\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	req := httptest.NewRequest(http.MethodGet, "/config/exit", nil)
+	w := httptest.NewRecorder()
+
+	handler.ExitConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response tor.ExitPolicy
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if strings.Join(response.ExitNodes, ",") != "us,ca" || !response.StrictNodes {
+		t.Errorf("unexpected exit policy: %+v", response)
+	}
+}
+
+func TestExitConfig_Put(t *testing.T) {
+	var mu sync.Mutex
+	var commands []string
+	addr := startFakeTor(t, func(cmd string) string {
+		mu.Lock()
+		commands = append(commands, cmd)
+		mu.Unlock()
+		switch {
+		case cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes":
+			return "250-ExitNodes={us}\r\n250-ExcludeExitNodes\r\n250 StrictNodes=1\r\n"
+		case strings.HasPrefix(cmd, "SETCONF"), cmd == "SAVECONF":
+			return "250 OK\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	body := strings.NewReader(`{"exit_nodes":["DE","{nl}"],"save":true}`)
+	req := httptest.NewRequest(http.MethodPut, "/config/exit", body)
+	w := httptest.NewRecorder()
+
+	handler.ExitConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{
+		"GETCONF ExitNodes ExcludeExitNodes StrictNodes",
+		`SETCONF ExitNodes="{de},{nl}"`,
+		"SAVECONF",
+	}
+	if strings.Join(commands, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected commands %v, got %v", expected, commands)
+	}
+}
+
+func TestExitConfig_InvalidCountry(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		if cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes" {
+			return "250-ExitNodes\r\n250-ExcludeExitNodes\r\n250 StrictNodes=0\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	req := httptest.NewRequest(http.MethodPut, "/config/exit", strings.NewReader(`{"exit_nodes":["usa"]}`))
+	w := httptest.NewRecorder()
+
+	handler.ExitConfig(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestExitConfig_KeepsRouterSetEntries(t *testing.T) {
+	var mu sync.Mutex
+	var commands []string
+	addr := startFakeTor(t, func(cmd string) string {
+		mu.Lock()
+		commands = append(commands, cmd)
+		mu.Unlock()
+		if cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes" {
+			return "250-ExitNodes={us},$" + strings.Repeat("A", 40) + "\r\n250-ExcludeExitNodes\r\n250 StrictNodes=1\r\n"
+		}
+		return "250 OK\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client, config: &config.Config{TorrcGenerate: true}}
+
+	w := httptest.NewRecorder()
+	handler.ExitConfig(w, httptest.NewRequest(http.MethodPut, "/config/exit", strings.NewReader(`{"strict_nodes":false}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ExitConfig(w, httptest.NewRequest(http.MethodPut, "/config/exit", strings.NewReader(`{"exit_nodes":["de"],"save":true}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected save to be refused with TORRC_GENERATE, got %d: %s", w.Code, w.Body.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{
+		"GETCONF ExitNodes ExcludeExitNodes StrictNodes",
+		`SETCONF StrictNodes="0"`,
+		"GETCONF ExitNodes ExcludeExitNodes StrictNodes",
+	}
+	if strings.Join(commands, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected commands %v, got %v", expected, commands)
+	}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/logging"
+)
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevel handles GET and PUT /admin/log-level. A PUT changes the level
+// until the next restart or configuration reload, which restores LOG_LEVEL.
+func (h *Handler) LogLevel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLogLevelError(w, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+
+		if err := changeLogLevel(strings.ToUpper(strings.TrimSpace(req.Level))); err != nil {
+			writeLogLevelError(w, fmt.Errorf("invalid level %q (valid options: DEBUG, INFO, WARN, ERROR)", req.Level))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"status": "OK",
+		"level":  logging.Level().String(),
+	}); err != nil {
+		slog.Error("Failed to encode log level response", "error", err)
+	}
+}
+
+// changeLogLevel applies a log level at runtime. The change is logged at the
+// more verbose of the two levels while that level is still in effect, so it
+// is visible whichever way the level moves.
+func changeLogLevel(levelName string) error {
+	if !slices.Contains(logging.Levels, levelName) {
+		return fmt.Errorf("invalid log level %q", levelName)
+	}
+	var next slog.Level
+	if err := next.UnmarshalText([]byte(levelName)); err != nil {
+		return err
+	}
+
+	previous := logging.Level()
+	if next == previous {
+		return nil
+	}
+	logChange := func() {
+		slog.Log(context.Background(), min(previous, next), "Log level changed", "previous", previous, "level", next)
+	}
+	if next > previous {
+		logChange()
+	}
+	if err := logging.SetLevel(levelName); err != nil {
+		return err
+	}
+	if next < previous {
+		logChange()
+	}
+	return nil
+}
+
+func writeLogLevelError(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"status": "ERROR",
+		"error":  err.Error(),
+	}); err != nil {
+		slog.Error("Failed to encode log level response", "error", err)
+	}
+}
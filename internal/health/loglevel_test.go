@@ -0,0 +1,90 @@
+package health
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/logging"
+)
+
+func TestLogLevel(t *testing.T) {
+	defer func() { _ = logging.SetLevel("INFO") }()
+	if err := logging.SetLevel("INFO"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		code      int
+		wantLevel slog.Level
+	}{
+		{name: "get", method: http.MethodGet, code: http.StatusOK, wantLevel: slog.LevelInfo},
+		{name: "set debug", method: http.MethodPut, body: `{"level": "debug"}`, code: http.StatusOK, wantLevel: slog.LevelDebug},
+		{name: "invalid level", method: http.MethodPut, body: `{"level": "verbose"}`, code: http.StatusBadRequest, wantLevel: slog.LevelDebug},
+		{name: "level offset", method: http.MethodPut, body: `{"level": "INFO+2"}`, code: http.StatusBadRequest, wantLevel: slog.LevelDebug},
+		{name: "invalid body", method: http.MethodPut, body: `level=warn`, code: http.StatusBadRequest, wantLevel: slog.LevelDebug},
+		{name: "set warn", method: http.MethodPut, body: `{"level": "WARN"}`, code: http.StatusOK, wantLevel: slog.LevelWarn},
+		{name: "wrong method", method: http.MethodPost, code: http.StatusMethodNotAllowed, wantLevel: slog.LevelWarn},
+	}
+
+	handler := &Handler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.LogLevel(w, httptest.NewRequest(tt.method, "/admin/log-level", strings.NewReader(tt.body)))
+
+			if w.Code != tt.code {
+				t.Fatalf("expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if logging.Level() != tt.wantLevel {
+				t.Errorf("expected level %v, got %v", tt.wantLevel, logging.Level())
+			}
+			if tt.code == http.StatusOK {
+				var resp map[string]string
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp["level"] != tt.wantLevel.String() {
+					t.Errorf("expected level %q in response, got %q", tt.wantLevel, resp["level"])
+				}
+			}
+		})
+	}
+}
+
+func TestChangeLogLevel_LogsChange(t *testing.T) {
+	defer func() { _ = logging.SetLevel("INFO") }()
+	if err := logging.SetLevel("INFO"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	handler, err := logging.NewHandler(&buf, logging.FormatLogfmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	defer slog.SetDefault(defaultLogger)
+
+	// ERROR to WARN must not be logged at INFO, which neither level shows
+	for _, level := range []string{"ERROR", "WARN", "DEBUG", "INFO"} {
+		buf.Reset()
+		if err := changeLogLevel(level); err != nil {
+			t.Fatalf("changeLogLevel(%s) error = %v", level, err)
+		}
+		if !strings.Contains(buf.String(), `msg="Log level changed"`) {
+			t.Errorf("expected the change to %s to be logged, got %q", level, buf.String())
+		}
+	}
+
+	if err := changeLogLevel("debug-4"); err == nil {
+		t.Error("expected a level offset to be rejected")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/eslutz/torarr/internal/onion"
 	"github.com/eslutz/torarr/internal/tor"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -18,10 +19,35 @@ type metrics struct {
 	torReady         prometheus.Gauge
 	torBytesRead     prometheus.Gauge
 	torBytesWritten  prometheus.Gauge
+	torReadTotal     prometheus.Counter
+	torWrittenTotal  prometheus.Counter
+	torStreams       *prometheus.GaugeVec
+	torExitInfo      *prometheus.GaugeVec
 	externalAttempts *prometheus.CounterVec
+	circuitRenewals  *prometheus.CounterVec
+	circuitsClosed   *prometheus.CounterVec
+
+	readinessFailures  prometheus.Gauge
+	autoRenewAttempts  *prometheus.CounterVec
+	autoRenewExhausted prometheus.Counter
 
 	webhookRequests *prometheus.CounterVec
 	webhookDuration *prometheus.HistogramVec
+
+	configReloads *prometheus.CounterVec
+
+	torLogMessages *prometheus.CounterVec
+
+	torBridgeUp *prometheus.GaugeVec
+
+	onionPublished *prometheus.GaugeVec
+
+	socksListenerUp    *prometheus.GaugeVec
+	socksListenerReady *prometheus.GaugeVec
+
+	accountingBytes     *prometheus.GaugeVec
+	accountingBytesLeft *prometheus.GaugeVec
+	torHibernating      prometheus.Gauge
 }
 
 func newMetrics() *metrics {
@@ -55,10 +81,46 @@ func newMetrics() *metrics {
 			Name: "torarr_tor_bytes_written",
 			Help: "Bytes written as reported by Tor traffic stats.",
 		}),
+		torReadTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "torarr_tor_read_bytes_total",
+			Help: "Bytes read by Tor, counted from BW events since the health server started.",
+		}),
+		torWrittenTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "torarr_tor_written_bytes_total",
+			Help: "Bytes written by Tor, counted from BW events since the health server started.",
+		}),
+		torStreams: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_tor_streams",
+			Help: "Tor streams by state as reported by stream-status.",
+		}, []string{"status"}),
+		torExitInfo: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_tor_exit_info",
+			Help: "Exit relay of the most recent general-purpose circuit (always 1).",
+		}, []string{"fingerprint", "nickname", "ip", "country"}),
 		externalAttempts: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "torarr_external_check_total",
 			Help: "External check attempts with result labels.",
 		}, []string{"endpoint", "success", "is_tor"}),
+		circuitRenewals: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "torarr_circuit_renewals_total",
+			Help: "Circuit renewals (NEWNYM) sent, by trigger.",
+		}, []string{"trigger"}),
+		circuitsClosed: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "torarr_circuits_closed_total",
+			Help: "Circuits closed through the API, by target (circuit or exit).",
+		}, []string{"target"}),
+		readinessFailures: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "torarr_readiness_consecutive_failures",
+			Help: "Consecutive failed readiness checks seen by automatic circuit renewal.",
+		}),
+		autoRenewAttempts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "torarr_auto_renew_attempts_total",
+			Help: "Automatic circuit renewal attempts by result (recovered, failed, error).",
+		}, []string{"result"}),
+		autoRenewExhausted: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "torarr_auto_renew_exhausted_total",
+			Help: "Times automatic circuit renewal gave up after the maximum attempts.",
+		}),
 		webhookRequests: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "torarr_webhook_requests_total",
 			Help: "Total webhook notification attempts.",
@@ -68,6 +130,42 @@ func newMetrics() *metrics {
 			Help:    "Webhook notification duration.",
 			Buckets: prometheus.DefBuckets,
 		}, []string{"event"}),
+		configReloads: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "torarr_config_reloads_total",
+			Help: "Configuration reloads by result (success, rejected).",
+		}, []string{"result"}),
+		torLogMessages: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "torarr_tor_log_messages_total",
+			Help: "Tor log messages relayed from NOTICE, WARN and ERR events, by severity.",
+		}, []string{"severity"}),
+		torBridgeUp: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_tor_bridge_up",
+			Help: "Whether Tor reports a working connection to a configured bridge (1 = up, 0 = not).",
+		}, []string{"bridge"}),
+		onionPublished: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_onion_service_published",
+			Help: "Whether a hosted onion service's descriptor has been published since Tor started serving it (1 = yes, 0 = no).",
+		}, []string{"name"}),
+		socksListenerUp: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_socks_listener_up",
+			Help: "Whether Tor is accepting connections on a configured SOCKS listener (1 = yes, 0 = no).",
+		}, []string{"listener"}),
+		socksListenerReady: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_socks_listener_ready",
+			Help: "Whether the latest egress check through a SOCKS listener confirmed Tor routing (1 = yes, 0 = no).",
+		}, []string{"listener"}),
+		accountingBytes: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_tor_accounting_bytes",
+			Help: "Bytes used in the current Tor accounting period, by direction.",
+		}, []string{"direction"}),
+		accountingBytesLeft: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "torarr_tor_accounting_bytes_left",
+			Help: "Bytes left before Tor hibernates in the current accounting period, by direction.",
+		}, []string{"direction"}),
+		torHibernating: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "torarr_tor_hibernating",
+			Help: "Whether Tor is hibernating because it reached its accounting limit (1 = yes, 0 = no).",
+		}),
 	}
 }
 
@@ -90,10 +188,97 @@ func (m *metrics) observeTorStatus(status *tor.Status) {
 	m.torBytesWritten.Set(float64(status.Traffic.BytesWritten))
 }
 
+func (m *metrics) observeBandwidth(bw tor.BandwidthEvent) {
+	m.torReadTotal.Add(float64(bw.Read))
+	m.torWrittenTotal.Add(float64(bw.Written))
+}
+
+func (m *metrics) observeAccounting(accounting *tor.Accounting) {
+	m.accountingBytes.Reset()
+	m.accountingBytesLeft.Reset()
+	if !accounting.Enabled {
+		m.torHibernating.Set(0)
+		return
+	}
+
+	m.accountingBytes.WithLabelValues("read").Set(float64(accounting.BytesRead))
+	m.accountingBytes.WithLabelValues("written").Set(float64(accounting.BytesWritten))
+	m.accountingBytesLeft.WithLabelValues("read").Set(float64(accounting.ReadLeft))
+	m.accountingBytesLeft.WithLabelValues("written").Set(float64(accounting.WrittenLeft))
+	if accounting.LimitReached() {
+		m.torHibernating.Set(1)
+	} else {
+		m.torHibernating.Set(0)
+	}
+}
+
+func (m *metrics) observeStreams(streams []tor.Stream) {
+	counts := tor.CountStreamsByStatus(streams)
+	for _, status := range tor.StreamStatuses {
+		m.torStreams.WithLabelValues(status).Set(float64(counts[status]))
+	}
+}
+
+func (m *metrics) observeExit(exit *ExitInfo) {
+	m.torExitInfo.Reset()
+	if exit != nil {
+		m.torExitInfo.WithLabelValues(exit.Fingerprint, exit.Nickname, exit.IP, exit.Country).Set(1)
+	}
+}
+
+func (m *metrics) observeBridges(bridges []BridgeStatus) {
+	m.torBridgeUp.Reset()
+	for _, bridge := range bridges {
+		up := 0.0
+		if bridge.Reachable {
+			up = 1
+		}
+		m.torBridgeUp.WithLabelValues(bridge.Bridge).Set(up)
+	}
+}
+
+func (m *metrics) observeOnions(services []onion.Service) {
+	m.onionPublished.Reset()
+	for _, service := range services {
+		m.observeOnion(service)
+	}
+}
+
+func (m *metrics) observeOnion(service onion.Service) {
+	published := 0.0
+	if service.Published {
+		published = 1
+	}
+	m.onionPublished.WithLabelValues(service.Name).Set(published)
+}
+
+func (m *metrics) observeListeners(listeners []SocksListenerStatus) {
+	m.socksListenerUp.Reset()
+	for _, listener := range listeners {
+		up := 0.0
+		if listener.Listening {
+			up = 1
+		}
+		m.socksListenerUp.WithLabelValues(listener.Name).Set(up)
+	}
+}
+
+func (m *metrics) observeListenerReady(listener string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	m.socksListenerReady.WithLabelValues(listener).Set(value)
+}
+
 func (m *metrics) observeExternalCheck(endpoint string, success, isTor bool) {
 	m.externalAttempts.WithLabelValues(endpoint, strconv.FormatBool(success), strconv.FormatBool(isTor)).Inc()
 }
 
+func (m *metrics) observeRenewal(trigger string) {
+	m.circuitRenewals.WithLabelValues(trigger).Inc()
+}
+
 func (m *metrics) observeWebhook(event string, success bool, duration time.Duration) {
 	status := "success"
 	if !success {
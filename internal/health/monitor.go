@@ -0,0 +1,243 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// statusSnapshot is the most recent Tor status collected from the control port.
+type statusSnapshot struct {
+	status         *tor.Status
+	err            error
+	exit           *ExitInfo
+	bridges        []BridgeStatus
+	onions         *OnionSummary
+	socksListeners []SocksListenerStatus
+	accounting     *tor.Accounting
+	checkedAt      time.Time
+}
+
+// readinessSnapshot is the most recent external egress check.
+type readinessSnapshot struct {
+	result    *ExternalCheckResult
+	listeners map[string]*ExternalCheckResult // Checks through named SOCKS listeners
+	checkedAt time.Time
+}
+
+// RunMonitor periodically collects Tor status every HEALTH_CHECK_INTERVAL and
+// checks Tor egress every HEALTH_EXTERNAL_INTERVAL. The HTTP handlers serve
+// these cached results, and health transitions, webhooks and automatic
+// renewal are driven from here rather than by incoming probes. It blocks
+// until ctx is cancelled.
+func (h *Handler) RunMonitor(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		h.monitorStatus(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		h.monitorReadiness(ctx)
+	}()
+	wg.Wait()
+}
+
+func (h *Handler) monitorStatus(ctx context.Context) {
+	ticker := time.NewTicker(h.currentConfig().HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.collectStatus()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) monitorReadiness(ctx context.Context) {
+	ticker := time.NewTicker(h.currentConfig().HealthExternalInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		snapshot := h.checkReadiness()
+
+		if h.currentConfig().AutoRenewEnabled {
+			if readinessOK(snapshot.result) {
+				failures = 0
+			} else {
+				failures++
+				slog.Warn("Readiness check failed",
+					"consecutive_failures", failures,
+					"threshold", h.currentConfig().AutoRenewFailureThreshold,
+					"error", readinessError(snapshot.result),
+				)
+			}
+
+			if failures >= h.currentConfig().AutoRenewFailureThreshold {
+				if h.metrics != nil {
+					h.metrics.readinessFailures.Set(float64(failures))
+				}
+				h.remediate(ctx)
+				failures = 0
+			}
+			if h.metrics != nil {
+				h.metrics.readinessFailures.Set(float64(failures))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectStatus queries Tor, caches the result and reports health transitions.
+func (h *Handler) collectStatus() *statusSnapshot {
+	status, err := h.torClient.GetStatus()
+	snapshot := &statusSnapshot{status: status, err: err, checkedAt: time.Now()}
+
+	if err != nil {
+		// Tor may be restarting, and would then lose limits set with SETCONF
+		h.resetTrafficLimits()
+	} else {
+		h.ensureTrafficLimits()
+
+		// Exit details are best effort; a partially resolved exit is still reported
+		exit, exitErr := h.resolveExit()
+		if exitErr != nil {
+			slog.Debug("Failed to resolve exit relay", "error", exitErr)
+		}
+		snapshot.exit = exit
+		snapshot.bridges = h.collectBridges()
+		snapshot.onions = h.collectOnions()
+		snapshot.socksListeners = h.collectSocksListeners()
+		snapshot.accounting = h.collectAccounting()
+		h.collectStreams()
+	}
+
+	h.cacheMu.Lock()
+	h.statusCache = snapshot
+	h.cacheMu.Unlock()
+
+	if h.metrics != nil {
+		if err != nil {
+			h.metrics.torReady.Set(0)
+		} else {
+			h.metrics.observeTorStatus(status)
+			h.metrics.observeExit(snapshot.exit)
+		}
+	}
+
+	switch {
+	case err != nil:
+		// Check for health state change first to avoid duplicate notifications
+		if !h.checkHealthStateChange(false) {
+			h.emit(notify.EventBootstrapFailed, "Tor bootstrap failed", notify.Details{
+				Error: err.Error(),
+			})
+		}
+	case status.BootstrapPhase < 100:
+		if !h.checkHealthStateChange(false) {
+			h.emit(notify.EventBootstrapFailed, "Tor bootstrap incomplete", notify.Details{
+				Bootstrap: &status.BootstrapPhase,
+				Circuits:  status.NumCircuits,
+			})
+		}
+	default:
+		h.checkHealthStateChange(true)
+	}
+
+	return snapshot
+}
+
+// collectStreams refreshes the stream gauges so /metrics does not depend on
+// anyone calling /streams.
+func (h *Handler) collectStreams() {
+	if h.metrics == nil {
+		return
+	}
+
+	streams, err := h.torClient.GetStreams()
+	if err != nil {
+		slog.Debug("Failed to query Tor streams", "error", err)
+		return
+	}
+	h.metrics.observeStreams(streams)
+}
+
+// checkReadiness runs the external egress check, through the default SOCKS
+// port and each named listener, and caches the results.
+func (h *Handler) checkReadiness() *readinessSnapshot {
+	result := h.checker().Check()
+	snapshot := &readinessSnapshot{result: result, listeners: h.checkListeners(), checkedAt: time.Now()}
+
+	h.cacheMu.Lock()
+	h.readinessCache = snapshot
+	h.cacheMu.Unlock()
+
+	if h.metrics != nil {
+		h.metrics.observeExternalCheck(result.Endpoint, result.Success, result.IsTor)
+		h.metrics.observeListenerReady(config.DefaultSocksListener, readinessOK(result))
+	}
+
+	message := "Tor egress check passed"
+	if !readinessOK(result) {
+		message = "Tor egress check failed"
+	} else {
+		// Clears a failure recorded when automatic renewal gave up
+		h.checkEgressStateChange(true)
+	}
+	h.publish(notify.EventReadinessChecked, message, notify.Details{
+		Healthy:  readinessOK(result),
+		IsTor:    &result.IsTor,
+		IP:       result.IP,
+		Endpoint: result.Endpoint,
+		Error:    result.Error,
+	})
+
+	return snapshot
+}
+
+// cachedStatus returns the monitor's latest status, collecting one
+// synchronously if the monitor has not run yet.
+func (h *Handler) cachedStatus() *statusSnapshot {
+	h.cacheMu.RLock()
+	snapshot := h.statusCache
+	h.cacheMu.RUnlock()
+
+	if snapshot == nil {
+		snapshot = h.collectStatus()
+	}
+	return snapshot
+}
+
+// cachedReadiness returns the monitor's latest egress check, running one
+// synchronously if the monitor has not run yet.
+func (h *Handler) cachedReadiness() *readinessSnapshot {
+	h.cacheMu.RLock()
+	snapshot := h.readinessCache
+	h.cacheMu.RUnlock()
+
+	if snapshot == nil {
+		snapshot = h.checkReadiness()
+	}
+	return snapshot
+}
+
+// ageSeconds reports how stale a cached result is, for HTTP responses.
+func ageSeconds(checkedAt time.Time) float64 {
+	return time.Since(checkedAt).Round(time.Millisecond).Seconds()
+}
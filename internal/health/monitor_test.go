@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// bootstrapTor serves GETINFO status queries with an adjustable bootstrap
+// progress and counts how often Tor was queried.
+type bootstrapTor struct {
+	mu       sync.Mutex
+	progress int
+	queries  int
+}
+
+func (b *bootstrapTor) respond(cmd string) string {
+	if cmd != "GETINFO version status/bootstrap-phase status/circuit-established traffic/read traffic/written circuit-status" {
+		return "552 Unrecognized key\r\n"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queries++
+	return "250-version=0.4.8.10\r\n" +
+		fmt.Sprintf("250-status/bootstrap-phase=NOTICE BOOTSTRAP PROGRESS=%d TAG=done SUMMARY=\"Done\"\r\n", b.progress) +
+		"250-status/circuit-established=1\r\n" +
+		"250 OK\r\n"
+}
+
+func (b *bootstrapTor) set(progress int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.progress = progress
+}
+
+func (b *bootstrapTor) Queries() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queries
+}
+
+func TestHealth_ServesCachedStatus(t *testing.T) {
+	fake := &bootstrapTor{progress: 100}
+	client := tor.NewClient(startFakeTor(t, fake.respond), "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if _, ok := response["age_seconds"]; !ok {
+			t.Error("expected age_seconds in health response")
+		}
+	}
+
+	if fake.Queries() != 1 {
+		t.Errorf("expected probes to share one cached status query, got %d", fake.Queries())
+	}
+}
+
+func TestCollectStatus_HealthTransitions(t *testing.T) {
+	fake := &bootstrapTor{progress: 50}
+	client := tor.NewClient(startFakeTor(t, fake.respond), "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	handler.collectStatus()
+	if handler.previousHealthy == nil || *handler.previousHealthy {
+		t.Fatal("expected partial bootstrap to record unhealthy state")
+	}
+
+	w := httptest.NewRecorder()
+	handler.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	fake.set(100)
+	handler.collectStatus()
+	if !*handler.previousHealthy {
+		t.Error("expected completed bootstrap to record healthy state")
+	}
+
+	w = httptest.NewRecorder()
+	handler.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestReady_ServesCachedCheck(t *testing.T) {
+	var mu sync.Mutex
+	checks := 0
+	checker := startTorCheck(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		checks++
+		return true
+	})
+	handler := &Handler{readinessChecker: checker}
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.Ready(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response struct {
+			IsTor      bool     `json:"is_tor"`
+			AgeSeconds *float64 `json:"age_seconds"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !response.IsTor || response.AgeSeconds == nil {
+			t.Errorf("unexpected ready response: %+v", response)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if checks != 1 {
+		t.Errorf("expected probes to share one cached egress check, got %d", checks)
+	}
+}
+
+func TestRunMonitor_AutoRenewThreshold(t *testing.T) {
+	counter := &newnymCounter{}
+	handler := newRemediationHandler(t, counter, func() bool { return false }, &config.Config{
+		HealthCheckInterval:       time.Hour,
+		HealthExternalInterval:    10 * time.Millisecond,
+		AutoRenewEnabled:          true,
+		AutoRenewFailureThreshold: 2,
+		AutoRenewMaxAttempts:      1,
+		AutoRenewRecheckDelay:     time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handler.RunMonitor(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for counter.Count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if counter.Count() != 1 {
+		t.Errorf("expected 1 automatic NEWNYM, got %d", counter.Count())
+	}
+}
@@ -0,0 +1,219 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/onion"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// OnionSummary counts the hosted onion services on /status.
+type OnionSummary struct {
+	Total     int `json:"total"`
+	Published int `json:"published"`
+}
+
+// onionRequest is the body of POST /onions.
+type onionRequest struct {
+	onion.Spec
+	ClientAuthGenerate bool `json:"client_auth_generate"` // Add a generated client key and return its private half once
+}
+
+// Onions lists (GET) or creates (POST) hosted onion services.
+func (h *Handler) Onions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		services, err := h.onions.List()
+		if err != nil {
+			h.writeOnionError(w, http.StatusInternalServerError, err)
+			return
+		}
+		h.writeOnionResponse(w, http.StatusOK, map[string]interface{}{
+			"status": "OK",
+			"onions": services,
+		})
+	case http.MethodPost:
+		h.createOnion(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) createOnion(w http.ResponseWriter, r *http.Request) {
+	var req onionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeOnionError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	var clientPrivateKey string
+	if req.ClientAuthGenerate {
+		publicKey, privateKey, err := onion.GenerateClientAuth()
+		if err != nil {
+			h.writeOnionError(w, http.StatusInternalServerError, err)
+			return
+		}
+		req.ClientAuth = append(req.ClientAuth, publicKey)
+		clientPrivateKey = privateKey
+	}
+
+	if err := req.Validate(); err != nil {
+		h.writeOnionError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	service, err := h.onions.Create(req.Spec)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, onion.ErrExists) {
+			status = http.StatusConflict
+		}
+		h.writeOnionError(w, status, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"status": "OK",
+		"onion":  service,
+	}
+	if clientPrivateKey != "" {
+		response["client_auth_private"] = onion.ClientAuthLine(service.ServiceID(), clientPrivateKey)
+	}
+	h.writeOnionResponse(w, http.StatusCreated, response)
+}
+
+// Onion shows (GET) or removes (DELETE) the service named by /onions/<name>.
+// Removing a service deletes its key, so its address cannot be reused.
+func (h *Handler) Onion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/onions/")
+
+	if r.Method == http.MethodDelete {
+		if err := h.onions.Remove(name); err != nil {
+			h.writeOnionError(w, onionErrorStatus(err), err)
+			return
+		}
+		if h.metrics != nil {
+			h.metrics.onionPublished.DeleteLabelValues(name)
+		}
+		h.writeOnionResponse(w, http.StatusOK, map[string]interface{}{
+			"status":  "OK",
+			"message": "Onion service removed",
+		})
+		return
+	}
+
+	service, err := h.onions.Get(name)
+	if err != nil {
+		h.writeOnionError(w, onionErrorStatus(err), err)
+		return
+	}
+	h.writeOnionResponse(w, http.StatusOK, map[string]interface{}{
+		"status": "OK",
+		"onion":  service,
+	})
+}
+
+func onionErrorStatus(err error) int {
+	if errors.Is(err, onion.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+func (h *Handler) writeOnionResponse(w http.ResponseWriter, status int, response map[string]interface{}) {
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("Failed to encode onion response", "error", err)
+	}
+}
+
+func (h *Handler) writeOnionError(w http.ResponseWriter, status int, err error) {
+	h.writeOnionResponse(w, status, map[string]interface{}{
+		"status": "ERROR",
+		"error":  err.Error(),
+	})
+}
+
+// collectOnions re-adds stored onion services Tor is not serving, such as
+// after Tor restarted, and summarizes whether their descriptors are
+// published. It returns nil when no services are hosted.
+func (h *Handler) collectOnions() *OnionSummary {
+	if h.onions == nil {
+		return nil
+	}
+
+	if err := h.onions.Restore(); err != nil {
+		slog.Warn("Failed to restore onion services", "error", err)
+	}
+	services, err := h.onions.List()
+	if err != nil {
+		slog.Warn("Failed to list onion services", "error", err)
+		return nil
+	}
+
+	if h.metrics != nil {
+		h.metrics.observeOnions(services)
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	summary := &OnionSummary{Total: len(services)}
+	for _, service := range services {
+		if service.Published {
+			summary.Published++
+		}
+	}
+	return summary
+}
+
+// handleOnionEvent tracks descriptor uploads of hosted onion services from
+// HS_DESC events.
+func (h *Handler) handleOnionEvent(evt tor.Event) {
+	if h.onions == nil {
+		return
+	}
+
+	desc, err := tor.ParseHSDescEvent(evt)
+	if err != nil {
+		slog.Warn("Failed to parse Tor HS_DESC event", "error", err)
+		return
+	}
+	service, ok := h.onions.HandleDescEvent(desc)
+	if !ok {
+		return
+	}
+
+	if h.metrics != nil {
+		h.metrics.observeOnion(service)
+	}
+
+	details := notify.Details{Onion: service.Address, Reason: desc.Reason}
+	switch desc.Action {
+	case "UPLOADED":
+		slog.Debug("Onion service descriptor published", "name", service.Name, "hsdir", desc.HSDir)
+		h.publish(notify.EventOnionPublished, "Onion service descriptor published", details)
+	case "FAILED":
+		slog.Warn("Onion service descriptor upload failed",
+			"name", service.Name,
+			"hsdir", desc.HSDir,
+			"reason", desc.Reason,
+		)
+		h.publish(notify.EventOnionPublishFailed, "Onion service descriptor upload failed", details)
+	}
+}
@@ -0,0 +1,196 @@
+package health
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/onion"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+const testServiceID = "bnnbnwvxbpqmhvmp4xbkj6dbyxqy66eyq7kbffoy6jfmbvnpqvnbkyid"
+
+var testOnionKey = "ED25519-V3:" + base64.StdEncoding.EncodeToString(make([]byte, 64))
+
+// startOnionTor runs a fake Tor that hosts at most testServiceID and records
+// the onion commands it receives.
+func startOnionTor(t *testing.T) (*tor.Client, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var commands []string
+	running := false
+
+	addr := startFakeTor(t, func(cmd string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		commands = append(commands, cmd)
+
+		switch {
+		case strings.HasPrefix(cmd, "ADD_ONION NEW:"):
+			running = true
+			return "250-ServiceID=" + testServiceID + "\r\n250-PrivateKey=" + testOnionKey + "\r\n250 OK\r\n"
+		case strings.HasPrefix(cmd, "ADD_ONION "+testOnionKey):
+			running = true
+			return "250-ServiceID=" + testServiceID + "\r\n250 OK\r\n"
+		case cmd == "DEL_ONION "+testServiceID && running:
+			running = false
+			return "250 OK\r\n"
+		case cmd == "GETINFO onions/detached" && running:
+			return "250-onions/detached=" + testServiceID + "\r\n250 OK\r\n"
+		case cmd == "GETINFO onions/detached":
+			return "551 No onion services of the specified type.\r\n"
+		}
+		return "552 Unrecognized command\r\n"
+	})
+
+	client := tor.NewClient(addr, "")
+	t.Cleanup(func() { _ = client.Close() })
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func serveOnions(handler *Handler, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/onions", handler.Onions)
+	mux.HandleFunc("/onions/", handler.Onion)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestOnions_Lifecycle(t *testing.T) {
+	client, commands := startOnionTor(t)
+	handler := &Handler{torClient: client, onions: onion.NewManager(client, t.TempDir())}
+
+	w := serveOnions(handler, http.MethodPost, "/onions",
+		`{"name":"sonarr","ports":[{"virtual":80,"target":"sonarr:8989"}],"client_auth_generate":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct {
+		Onion             onion.Service `json:"onion"`
+		ClientAuthPrivate string        `json:"client_auth_private"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Onion.Address != testServiceID+".onion" || len(created.Onion.ClientAuth) != 1 {
+		t.Errorf("unexpected onion service: %+v", created.Onion)
+	}
+	if !strings.HasPrefix(created.ClientAuthPrivate, testServiceID+":descriptor:x25519:") {
+		t.Errorf("expected a client authorization line, got %q", created.ClientAuthPrivate)
+	}
+	addCmd := commands()[0]
+	if !strings.Contains(addCmd, "Flags=Detach,V3Auth") || !strings.Contains(addCmd, "Port=80,sonarr:8989") ||
+		!strings.Contains(addCmd, "ClientAuthV3="+created.Onion.ClientAuth[0]) {
+		t.Errorf("unexpected ADD_ONION command %q", addCmd)
+	}
+
+	if w := serveOnions(handler, http.MethodPost, "/onions", `{"name":"sonarr","ports":[{"virtual":80}]}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a duplicate name, got %d", http.StatusConflict, w.Code)
+	}
+
+	w = serveOnions(handler, http.MethodGet, "/onions", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"sonarr"`) {
+		t.Errorf("expected the service to be listed, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), testOnionKey) {
+		t.Error("expected the private key to stay out of responses")
+	}
+
+	if w := serveOnions(handler, http.MethodGet, "/onions/sonarr", ""); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if w := serveOnions(handler, http.MethodDelete, "/onions/sonarr", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if cmds := commands(); cmds[len(cmds)-1] != "DEL_ONION "+testServiceID {
+		t.Errorf("expected DEL_ONION, got %q", cmds[len(cmds)-1])
+	}
+	if w := serveOnions(handler, http.MethodGet, "/onions/sonarr", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d after removal, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestOnions_InvalidRequests(t *testing.T) {
+	client, commands := startOnionTor(t)
+	handler := &Handler{torClient: client, onions: onion.NewManager(client, t.TempDir())}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "malformed body", method: http.MethodPost, path: "/onions", body: `{`, want: http.StatusBadRequest},
+		{name: "invalid name", method: http.MethodPost, path: "/onions", body: `{"name":"../x","ports":[{"virtual":80}]}`, want: http.StatusBadRequest},
+		{name: "no ports", method: http.MethodPost, path: "/onions", body: `{"name":"sonarr"}`, want: http.StatusBadRequest},
+		{name: "injected target", method: http.MethodPost, path: "/onions", body: `{"name":"sonarr","ports":[{"virtual":80,"target":"a:1 Flags=x"}]}`, want: http.StatusBadRequest},
+		{name: "bad client key", method: http.MethodPost, path: "/onions", body: `{"name":"sonarr","ports":[{"virtual":80}],"client_auth":["abc"]}`, want: http.StatusBadRequest},
+		{name: "unknown service", method: http.MethodDelete, path: "/onions/radarr", want: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPut, path: "/onions", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveOnions(handler, tt.method, tt.path, tt.body); w.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+	if cmds := commands(); len(cmds) != 0 {
+		t.Errorf("expected invalid requests not to reach Tor, got %q", cmds)
+	}
+}
+
+func TestCollectOnions_RestoresAndTracksPublishing(t *testing.T) {
+	client, commands := startOnionTor(t)
+	dir := t.TempDir()
+	if _, err := onion.NewManager(client, dir).Create(onion.Spec{Name: "sonarr", Ports: []tor.OnionPort{{Virtual: 80}}}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := client.DelOnion(testServiceID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh handler, as after a restart, re-adds the stored service
+	handler := &Handler{torClient: client, events: newEventBroker(), onions: onion.NewManager(client, dir)}
+	summary := handler.collectOnions()
+	if summary == nil || summary.Total != 1 || summary.Published != 0 {
+		t.Fatalf("expected one unpublished service, got %+v", summary)
+	}
+	if cmds := commands(); !strings.HasPrefix(cmds[len(cmds)-1], "ADD_ONION "+testOnionKey) {
+		t.Errorf("expected the stored key to be re-added, got %q", cmds[len(cmds)-1])
+	}
+
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	handler.handleTorEvent(tor.Event{Type: tor.EventHSDesc, Data: "UPLOADED " + testServiceID + " UNKNOWN $AAAA~hsdir"})
+	select {
+	case payload := <-ch:
+		if payload.Event != notify.EventOnionPublished || payload.Details.Onion != testServiceID+".onion" {
+			t.Errorf("unexpected event: %+v", payload)
+		}
+	default:
+		t.Fatal("expected an onion_published event")
+	}
+
+	if summary := handler.collectOnions(); summary.Published != 1 {
+		t.Errorf("expected the service to be published, got %+v", summary)
+	}
+}
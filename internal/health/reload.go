@@ -0,0 +1,198 @@
+package health
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/config"
+)
+
+// reloadableSettings are the config keys Reload applies to the running
+// server. Changes to any other key are reported but need a restart.
+var reloadableSettings = []string{
+	"log_level",
+	"health_external_endpoints",
+	"health_external_timeout",
+	"webhook_url",
+	"webhook_template",
+	"webhook_events",
+	"webhook_timeout",
+	"tor_socks_port",
+	"tor_socks_isolation",
+	"tor_socks_listeners",
+	"tor_bandwidth_rate",
+	"tor_bandwidth_burst",
+	"tor_accounting_max",
+	"tor_accounting_start",
+}
+
+// ReloadResult describes the outcome of a configuration reload.
+type ReloadResult struct {
+	Changed         []string `json:"changed"`
+	RestartRequired []string `json:"restart_required"`
+	TorReloaded     bool     `json:"tor_reloaded"`
+	TorError        string   `json:"tor_error,omitempty"`
+}
+
+// Reload reads the configuration again and applies the reloadable settings
+// without restarting the HTTP servers, then sends SIGNAL RELOAD so Tor
+// re-reads its torrc. SOCKS listeners and traffic limits are then applied
+// with SETCONF. Configuration is loaded in strict mode: if any value is invalid
+// the reload is rejected and the running configuration is kept.
+func (h *Handler) Reload() (*ReloadResult, error) {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	cfg, err := h.loadConfig()
+	if err != nil {
+		h.observeReload("rejected")
+		slog.Error("Configuration reload rejected; keeping current configuration", "error", err)
+		return nil, err
+	}
+
+	current := h.currentConfig()
+	changed, restartRequired := diffConfig(current, cfg)
+
+	applied := *current
+	applied.LogLevel = cfg.LogLevel
+	applied.HealthExternalEndpoints = cfg.HealthExternalEndpoints
+	applied.HealthExternalTimeout = cfg.HealthExternalTimeout
+	applied.WebhookURL = cfg.WebhookURL
+	applied.WebhookTemplate = cfg.WebhookTemplate
+	applied.WebhookEvents = cfg.WebhookEvents
+	applied.WebhookTimeout = cfg.WebhookTimeout
+	applied.TorSocksPort = cfg.TorSocksPort
+	applied.TorSocksIsolation = cfg.TorSocksIsolation
+	applied.TorSocksListeners = cfg.TorSocksListeners
+	applied.TorBandwidthRate = cfg.TorBandwidthRate
+	applied.TorBandwidthBurst = cfg.TorBandwidthBurst
+	applied.TorAccountingMax = cfg.TorAccountingMax
+	applied.TorAccountingStart = cfg.TorAccountingStart
+	socksChanged := slices.ContainsFunc(changed, func(key string) bool {
+		return slices.Contains(socksSettings, key)
+	})
+	trafficChanged := slices.ContainsFunc(changed, func(key string) bool {
+		return slices.Contains(trafficSettings, key)
+	})
+
+	h.settingsMu.Lock()
+	h.config = &applied
+	if slices.Contains(changed, "health_external_endpoints") || slices.Contains(changed, "health_external_timeout") || socksChanged {
+		h.readinessChecker = newReadinessChecker(&applied)
+		h.listenerCheckers = newListenerCheckers(&applied)
+	}
+	if slices.Contains(changed, "webhook_url") || slices.Contains(changed, "webhook_template") {
+		h.webhook = newWebhook(&applied)
+	}
+	h.webhookEvents = applied.WebhookEvents
+	h.settingsMu.Unlock()
+
+	if err := changeLogLevel(applied.LogLevel); err != nil {
+		slog.Error("Failed to apply log level", "level", applied.LogLevel, "error", err)
+	}
+
+	h.observeReload("success")
+	if len(restartRequired) > 0 {
+		slog.Warn("Some changed settings only take effect after a restart", "settings", restartRequired)
+	}
+	slog.Info("Configuration reloaded", "changed", changed)
+
+	result := &ReloadResult{
+		Changed:         changed,
+		RestartRequired: restartRequired,
+	}
+	if err := h.torClient.Signal("RELOAD"); err != nil {
+		slog.Error("Failed to reload Tor configuration", "error", err)
+		result.TorError = err.Error()
+	} else {
+		result.TorReloaded = true
+	}
+
+	// SIGNAL RELOAD restores the SocksPorts and limits from torrc, so
+	// changed listeners and configured limits are applied afterwards
+	if socksChanged {
+		if h.metrics != nil {
+			h.metrics.socksListenerReady.Reset()
+		}
+		if err := h.applySocksListeners(&applied); err != nil {
+			slog.Error("Failed to apply SOCKS listeners", "error", err)
+			result.addTorError(err)
+		}
+	}
+	h.resetTrafficLimits()
+	if trafficChanged {
+		if err := h.applyTrafficLimits(&applied); err != nil {
+			slog.Error("Failed to apply Tor traffic limits", "error", err)
+			result.addTorError(err)
+		}
+	} else {
+		h.ensureTrafficLimits()
+	}
+
+	return result, nil
+}
+
+// addTorError records a failure to apply settings to Tor.
+func (r *ReloadResult) addTorError(err error) {
+	if r.TorError != "" {
+		r.TorError += "; "
+	}
+	r.TorError += err.Error()
+}
+
+func (h *Handler) observeReload(result string) {
+	if h.metrics != nil {
+		h.metrics.configReloads.WithLabelValues(result).Inc()
+	}
+}
+
+// diffConfig lists the config keys whose values differ, split into those
+// Reload applies and those that need a restart.
+func diffConfig(current, next *config.Config) (changed, restartRequired []string) {
+	changed, restartRequired = []string{}, []string{}
+
+	cv, nv := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		key, _, _ := strings.Cut(cv.Type().Field(i).Tag.Get("config"), ",")
+		if key == "" || reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		if slices.Contains(reloadableSettings, key) {
+			changed = append(changed, key)
+		} else {
+			restartRequired = append(restartRequired, key)
+		}
+	}
+	return changed, restartRequired
+}
+
+// AdminReload handles POST /admin/reload, the HTTP equivalent of SIGHUP.
+func (h *Handler) AdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	result, err := h.Reload()
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"status": "ERROR",
+			"error":  err.Error(),
+		}); err != nil {
+			slog.Error("Failed to encode reload error response", "error", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.Error("Failed to encode reload response", "error", err)
+	}
+}
@@ -0,0 +1,187 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// signalRecorder answers SIGNAL commands from a fake Tor and remembers them.
+type signalRecorder struct {
+	mu      sync.Mutex
+	signals []string
+	fail    bool
+}
+
+func (s *signalRecorder) respond(cmd string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals = append(s.signals, cmd)
+	if s.fail {
+		return "552 Unrecognized signal\r\n"
+	}
+	return "250 OK\r\n"
+}
+
+func (s *signalRecorder) Signals() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.signals)
+}
+
+func newReloadHandler(t *testing.T, recorder *signalRecorder, current *config.Config, load func() (*config.Config, error)) *Handler {
+	t.Helper()
+
+	client := tor.NewClient(startFakeTor(t, recorder.respond), "")
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &Handler{
+		torClient:        client,
+		readinessChecker: newReadinessChecker(current),
+		config:           current,
+		webhookEvents:    current.WebhookEvents,
+		loadConfig:       load,
+	}
+}
+
+func reloadTestConfig() *config.Config {
+	return &config.Config{
+		HealthPort:              "9091",
+		HealthExternalEndpoints: []string{"https://check.torproject.org/api/ip"},
+		HealthExternalTimeout:   15,
+		LogLevel:                "INFO",
+		WebhookEvents:           []string{"circuit_renewed"},
+		WebhookTimeout:          10 * time.Second,
+	}
+}
+
+func TestReload_AppliesSettings(t *testing.T) {
+	recorder := &signalRecorder{}
+	current := reloadTestConfig()
+
+	next := reloadTestConfig()
+	next.HealthPort = "9191"
+	next.HealthExternalEndpoints = []string{"https://ipinfo.io/json"}
+	next.WebhookURL = "https://example.com/hook"
+	next.WebhookTemplate = "json"
+	next.WebhookEvents = []string{"health_changed"}
+	next.WebhookTimeout = 5 * time.Second
+
+	handler := newReloadHandler(t, recorder, current, func() (*config.Config, error) { return next, nil })
+	oldChecker := handler.checker()
+
+	result, err := handler.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	expectedChanged := []string{"health_external_endpoints", "webhook_url", "webhook_template", "webhook_events", "webhook_timeout"}
+	if !slices.Equal(result.Changed, expectedChanged) {
+		t.Errorf("expected changed %v, got %v", expectedChanged, result.Changed)
+	}
+	if !slices.Equal(result.RestartRequired, []string{"health_port"}) {
+		t.Errorf("expected health_port to require a restart, got %v", result.RestartRequired)
+	}
+	if !result.TorReloaded {
+		t.Errorf("expected Tor to be reloaded, got error %q", result.TorError)
+	}
+	if signals := recorder.Signals(); !slices.Equal(signals, []string{"SIGNAL RELOAD"}) {
+		t.Errorf("expected SIGNAL RELOAD to be sent, got %v", signals)
+	}
+
+	cfg := handler.currentConfig()
+	if cfg.HealthPort != "9091" {
+		t.Errorf("expected restart-only HealthPort to stay 9091, got %q", cfg.HealthPort)
+	}
+	if cfg.WebhookTimeout != 5*time.Second {
+		t.Errorf("expected WebhookTimeout 5s, got %v", cfg.WebhookTimeout)
+	}
+	if handler.checker() == oldChecker {
+		t.Error("expected readiness checker to be rebuilt for new endpoints")
+	}
+	if handler.webhook == nil {
+		t.Error("expected webhook to be created")
+	}
+	if !slices.Equal(handler.webhookEvents, []string{"health_changed"}) {
+		t.Errorf("expected webhook events to be updated, got %v", handler.webhookEvents)
+	}
+}
+
+func TestReload_RejectsInvalidConfig(t *testing.T) {
+	recorder := &signalRecorder{}
+	current := reloadTestConfig()
+	handler := newReloadHandler(t, recorder, current, func() (*config.Config, error) {
+		return nil, &config.ValidationError{Problems: []error{errors.New("Invalid log level")}}
+	})
+
+	if _, err := handler.Reload(); err == nil {
+		t.Fatal("expected invalid configuration to be rejected")
+	}
+	if handler.currentConfig() != current {
+		t.Error("expected current configuration to be kept")
+	}
+	if signals := recorder.Signals(); len(signals) != 0 {
+		t.Errorf("expected Tor not to be reloaded, got %v", signals)
+	}
+}
+
+func TestReload_TorFailure(t *testing.T) {
+	recorder := &signalRecorder{fail: true}
+	next := reloadTestConfig()
+	next.WebhookTimeout = time.Second
+	handler := newReloadHandler(t, recorder, reloadTestConfig(), func() (*config.Config, error) { return next, nil })
+
+	result, err := handler.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if result.TorReloaded || result.TorError == "" {
+		t.Errorf("expected Tor reload failure to be reported, got %+v", result)
+	}
+	if handler.currentConfig().WebhookTimeout != time.Second {
+		t.Error("expected health server settings to be applied despite the Tor failure")
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		load   func() (*config.Config, error)
+		code   int
+	}{
+		{name: "success", method: http.MethodPost, load: func() (*config.Config, error) { return reloadTestConfig(), nil }, code: http.StatusOK},
+		{name: "rejected", method: http.MethodPost, load: func() (*config.Config, error) { return nil, errors.New("bad config") }, code: http.StatusUnprocessableEntity},
+		{name: "wrong method", method: http.MethodGet, code: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newReloadHandler(t, &signalRecorder{}, reloadTestConfig(), tt.load)
+
+			w := httptest.NewRecorder()
+			handler.AdminReload(w, httptest.NewRequest(tt.method, "/admin/reload", nil))
+
+			if w.Code != tt.code {
+				t.Fatalf("expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if tt.code == http.StatusOK {
+				var result ReloadResult
+				if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if !result.TorReloaded || len(result.Changed) != 0 {
+					t.Errorf("expected an unchanged reload of Tor, got %+v", result)
+				}
+			}
+		})
+	}
+}
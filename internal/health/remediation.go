@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+)
+
+// Remediation attempt results, used as the torarr_auto_renew_attempts_total label.
+const (
+	remediationRecovered = "recovered"
+	remediationFailed    = "failed"
+	remediationError     = "error"
+)
+
+// remediate is called by the monitor after AUTO_RENEW_FAILURE_THRESHOLD
+// consecutive failed readiness checks. It renews circuits and re-checks until
+// egress recovers or AUTO_RENEW_MAX_ATTEMPTS run out, in which case it gives
+// up and notifies.
+func (h *Handler) remediate(ctx context.Context) {
+	var lastErr string
+
+	for attempt := 1; attempt <= h.currentConfig().AutoRenewMaxAttempts; attempt++ {
+		if err := h.renewForRemediation(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Automatic circuit renewal failed", "attempt", attempt, "error", err)
+			h.observeRemediation(remediationError)
+			lastErr = err.Error()
+			continue
+		}
+
+		if !sleepContext(ctx, h.currentConfig().AutoRenewRecheckDelay) {
+			return
+		}
+
+		result := h.checkReadiness().result
+		if readinessOK(result) {
+			slog.Info("Automatic circuit renewal restored Tor egress", "attempt", attempt, "ip", result.IP)
+			h.observeRemediation(remediationRecovered)
+			return
+		}
+
+		lastErr = readinessError(result)
+		slog.Warn("Tor egress still failing after automatic circuit renewal",
+			"attempt", attempt,
+			"max_attempts", h.currentConfig().AutoRenewMaxAttempts,
+			"error", lastErr,
+		)
+		h.observeRemediation(remediationFailed)
+	}
+
+	slog.Error("Giving up on automatic circuit renewal",
+		"attempts", h.currentConfig().AutoRenewMaxAttempts,
+		"error", lastErr,
+	)
+	if h.metrics != nil {
+		h.metrics.autoRenewExhausted.Inc()
+	}
+
+	// health_changed replaces bootstrap_failed when this is a transition
+	if !h.checkEgressStateChange(false) {
+		h.emit(notify.EventBootstrapFailed,
+			fmt.Sprintf("Tor egress still failing after %d automatic circuit renewals", h.currentConfig().AutoRenewMaxAttempts),
+			notify.Details{
+				Trigger: RenewTriggerAuto,
+				Error:   lastErr,
+			})
+	}
+}
+
+// renewForRemediation sends NEWNYM, waiting out Tor's rate limit if a renewal
+// was sent moments ago.
+func (h *Handler) renewForRemediation(ctx context.Context) error {
+	for {
+		err := h.renewCircuit(RenewTriggerAuto)
+
+		var rateLimited *RateLimitedError
+		if !errors.As(err, &rateLimited) {
+			return err
+		}
+		if !sleepContext(ctx, rateLimited.RetryAfter) {
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *Handler) observeRemediation(result string) {
+	if h.metrics != nil {
+		h.metrics.autoRenewAttempts.WithLabelValues(result).Inc()
+	}
+}
+
+func readinessOK(result *ExternalCheckResult) bool {
+	return result.Success && result.IsTor
+}
+
+func readinessError(result *ExternalCheckResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	if !result.IsTor {
+		return fmt.Sprintf("%s reports traffic is not exiting through Tor", result.Endpoint)
+	}
+	return ""
+}
+
+// sleepContext waits for d and reports false if ctx was cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
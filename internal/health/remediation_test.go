@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// startTorCheck serves a check.torproject.org style response whose IsTor
+// value is decided per request.
+func startTorCheck(t *testing.T, isTor func() bool) *ExternalChecker {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"IsTor":%t,"IP":"203.0.113.9"}`, isTor())
+	}))
+	t.Cleanup(server.Close)
+
+	return NewExternalChecker([]string{server.URL + "/check.torproject.org/api/ip"}, time.Second, "")
+}
+
+func newRemediationHandler(t *testing.T, counter *newnymCounter, isTor func() bool, cfg *config.Config) *Handler {
+	t.Helper()
+
+	client := tor.NewClient(startFakeTor(t, counter.respond), "")
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &Handler{
+		torClient:        client,
+		readinessChecker: startTorCheck(t, isTor),
+		config:           cfg,
+	}
+}
+
+func TestRemediate_Recovers(t *testing.T) {
+	counter := &newnymCounter{}
+	handler := newRemediationHandler(t, counter, func() bool { return counter.Count() > 0 }, &config.Config{
+		AutoRenewMaxAttempts:  3,
+		AutoRenewRecheckDelay: time.Millisecond,
+	})
+
+	handler.remediate(context.Background())
+
+	if counter.Count() != 1 {
+		t.Errorf("expected 1 NEWNYM before egress recovered, got %d", counter.Count())
+	}
+	if handler.previousHealthy != nil {
+		t.Error("expected no health state change after recovery")
+	}
+}
+
+func TestRemediate_GivesUp(t *testing.T) {
+	counter := &newnymCounter{}
+	handler := newRemediationHandler(t, counter, func() bool { return false }, &config.Config{
+		AutoRenewMaxAttempts:  1,
+		AutoRenewRecheckDelay: time.Millisecond,
+	})
+
+	handler.remediate(context.Background())
+
+	if counter.Count() != 1 {
+		t.Errorf("expected 1 NEWNYM, got %d", counter.Count())
+	}
+	if handler.previousHealthy == nil || *handler.previousHealthy {
+		t.Error("expected giving up to record unhealthy state")
+	}
+}
+
+func TestRemediate_Cancelled(t *testing.T) {
+	counter := &newnymCounter{}
+	handler := newRemediationHandler(t, counter, func() bool { return false }, &config.Config{
+		AutoRenewMaxAttempts:  3,
+		AutoRenewRecheckDelay: time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	done := make(chan struct{})
+	go func() {
+		handler.remediate(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected remediation to stop when cancelled")
+	}
+	if handler.previousHealthy != nil {
+		t.Error("expected cancelled remediation not to give up")
+	}
+}
+
+func TestRemediate_GivesUpThenStatusTick(t *testing.T) {
+	var egressOK atomic.Bool
+	counter := &newnymCounter{}
+	bootstrap := &bootstrapTor{progress: 100}
+	client := tor.NewClient(startFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "GETINFO version ") {
+			return bootstrap.respond(cmd)
+		}
+		return counter.respond(cmd)
+	}), "")
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &Handler{
+		torClient:        client,
+		readinessChecker: startTorCheck(t, egressOK.Load),
+		config:           &config.Config{AutoRenewMaxAttempts: 1, AutoRenewRecheckDelay: time.Millisecond},
+		events:           newEventBroker(),
+	}
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	healthChanges := func() []bool {
+		var changes []bool
+		for len(ch) > 0 {
+			if payload := <-ch; payload.Event == notify.EventHealthChanged {
+				changes = append(changes, payload.Details.Healthy)
+			}
+		}
+		return changes
+	}
+
+	handler.collectStatus()
+	handler.remediate(context.Background())
+	handler.collectStatus()
+	if changes := healthChanges(); !slices.Equal(changes, []bool{false}) {
+		t.Errorf("expected only an unhealthy transition while egress fails, got %v", changes)
+	}
+
+	egressOK.Store(true)
+	handler.checkReadiness()
+	handler.collectStatus()
+	if changes := healthChanges(); !slices.Equal(changes, []bool{true}) {
+		t.Errorf("expected a healthy transition once egress recovers, got %v", changes)
+	}
+}
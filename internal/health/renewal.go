@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/schedule"
+)
+
+// newnymInterval mirrors Tor's own NEWNYM rate limit. Tor accepts signals
+// sent sooner but silently delays them, so they are rejected here instead.
+const newnymInterval = 10 * time.Second
+
+// Renewal triggers, used as the torarr_circuit_renewals_total label and in
+// circuit_renewed webhooks.
+const (
+	RenewTriggerManual    = "manual"
+	RenewTriggerScheduled = "scheduled"
+	RenewTriggerAuto      = "auto"
+)
+
+// RateLimitedError is returned when a renewal is requested before Tor's
+// NEWNYM rate limit has elapsed.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("circuit renewal rate limited, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// renewCircuit sends NEWNYM, records the renewal and emits the
+// circuit_renewed webhook labelled with trigger.
+func (h *Handler) renewCircuit(trigger string) error {
+	h.renewMu.Lock()
+	if since := time.Since(h.lastRenewal); !h.lastRenewal.IsZero() && since < newnymInterval {
+		h.renewMu.Unlock()
+		return &RateLimitedError{RetryAfter: newnymInterval - since}
+	}
+
+	if err := h.torClient.Signal("NEWNYM"); err != nil {
+		h.renewMu.Unlock()
+		return err
+	}
+	h.lastRenewal = time.Now()
+	h.renewMu.Unlock()
+
+	if h.metrics != nil {
+		h.metrics.observeRenewal(trigger)
+	}
+	slog.Info("Tor circuit renewal requested", "trigger", trigger)
+
+	// Build details for webhook notification using current Tor status, if available
+	status, err := h.torClient.GetStatus()
+	if err != nil {
+		// If we can't retrieve status, skip sending a potentially misleading webhook
+		slog.Warn("Failed to get Tor status after NEWNYM; skipping circuit renewal webhook", "error", err)
+		h.publish(notify.EventCircuitRenewed, "Tor circuit renewal requested", notify.Details{
+			Trigger: trigger,
+		})
+		return nil
+	}
+
+	h.emit(notify.EventCircuitRenewed, "Tor circuit renewal requested", notify.Details{
+		Circuits: status.NumCircuits,
+		Healthy:  status.CircuitEstablished,
+		Trigger:  trigger,
+	})
+
+	return nil
+}
+
+// RunRenewalSchedule renews circuits on CIRCUIT_RENEW_INTERVAL and/or
+// CIRCUIT_RENEW_CRON, whichever comes first, delaying each renewal by a
+// random amount up to CIRCUIT_RENEW_JITTER. It returns immediately when no
+// schedule is configured and otherwise blocks until ctx is cancelled.
+func (h *Handler) RunRenewalSchedule(ctx context.Context) {
+	var schedules []schedule.Schedule
+	if h.currentConfig().CircuitRenewInterval > 0 {
+		schedules = append(schedules, schedule.Every(h.currentConfig().CircuitRenewInterval))
+	}
+	if h.currentConfig().CircuitRenewCron != "" {
+		cron, err := schedule.ParseCron(h.currentConfig().CircuitRenewCron)
+		if err != nil {
+			slog.Error("Invalid circuit renewal cron expression", "cron", h.currentConfig().CircuitRenewCron, "error", err)
+		} else {
+			schedules = append(schedules, cron)
+		}
+	}
+	if len(schedules) == 0 {
+		return
+	}
+
+	slog.Info("Scheduled circuit renewal enabled",
+		"interval", h.currentConfig().CircuitRenewInterval,
+		"cron", h.currentConfig().CircuitRenewCron,
+		"jitter", h.currentConfig().CircuitRenewJitter,
+	)
+
+	for {
+		next := schedule.Earliest(time.Now(), schedules...)
+		if next.IsZero() {
+			slog.Warn("Circuit renewal schedule has no upcoming run; stopping")
+			return
+		}
+		if jitter := h.currentConfig().CircuitRenewJitter; jitter > 0 {
+			next = next.Add(rand.N(jitter))
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := h.renewCircuit(RenewTriggerScheduled); err != nil {
+			slog.Warn("Scheduled circuit renewal failed", "error", err)
+		}
+	}
+}
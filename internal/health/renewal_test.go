@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// newnymCounter is a fake Tor responder that counts NEWNYM signals.
+type newnymCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (n *newnymCounter) respond(cmd string) string {
+	if cmd == "SIGNAL NEWNYM" {
+		n.mu.Lock()
+		n.count++
+		n.mu.Unlock()
+		return "250 OK\r\n"
+	}
+	return "510 Unrecognized command\r\n"
+}
+
+func (n *newnymCounter) Count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.count
+}
+
+func TestRenewCircuit_RateLimited(t *testing.T) {
+	counter := &newnymCounter{}
+	client := tor.NewClient(startFakeTor(t, counter.respond), "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client}
+
+	if err := handler.renewCircuit(RenewTriggerManual); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := handler.renewCircuit(RenewTriggerScheduled)
+	var rateLimited *RateLimitedError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+	if rateLimited.RetryAfter <= 0 || rateLimited.RetryAfter > newnymInterval {
+		t.Errorf("unexpected retry after: %v", rateLimited.RetryAfter)
+	}
+
+	if counter.Count() != 1 {
+		t.Errorf("expected 1 NEWNYM, got %d", counter.Count())
+	}
+}
+
+func TestRenew_RateLimited(t *testing.T) {
+	counter := &newnymCounter{}
+	client := tor.NewClient(startFakeTor(t, counter.respond), "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{torClient: client, lastRenewal: time.Now()}
+
+	req := httptest.NewRequest(http.MethodPost, "/renew", nil)
+	w := httptest.NewRecorder()
+
+	handler.Renew(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+		t.Errorf("expected Retry-After header, got %q", retryAfter)
+	}
+	if counter.Count() != 0 {
+		t.Errorf("expected no NEWNYM, got %d", counter.Count())
+	}
+}
+
+func TestRunRenewalSchedule_Interval(t *testing.T) {
+	counter := &newnymCounter{}
+	client := tor.NewClient(startFakeTor(t, counter.respond), "")
+	defer func() { _ = client.Close() }()
+	handler := &Handler{
+		torClient: client,
+		config:    &config.Config{CircuitRenewInterval: 10 * time.Millisecond},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handler.RunRenewalSchedule(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for counter.Count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// Later ticks fall inside the NEWNYM rate limit and are skipped
+	if counter.Count() != 1 {
+		t.Errorf("expected exactly 1 scheduled NEWNYM, got %d", counter.Count())
+	}
+}
+
+func TestRunRenewalSchedule_Disabled(t *testing.T) {
+	handler := &Handler{config: &config.Config{}}
+
+	done := make(chan struct{})
+	go func() {
+		handler.RunRenewalSchedule(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected RunRenewalSchedule to return without a schedule")
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	err := &RateLimitedError{RetryAfter: 4200 * time.Millisecond}
+	if !strings.Contains(err.Error(), "4s") {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+// socksSettings are the config keys that make up Tor's SocksPort lines.
+var socksSettings = []string{"tor_socks_port", "tor_socks_isolation", "tor_socks_listeners"}
+
+// SocksListenerStatus reports whether Tor has a configured SOCKS listener open.
+type SocksListenerStatus struct {
+	Name      string   `json:"name"`
+	Address   string   `json:"address"`
+	Flags     []string `json:"flags,omitempty"`
+	Listening bool     `json:"listening"`
+}
+
+// newListenerCheckers returns an egress checker for each named SOCKS
+// listener. The default listener uses the readiness checker.
+func newListenerCheckers(cfg *config.Config) map[string]*ExternalChecker {
+	checkers := make(map[string]*ExternalChecker, len(cfg.TorSocksListeners))
+	for _, listener := range cfg.TorSocksListeners {
+		checkers[listener.Name] = NewExternalChecker(
+			cfg.HealthExternalEndpoints,
+			time.Duration(cfg.HealthExternalTimeout)*time.Second,
+			socksProxyURL(listener.Address),
+		)
+	}
+	return checkers
+}
+
+func (h *Handler) listenerCheckerSet() map[string]*ExternalChecker {
+	h.settingsMu.RLock()
+	defer h.settingsMu.RUnlock()
+	return h.listenerCheckers
+}
+
+// checkListeners runs the egress check through every named SOCKS listener
+// in parallel, so each application's identity is verified separately.
+func (h *Handler) checkListeners() map[string]*ExternalCheckResult {
+	checkers := h.listenerCheckerSet()
+	if len(checkers) == 0 {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]*ExternalCheckResult, len(checkers))
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := checker.Check()
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for name, result := range results {
+		h.observeListenerCheck(name, result)
+	}
+	return results
+}
+
+// observeListenerCheck records a listener's egress check in metrics and on
+// /events.
+func (h *Handler) observeListenerCheck(name string, result *ExternalCheckResult) {
+	if h.metrics != nil {
+		h.metrics.observeListenerReady(name, readinessOK(result))
+	}
+
+	message := fmt.Sprintf("Tor egress check through SOCKS listener %s passed", name)
+	if !readinessOK(result) {
+		message = fmt.Sprintf("Tor egress check through SOCKS listener %s failed", name)
+	}
+	h.publish(notify.EventReadinessChecked, message, notify.Details{
+		Healthy:  readinessOK(result),
+		IsTor:    &result.IsTor,
+		IP:       result.IP,
+		Endpoint: result.Endpoint,
+		Error:    result.Error,
+		Listener: name,
+	})
+}
+
+// collectSocksListeners reports which configured SOCKS listeners Tor has
+// open, matched to GETINFO net/listeners/socks by port. It returns nil if
+// Tor cannot be queried.
+func (h *Handler) collectSocksListeners() []SocksListenerStatus {
+	cfg := h.currentConfig()
+	if cfg == nil {
+		return nil
+	}
+
+	addresses, err := h.torClient.GetSocksListeners()
+	if err != nil {
+		slog.Debug("Failed to query Tor SOCKS listeners", "error", err)
+		return nil
+	}
+	open := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		if _, port, err := net.SplitHostPort(address); err == nil {
+			open[port] = true
+		}
+	}
+
+	listeners := cfg.SocksListeners()
+	statuses := make([]SocksListenerStatus, 0, len(listeners))
+	for _, listener := range listeners {
+		statuses = append(statuses, SocksListenerStatus{
+			Name:      listener.Name,
+			Address:   listener.Address,
+			Flags:     listener.Flags,
+			Listening: open[torrc.AddressPort(listener.Address)],
+		})
+	}
+
+	if h.metrics != nil {
+		h.metrics.observeListeners(statuses)
+	}
+	return statuses
+}
+
+// applySocksListeners replaces Tor's SocksPort lines with the configured
+// listeners, opening added ones and closing removed ones.
+func (h *Handler) applySocksListeners(cfg *config.Config) error {
+	listeners := cfg.SocksListeners()
+	values := make([]string, len(listeners))
+	for i, listener := range listeners {
+		values[i] = listener.SocksPort().Value()
+	}
+	return h.torClient.SetSocksPorts(values)
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+func TestReady_Listener(t *testing.T) {
+	handler := &Handler{
+		readinessChecker: startTorCheck(t, func() bool { return true }),
+		listenerCheckers: map[string]*ExternalChecker{
+			"sonarr": startTorCheck(t, func() bool { return false }),
+			"radarr": startTorCheck(t, func() bool { return true }),
+		},
+		events: newEventBroker(),
+	}
+
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+	handler.checkReadiness()
+
+	listeners := map[string]bool{}
+	for len(ch) > 0 {
+		payload := <-ch
+		if payload.Event == notify.EventReadinessChecked && payload.Details.Listener != "" {
+			listeners[payload.Details.Listener] = payload.Details.Healthy
+		}
+	}
+	if len(listeners) != 2 || listeners["sonarr"] || !listeners["radarr"] {
+		t.Errorf("expected a readiness event per listener, got %v", listeners)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/ready", want: http.StatusOK},
+		{path: "/ready?listener=default", want: http.StatusOK},
+		{path: "/ready?listener=radarr", want: http.StatusOK},
+		{path: "/ready?listener=sonarr", want: http.StatusServiceUnavailable},
+		{path: "/ready?listener=lidarr", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.Ready(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.path, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCollectSocksListeners(t *testing.T) {
+	addr := startFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO net/listeners/socks" {
+			return "250-net/listeners/socks=\"0.0.0.0:9050\"\r\n250 OK\r\n"
+		}
+		return "250 OK\r\n"
+	})
+	client := tor.NewClient(addr, "")
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &Handler{torClient: client, config: &config.Config{
+		TorSocksPort: "0.0.0.0:9050",
+		TorSocksListeners: []config.SocksListener{
+			{Name: "sonarr", Address: "0.0.0.0:9060", Flags: []string{"IsolateDestAddr"}},
+		},
+	}}
+
+	statuses := handler.collectSocksListeners()
+	expected := []SocksListenerStatus{
+		{Name: "default", Address: "0.0.0.0:9050", Listening: true},
+		{Name: "sonarr", Address: "0.0.0.0:9060", Flags: []string{"IsolateDestAddr"}, Listening: false},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d listeners, got %+v", len(expected), statuses)
+	}
+	for i := range expected {
+		if statuses[i].Name != expected[i].Name || statuses[i].Listening != expected[i].Listening ||
+			!slices.Equal(statuses[i].Flags, expected[i].Flags) {
+			t.Errorf("expected %+v, got %+v", expected[i], statuses[i])
+		}
+	}
+
+	if statuses := (&Handler{torClient: client}).collectSocksListeners(); statuses != nil {
+		t.Errorf("expected no listeners without a configuration, got %+v", statuses)
+	}
+}
+
+func TestReload_AppliesSocksListeners(t *testing.T) {
+	recorder := &signalRecorder{}
+	current := reloadTestConfig()
+	current.TorSocksPort = "0.0.0.0:9050"
+
+	next := reloadTestConfig()
+	next.TorSocksPort = "0.0.0.0:9050"
+	next.TorSocksListeners = []config.SocksListener{
+		{Name: "sonarr", Address: "0.0.0.0:9060", Flags: []string{"IsolateDestAddr"}},
+	}
+
+	handler := newReloadHandler(t, recorder, current, func() (*config.Config, error) { return next, nil })
+
+	result, err := handler.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !slices.Equal(result.Changed, []string{"tor_socks_listeners"}) || len(result.RestartRequired) != 0 {
+		t.Errorf("expected listeners to be reloadable, got %+v", result)
+	}
+
+	// SETCONF must follow SIGNAL RELOAD, which would otherwise discard it
+	expected := []string{
+		"SIGNAL RELOAD",
+		`SETCONF SocksPort="0.0.0.0:9050" SocksPort="0.0.0.0:9060 IsolateDestAddr"`,
+	}
+	if commands := recorder.Signals(); !slices.Equal(commands, expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+	if _, ok := handler.listenerCheckerSet()["sonarr"]; !ok {
+		t.Error("expected a readiness checker for the new listener")
+	}
+}
@@ -0,0 +1,275 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// trafficSettings are the config keys applied to Tor by SetTrafficLimits.
+var trafficSettings = []string{"tor_bandwidth_rate", "tor_bandwidth_burst", "tor_accounting_max", "tor_accounting_start"}
+
+// defaultTrafficWindow is the /traffic window when none is requested.
+const defaultTrafficWindow = 5 * time.Minute
+
+// maxTrafficPoints bounds the samples in a /traffic response when no step
+// is requested.
+const maxTrafficPoints = 300
+
+// TrafficSample is the bytes Tor transferred during a step of /traffic.
+type TrafficSample struct {
+	Time    time.Time `json:"time"`
+	Read    int64     `json:"read"`
+	Written int64     `json:"written"`
+}
+
+// trafficHistory is a ring buffer of the per-second samples Tor reports in
+// BW events. A nil history records nothing.
+type trafficHistory struct {
+	mu      sync.Mutex
+	samples []TrafficSample
+	next    int
+	full    bool
+}
+
+func newTrafficHistory(history time.Duration) *trafficHistory {
+	return &trafficHistory{samples: make([]TrafficSample, int(history/time.Second))}
+}
+
+func (t *trafficHistory) add(sample TrafficSample) {
+	if t == nil || len(t.samples) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.next] = sample
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// since returns the samples taken after cutoff, oldest first.
+func (t *trafficHistory) since(cutoff time.Time) []TrafficSample {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ordered := t.samples[:t.next]
+	if t.full {
+		ordered = append(slices.Clone(t.samples[t.next:]), ordered...)
+	}
+
+	start, _ := slices.BinarySearchFunc(ordered, cutoff, func(sample TrafficSample, cutoff time.Time) int {
+		if sample.Time.After(cutoff) {
+			return 1
+		}
+		return -1
+	})
+	return slices.Clone(ordered[start:])
+}
+
+// length is how much history the buffer can hold.
+func (t *trafficHistory) length() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(len(t.samples)) * time.Second
+}
+
+// handleBandwidthEvent records a BW event, which Tor sends every second
+// with the bytes transferred in that second.
+func (h *Handler) handleBandwidthEvent(evt tor.Event) {
+	bw, err := tor.ParseBandwidthEvent(evt)
+	if err != nil {
+		slog.Warn("Failed to parse Tor bandwidth event", "error", err)
+		return
+	}
+
+	h.traffic.add(TrafficSample{Time: evt.Received, Read: bw.Read, Written: bw.Written})
+	if h.metrics != nil {
+		h.metrics.observeBandwidth(bw)
+	}
+}
+
+// Traffic serves the traffic history: totals, average and peak rates over
+// ?window (default 5m), and samples summed per ?step.
+func (h *Handler) Traffic(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	window, step, err := trafficRange(r, h.traffic.length())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"status": "ERROR",
+			"error":  err.Error(),
+		}); err != nil {
+			slog.Error("Failed to encode traffic response", "error", err)
+		}
+		return
+	}
+
+	samples := h.traffic.since(time.Now().Add(-window))
+	var read, written, peakRead, peakWritten int64
+	buckets := []TrafficSample{}
+	for _, sample := range samples {
+		read += sample.Read
+		written += sample.Written
+		peakRead = max(peakRead, sample.Read)
+		peakWritten = max(peakWritten, sample.Written)
+
+		bucket := sample.Time.Truncate(step)
+		if n := len(buckets); n > 0 && buckets[n-1].Time.Equal(bucket) {
+			buckets[n-1].Read += sample.Read
+			buckets[n-1].Written += sample.Written
+			continue
+		}
+		buckets = append(buckets, TrafficSample{Time: bucket, Read: sample.Read, Written: sample.Written})
+	}
+
+	// Each sample covers one second, so the averages only count the time
+	// history was recorded for
+	var readRate, writtenRate float64
+	if len(samples) > 0 {
+		readRate = float64(read) / float64(len(samples))
+		writtenRate = float64(written) / float64(len(samples))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":            "OK",
+		"window_seconds":    window.Seconds(),
+		"step_seconds":      step.Seconds(),
+		"bytes_read":        read,
+		"bytes_written":     written,
+		"read_rate":         readRate,
+		"written_rate":      writtenRate,
+		"peak_read_rate":    peakRead,
+		"peak_written_rate": peakWritten,
+		"samples":           buckets,
+	}); err != nil {
+		slog.Error("Failed to encode traffic response", "error", err)
+	}
+}
+
+// trafficRange reads ?window and ?step. Without a step, samples are summed
+// so at most maxTrafficPoints are returned.
+func trafficRange(r *http.Request, history time.Duration) (window, step time.Duration, err error) {
+	window = min(defaultTrafficWindow, history)
+	if value := r.URL.Query().Get("window"); value != "" {
+		if window, err = time.ParseDuration(value); err != nil || window < time.Second || window > history {
+			return 0, 0, fmt.Errorf("invalid window %q: must be between 1s and %s", value, history)
+		}
+	}
+
+	step = max(time.Second, (window / maxTrafficPoints).Round(time.Second))
+	if value := r.URL.Query().Get("step"); value != "" {
+		if step, err = time.ParseDuration(value); err != nil || step < time.Second || step > window {
+			return 0, 0, fmt.Errorf("invalid step %q: must be between 1s and the window", value)
+		}
+	}
+	return window.Truncate(time.Second), step.Truncate(time.Second), nil
+}
+
+// trafficLimits returns the configured bandwidth and accounting limits.
+func trafficLimits(cfg *config.Config) tor.TrafficLimits {
+	return tor.TrafficLimits{
+		BandwidthRate:   cfg.TorBandwidthRate,
+		BandwidthBurst:  cfg.TorBandwidthBurst,
+		AccountingMax:   cfg.TorAccountingMax,
+		AccountingStart: cfg.TorAccountingStart,
+	}
+}
+
+// applyTrafficLimits sets the configured limits with SETCONF, so they take
+// effect whether or not the torrc is generated.
+func (h *Handler) applyTrafficLimits(cfg *config.Config) error {
+	if err := h.torClient.SetTrafficLimits(trafficLimits(cfg)); err != nil {
+		return err
+	}
+
+	h.trafficMu.Lock()
+	h.limitsApplied = true
+	h.trafficMu.Unlock()
+	return nil
+}
+
+// ensureTrafficLimits applies configured limits once per Tor session. Limits
+// that are not configured are left as the torrc sets them.
+func (h *Handler) ensureTrafficLimits() {
+	h.trafficMu.Lock()
+	applied := h.limitsApplied
+	h.trafficMu.Unlock()
+
+	cfg := h.currentConfig()
+	if applied || cfg == nil || trafficLimits(cfg) == (tor.TrafficLimits{}) {
+		return
+	}
+
+	if err := h.applyTrafficLimits(cfg); err != nil {
+		slog.Warn("Failed to apply Tor traffic limits", "error", err)
+		return
+	}
+	slog.Info("Applied Tor traffic limits",
+		"bandwidth_rate", cfg.TorBandwidthRate,
+		"bandwidth_burst", cfg.TorBandwidthBurst,
+		"accounting_max", cfg.TorAccountingMax,
+		"accounting_start", cfg.TorAccountingStart,
+	)
+}
+
+// resetTrafficLimits marks the limits for re-applying, for when Tor may
+// have restarted or re-read its torrc.
+func (h *Handler) resetTrafficLimits() {
+	h.trafficMu.Lock()
+	h.limitsApplied = false
+	h.trafficMu.Unlock()
+}
+
+// collectAccounting reads Tor's accounting state and sends
+// accounting_limit_reached when Tor starts hibernating. It returns nil when
+// accounting is disabled or cannot be read.
+func (h *Handler) collectAccounting() *tor.Accounting {
+	accounting, err := h.torClient.GetAccounting()
+	if err != nil {
+		slog.Debug("Failed to query Tor accounting", "error", err)
+		return nil
+	}
+	if h.metrics != nil {
+		h.metrics.observeAccounting(accounting)
+	}
+	if !accounting.Enabled {
+		return nil
+	}
+
+	reached := accounting.LimitReached()
+	h.trafficMu.Lock()
+	changed := reached != h.accountingLimitReached
+	h.accountingLimitReached = reached
+	h.trafficMu.Unlock()
+
+	switch {
+	case changed && reached:
+		message := fmt.Sprintf("Tor reached its accounting limit and is hibernating until %s",
+			accounting.IntervalEnd.Format(time.RFC3339))
+		slog.Warn(message, "hibernating", accounting.Hibernating)
+		h.emit(notify.EventAccountingLimitReached, message, notify.Details{
+			Reason: accounting.Hibernating,
+		})
+	case changed:
+		slog.Info("Tor accounting period reset; traffic resumed")
+	}
+
+	return accounting
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+func TestTrafficHistory_Wraps(t *testing.T) {
+	history := newTrafficHistory(3 * time.Second)
+	start := time.Now().Add(-time.Minute)
+	for i := range 5 {
+		history.add(TrafficSample{Time: start.Add(time.Duration(i) * time.Second), Read: int64(i)})
+	}
+
+	var reads []int64
+	for _, sample := range history.since(start) {
+		reads = append(reads, sample.Read)
+	}
+	if expected := []int64{2, 3, 4}; !slices.Equal(reads, expected) {
+		t.Errorf("expected the latest samples %v oldest first, got %v", expected, reads)
+	}
+	if got := history.since(start.Add(3 * time.Second)); len(got) != 1 || got[0].Read != 4 {
+		t.Errorf("expected only samples after the cutoff, got %+v", got)
+	}
+
+	var empty *trafficHistory
+	empty.add(TrafficSample{Read: 1})
+	if empty.since(start) != nil || empty.length() != 0 {
+		t.Error("expected a nil history to record nothing")
+	}
+}
+
+func TestTraffic(t *testing.T) {
+	handler := &Handler{traffic: newTrafficHistory(time.Hour)}
+	now := time.Now().Truncate(time.Second)
+	for i := range 4 {
+		handler.handleBandwidthEvent(tor.Event{
+			Type:     tor.EventBW,
+			Data:     "100 " + strings.Repeat("5", i+1),
+			Received: now.Add(time.Duration(i-4) * time.Second),
+		})
+	}
+	handler.handleBandwidthEvent(tor.Event{Type: tor.EventBW, Data: "malformed"})
+
+	w := httptest.NewRecorder()
+	handler.Traffic(w, httptest.NewRequest(http.MethodGet, "/traffic?window=1m&step=1m", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		WindowSeconds   float64         `json:"window_seconds"`
+		BytesRead       int64           `json:"bytes_read"`
+		BytesWritten    int64           `json:"bytes_written"`
+		ReadRate        float64         `json:"read_rate"`
+		PeakWrittenRate int64           `json:"peak_written_rate"`
+		Samples         []TrafficSample `json:"samples"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.WindowSeconds != 60 || response.BytesRead != 400 || response.BytesWritten != 6170 ||
+		response.ReadRate != 100 || response.PeakWrittenRate != 5555 {
+		t.Errorf("unexpected totals: %+v", response)
+	}
+
+	var read int64
+	for _, sample := range response.Samples {
+		read += sample.Read
+	}
+	if len(response.Samples) == 0 || len(response.Samples) > 2 || read != 400 {
+		t.Errorf("expected the samples summed per minute, got %+v", response.Samples)
+	}
+}
+
+func TestTraffic_InvalidRange(t *testing.T) {
+	handler := &Handler{traffic: newTrafficHistory(time.Hour)}
+
+	for _, query := range []string{"window=2h", "window=soon", "window=500ms", "step=10m&window=1m", "step=0s"} {
+		w := httptest.NewRecorder()
+		handler.Traffic(w, httptest.NewRequest(http.MethodGet, "/traffic?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestCollectAccounting_LimitReached(t *testing.T) {
+	var hibernating atomic.Value
+	hibernating.Store("awake")
+	addr := startFakeTor(t, func(cmd string) string {
+		switch {
+		case cmd == "GETINFO accounting/enabled":
+			return "250-accounting/enabled=1\r\n250 OK\r\n"
+		case strings.HasPrefix(cmd, "GETINFO accounting/hibernating"):
+			return "250-accounting/hibernating=" + hibernating.Load().(string) + "\r\n" +
+				"250-accounting/bytes=1000 2000\r\n" +
+				"250-accounting/bytes-left=0 0\r\n" +
+				"250-accounting/interval-end=2026-11-01 00:00:00\r\n" +
+				"250 OK\r\n"
+		}
+		return "250 OK\r\n"
+	})
+	client := tor.NewClient(addr, "")
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &Handler{torClient: client, events: newEventBroker()}
+	ch := handler.events.subscribe()
+	defer handler.events.unsubscribe(ch)
+
+	reached := func() int {
+		count := 0
+		for len(ch) > 0 {
+			if payload := <-ch; payload.Event == notify.EventAccountingLimitReached {
+				count++
+			}
+		}
+		return count
+	}
+
+	if accounting := handler.collectAccounting(); accounting == nil || accounting.BytesWritten != 2000 {
+		t.Fatalf("expected accounting to be reported, got %+v", accounting)
+	}
+	if count := reached(); count != 0 {
+		t.Errorf("expected no event while awake, got %d", count)
+	}
+
+	hibernating.Store("hard")
+	handler.collectAccounting()
+	handler.collectAccounting()
+	if count := reached(); count != 1 {
+		t.Errorf("expected one accounting_limit_reached event, got %d", count)
+	}
+}
+
+func TestReload_AppliesTrafficLimits(t *testing.T) {
+	recorder := &signalRecorder{}
+	current := reloadTestConfig()
+
+	next := reloadTestConfig()
+	next.TorBandwidthRate = "1 MBytes"
+	next.TorAccountingMax = "100 GBytes"
+	next.TorAccountingStart = "month 1 00:00"
+
+	handler := newReloadHandler(t, recorder, current, func() (*config.Config, error) { return next, nil })
+
+	result, err := handler.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	expectedChanged := []string{"tor_bandwidth_rate", "tor_accounting_max", "tor_accounting_start"}
+	if !slices.Equal(result.Changed, expectedChanged) || len(result.RestartRequired) != 0 {
+		t.Errorf("expected traffic limits to be reloadable, got %+v", result)
+	}
+
+	// SETCONF must follow SIGNAL RELOAD, which would otherwise discard it
+	expected := []string{
+		"SIGNAL RELOAD",
+		`SETCONF BandwidthRate="1 MBytes" AccountingMax="100 GBytes" AccountingStart="month 1 00:00"`,
+	}
+	if commands := recorder.Signals(); !slices.Equal(commands, expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+}
+
+func TestEnsureTrafficLimits_LeavesUnsetLimits(t *testing.T) {
+	recorder := &signalRecorder{}
+	cfg := reloadTestConfig()
+	cfg.TorAccountingMax = "100 GBytes"
+	handler := newReloadHandler(t, recorder, cfg, nil)
+
+	// Applied once per Tor session
+	handler.ensureTrafficLimits()
+	handler.ensureTrafficLimits()
+
+	// BandwidthRate and BandwidthBurst from a custom torrc must survive
+	expected := []string{`SETCONF AccountingMax="100 GBytes"`}
+	if commands := recorder.Signals(); !slices.Equal(commands, expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+
+	handler = newReloadHandler(t, recorder, reloadTestConfig(), nil)
+	handler.ensureTrafficLimits()
+	if commands := recorder.Signals(); len(commands) != 1 {
+		t.Errorf("expected no SETCONF without limits, got %q", commands)
+	}
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/eslutz/torarr/internal/notify"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// reconnectInterval is how often the watcher re-establishes a dropped control connection.
+const reconnectInterval = 10 * time.Second
+
+// torLogLevels maps Tor's log events to the slog level they are relayed at.
+var torLogLevels = map[tor.EventType]slog.Level{
+	tor.EventNotice: slog.LevelInfo,
+	tor.EventWarn:   slog.LevelWarn,
+	tor.EventErr:    slog.LevelError,
+}
+
+// WatchTorEvents subscribes to Tor's asynchronous status, circuit, bandwidth
+// and onion descriptor events so health transitions, webhooks and /events
+// fire as soon as Tor reports them instead of on the next probe. With
+// TOR_LOG_RELAY it also subscribes to Tor's log messages. It blocks until ctx
+// is cancelled.
+func (h *Handler) WatchTorEvents(ctx context.Context) {
+	types := []tor.EventType{tor.EventStatusClient, tor.EventCirc, tor.EventBW, tor.EventHSDesc}
+	if h.currentConfig().TorLogRelay {
+		types = append(types, tor.EventNotice, tor.EventWarn, tor.EventErr)
+	}
+
+	sub, err := h.torClient.Subscribe(types...)
+	if err != nil {
+		slog.Error("Failed to subscribe to Tor events", "error", err)
+		return
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			slog.Debug("Failed to unsubscribe from Tor events", "error", err)
+		}
+	}()
+
+	h.ensureConnected()
+
+	ticker := time.NewTicker(reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.ensureConnected()
+		case evt, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			h.handleTorEvent(evt)
+		}
+	}
+}
+
+// ensureConnected dials the control port if needed so SETEVENTS is in effect.
+func (h *Handler) ensureConnected() {
+	if err := h.torClient.Connect(); err != nil {
+		slog.Debug("Tor control port unavailable for event subscription", "error", err)
+	}
+}
+
+func (h *Handler) handleTorEvent(evt tor.Event) {
+	switch evt.Type {
+	case tor.EventStatusClient:
+		h.handleStatusEvent(evt)
+	case tor.EventCirc:
+		h.handleCircuitEvent(evt)
+	case tor.EventBW:
+		h.handleBandwidthEvent(evt)
+	case tor.EventHSDesc:
+		h.handleOnionEvent(evt)
+	case tor.EventNotice, tor.EventWarn, tor.EventErr:
+		h.handleLogEvent(evt)
+	}
+}
+
+// handleLogEvent re-emits a Tor log message through slog so Tor and the
+// health server share one log stream and format.
+func (h *Handler) handleLogEvent(evt tor.Event) {
+	msg := tor.ParseLogEvent(evt)
+	if h.metrics != nil {
+		h.metrics.torLogMessages.WithLabelValues(msg.Severity).Inc()
+	}
+	slog.Log(context.Background(), torLogLevels[evt.Type], "Tor log message",
+		"source", "tor",
+		"severity", msg.Severity,
+		"tor_message", msg.Message,
+	)
+}
+
+// handleCircuitEvent publishes circuit builds and failures on /events.
+func (h *Handler) handleCircuitEvent(evt tor.Event) {
+	circuit, err := tor.ParseCircuitEvent(evt)
+	if err != nil {
+		slog.Warn("Failed to parse Tor circuit event", "error", err)
+		return
+	}
+
+	details := notify.Details{
+		CircuitID: circuit.ID,
+		Reason:    circuit.Reason,
+	}
+	if exit, ok := circuit.Exit(); ok {
+		details.Exit = exit.Fingerprint
+	}
+
+	switch circuit.Status {
+	case "BUILT":
+		h.publish(notify.EventCircuitBuilt, "Tor circuit built", details)
+	case "FAILED":
+		h.publish(notify.EventCircuitFailed, "Tor circuit failed", details)
+	}
+}
+
+func (h *Handler) handleStatusEvent(evt tor.Event) {
+
+	status, err := tor.ParseStatusEvent(evt)
+	if err != nil {
+		slog.Warn("Failed to parse Tor status event", "error", err)
+		return
+	}
+
+	switch status.Action {
+	case "BOOTSTRAP":
+		progress, err := strconv.Atoi(status.Args["PROGRESS"])
+		if err != nil {
+			return
+		}
+		if h.metrics != nil {
+			h.metrics.torBootstrap.Set(float64(progress))
+		}
+		if status.Severity == "WARN" {
+			h.recordBootstrapWarning(status)
+		}
+		if progress >= 100 {
+			h.clearBridgeWarning()
+		}
+		slog.Debug("Tor bootstrap progress", "progress", progress, "tag", status.Args["TAG"])
+		h.publish(notify.EventBootstrapProgress, status.Args["SUMMARY"], notify.Details{
+			Bootstrap: &progress,
+			Healthy:   progress >= 100,
+		})
+		h.checkHealthStateChange(progress >= 100)
+	case "CIRCUIT_ESTABLISHED":
+		if h.metrics != nil {
+			h.metrics.torCircuit.Set(1)
+			h.metrics.torReady.Set(1)
+		}
+	case "CIRCUIT_NOT_ESTABLISHED":
+		if h.metrics != nil {
+			h.metrics.torCircuit.Set(0)
+			h.metrics.torReady.Set(0)
+		}
+		slog.Warn("Tor reports no established circuit", "reason", status.Args["REASON"])
+	}
+}
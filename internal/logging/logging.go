@@ -0,0 +1,71 @@
+// Package logging configures the process-wide slog logger and lets its
+// level change while the health server is running.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+// Log formats accepted by LOG_FORMAT.
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+)
+
+// Formats lists the supported log formats.
+var Formats = []string{FormatJSON, FormatText, FormatLogfmt}
+
+// Levels lists the supported log level names.
+var Levels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+var level slog.LevelVar
+
+// Setup installs a logger on stdout as the slog default, writing the given
+// format and filtered at the named level (DEBUG, INFO, WARN or ERROR).
+func Setup(format, levelName string) error {
+	handler, err := NewHandler(os.Stdout, format)
+	if err != nil {
+		return err
+	}
+	if err := SetLevel(levelName); err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// NewHandler returns a handler writing format to w, filtered at the level
+// controlled by SetLevel.
+func NewHandler(w io.Writer, format string) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: &level}
+
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case FormatLogfmt:
+		return slog.NewTextHandler(w, opts), nil
+	case FormatText:
+		return newTextHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+// SetLevel changes the level of handlers created by this package. Only the
+// names in Levels are accepted, not slog offsets such as "INFO+2".
+func SetLevel(levelName string) error {
+	if !slices.Contains(Levels, levelName) {
+		return fmt.Errorf("invalid log level %q", levelName)
+	}
+	return level.UnmarshalText([]byte(levelName))
+}
+
+// Level returns the current log level.
+func Level() slog.Level {
+	return level.Level()
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	defer func() { _ = SetLevel("INFO") }()
+
+	if err := SetLevel("DEBUG"); err != nil {
+		t.Fatalf("SetLevel(DEBUG) error = %v", err)
+	}
+	if Level() != slog.LevelDebug {
+		t.Errorf("expected DEBUG level, got %v", Level())
+	}
+
+	for _, name := range []string{"LOUD", "INFO+2", "DEBUG-4"} {
+		if err := SetLevel(name); err == nil {
+			t.Errorf("expected error for level %q", name)
+		}
+	}
+	if Level() != slog.LevelDebug {
+		t.Errorf("expected failed SetLevel to keep DEBUG, got %v", Level())
+	}
+}
+
+func TestNewHandler_Formats(t *testing.T) {
+	defer func() { _ = SetLevel("INFO") }()
+	if err := SetLevel("INFO"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, line string)
+	}{
+		{
+			format: FormatJSON,
+			check: func(t *testing.T, line string) {
+				var entry map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("expected JSON, got %q: %v", line, err)
+				}
+				if entry["msg"] != "Circuit built" || entry["component"] != "tor" || entry["circuit_id"] != "12" {
+					t.Errorf("unexpected JSON entry %v", entry)
+				}
+			},
+		},
+		{
+			format: FormatLogfmt,
+			check: func(t *testing.T, line string) {
+				for _, want := range []string{"level=INFO", `msg="Circuit built"`, "component=tor", "circuit_id=12"} {
+					if !strings.Contains(line, want) {
+						t.Errorf("expected %q in %q", want, line)
+					}
+				}
+			},
+		},
+		{
+			format: FormatText,
+			check: func(t *testing.T, line string) {
+				pattern := `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}\S* INFO  Circuit built component=tor circuit_id=12$`
+				if !regexp.MustCompile(pattern).MatchString(line) {
+					t.Errorf("expected %q to match %s", line, pattern)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler, err := NewHandler(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("NewHandler() error = %v", err)
+			}
+			logger := slog.New(handler).With("component", "tor")
+
+			logger.Debug("Filtered out")
+			logger.Info("Circuit built", "circuit_id", "12")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("expected one line above the level filter, got %q", buf.String())
+			}
+			tt.check(t, lines[0])
+		})
+	}
+
+	if _, err := NewHandler(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestTextHandler_Groups(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewHandler(&buf, FormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slog.New(handler).WithGroup("request").Warn("Slow request", "path", "/ready")
+
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), "WARN  Slow request request.path=/ready") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
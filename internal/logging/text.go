@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// textHandler writes human-readable lines for terminals:
+//
+//	2025-01-02T15:04:05.000Z INFO  Starting health server port=9091
+//
+// Attributes are formatted by an inner slog.TextHandler so groups and
+// quoting behave exactly as in logfmt output.
+type textHandler struct {
+	out   io.Writer
+	mu    *sync.Mutex   // Serializes writes to out and use of buf
+	buf   *bytes.Buffer // Receives the inner handler's attribute output
+	inner slog.Handler
+}
+
+func newTextHandler(w io.Writer, opts *slog.HandlerOptions) *textHandler {
+	buf := &bytes.Buffer{}
+	innerOpts := *opts
+	innerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+			return slog.Attr{}
+		}
+		if opts.ReplaceAttr != nil {
+			return opts.ReplaceAttr(groups, a)
+		}
+		return a
+	}
+
+	return &textHandler{
+		out:   w,
+		mu:    &sync.Mutex{},
+		buf:   buf,
+		inner: slog.NewTextHandler(buf, &innerOpts),
+	}
+}
+
+func (h *textHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *textHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
+	}
+	attrs := bytes.TrimRight(h.buf.Bytes(), "\n")
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	line := fmt.Sprintf("%s %-5s %s", t.Format("2006-01-02T15:04:05.000Z07:00"), r.Level, r.Message)
+	if len(attrs) > 0 {
+		line += " " + string(attrs)
+	}
+
+	_, err := io.WriteString(h.out, line+"\n")
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{out: h.out, mu: h.mu, buf: h.buf, inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	return &textHandler{out: h.out, mu: h.mu, buf: h.buf, inner: h.inner.WithGroup(name)}
+}
@@ -16,9 +16,19 @@ import (
 type Event string
 
 const (
-	EventCircuitRenewed  Event = "circuit_renewed"
-	EventBootstrapFailed Event = "bootstrap_failed"
-	EventHealthChanged   Event = "health_changed"
+	EventCircuitRenewed         Event = "circuit_renewed"
+	EventBootstrapFailed        Event = "bootstrap_failed"
+	EventHealthChanged          Event = "health_changed"
+	EventAccountingLimitReached Event = "accounting_limit_reached"
+
+	// Published on the /events stream only, never sent as webhooks
+	EventBootstrapProgress  Event = "bootstrap_progress"
+	EventCircuitBuilt       Event = "circuit_built"
+	EventCircuitFailed      Event = "circuit_failed"
+	EventCircuitClosed      Event = "circuit_closed"
+	EventReadinessChecked   Event = "readiness_checked"
+	EventOnionPublished     Event = "onion_published"
+	EventOnionPublishFailed Event = "onion_publish_failed"
 )
 
 // Payload contains the webhook notification data
@@ -36,7 +46,17 @@ type Details struct {
 	Bootstrap *int   `json:"bootstrap,omitempty"`
 	Circuits  int    `json:"circuits,omitempty"`
 	Healthy   bool   `json:"healthy"`
+	Trigger   string `json:"trigger,omitempty"`
+	CircuitID string `json:"circuit_id,omitempty"`
+	Exit      string `json:"exit,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	IsTor     *bool  `json:"is_tor,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
 	Error     string `json:"error,omitempty"`
+	Bridge    string `json:"bridge,omitempty"`
+	Onion     string `json:"onion,omitempty"`
+	Listener  string `json:"listener,omitempty"`
 }
 
 // Template represents a webhook template format
@@ -216,6 +236,8 @@ func (w *Webhook) getColor(event Event) int {
 		return 15158332 // Red
 	case EventHealthChanged:
 		return 15844367 // Gold
+	case EventAccountingLimitReached:
+		return 15105570 // Orange
 	default:
 		return 9807270 // Gray
 	}
@@ -228,7 +250,7 @@ func (w *Webhook) getColorHex(event Event) string {
 		return "good"
 	case EventBootstrapFailed:
 		return "danger"
-	case EventHealthChanged:
+	case EventHealthChanged, EventAccountingLimitReached:
 		return "warning"
 	default:
 		return "#95a5a6"
@@ -244,6 +266,8 @@ func (w *Webhook) getPriority(event Event) int {
 		return 8
 	case EventHealthChanged:
 		return 6
+	case EventAccountingLimitReached:
+		return 7
 	default:
 		return 5
 	}
@@ -269,6 +293,22 @@ func (w *Webhook) buildFields(details Details) []map[string]interface{} {
 		})
 	}
 
+	if details.Trigger != "" {
+		fields = append(fields, map[string]interface{}{
+			"name":   "Trigger",
+			"value":  details.Trigger,
+			"inline": true,
+		})
+	}
+
+	if details.Bridge != "" {
+		fields = append(fields, map[string]interface{}{
+			"name":   "Bridge",
+			"value":  bridgeValue(details),
+			"inline": false,
+		})
+	}
+
 	if details.Error != "" {
 		fields = append(fields, map[string]interface{}{
 			"name":   "Error",
@@ -300,6 +340,22 @@ func (w *Webhook) buildSlackFields(details Details) []map[string]interface{} {
 		})
 	}
 
+	if details.Trigger != "" {
+		fields = append(fields, map[string]interface{}{
+			"title": "Trigger",
+			"value": details.Trigger,
+			"short": true,
+		})
+	}
+
+	if details.Bridge != "" {
+		fields = append(fields, map[string]interface{}{
+			"title": "Bridge",
+			"value": bridgeValue(details),
+			"short": false,
+		})
+	}
+
 	if details.Error != "" {
 		fields = append(fields, map[string]interface{}{
 			"title": "Error",
@@ -310,3 +366,11 @@ func (w *Webhook) buildSlackFields(details Details) []map[string]interface{} {
 
 	return fields
 }
+
+// bridgeValue describes the bridge a bootstrap failure is attributed to.
+func bridgeValue(details Details) string {
+	if details.Reason == "" {
+		return details.Bridge
+	}
+	return fmt.Sprintf("%s (%s)", details.Bridge, details.Reason)
+}
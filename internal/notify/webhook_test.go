@@ -323,6 +323,7 @@ func TestWebhook_GetColor(t *testing.T) {
 		{EventCircuitRenewed, 3447003},
 		{EventBootstrapFailed, 15158332},
 		{EventHealthChanged, 15844367},
+		{EventAccountingLimitReached, 15105570},
 		{Event("unknown"), 9807270},
 	}
 
@@ -346,6 +347,7 @@ func TestWebhook_GetColorHex(t *testing.T) {
 		{EventCircuitRenewed, "good"},
 		{EventBootstrapFailed, "danger"},
 		{EventHealthChanged, "warning"},
+		{EventAccountingLimitReached, "warning"},
 		{Event("unknown"), "#95a5a6"},
 	}
 
@@ -369,6 +371,7 @@ func TestWebhook_GetPriority(t *testing.T) {
 		{EventCircuitRenewed, 5},
 		{EventBootstrapFailed, 8},
 		{EventHealthChanged, 6},
+		{EventAccountingLimitReached, 7},
 		{Event("unknown"), 5},
 	}
 
@@ -407,6 +410,16 @@ func TestWebhook_BuildFields(t *testing.T) {
 			details: Details{Error: "connection failed"},
 			want:    1,
 		},
+		{
+			name:    "Circuits and trigger",
+			details: Details{Circuits: 4, Trigger: "scheduled"},
+			want:    2,
+		},
+		{
+			name:    "Bridge",
+			details: Details{Bootstrap: &bootstrap75, Bridge: "obfs4 192.0.2.1:443", Reason: "CONNECTREFUSED"},
+			want:    2,
+		},
 		{
 			name:    "Empty details",
 			details: Details{},
@@ -451,6 +464,18 @@ func TestWebhook_BuildSlackFields(t *testing.T) {
 	}
 }
 
+func TestWebhook_BridgeField(t *testing.T) {
+	webhook := NewWebhook("", TemplateSlack)
+
+	fields := webhook.buildSlackFields(Details{Bridge: "obfs4 192.0.2.1:443", Reason: "CONNECTREFUSED"})
+	if len(fields) != 1 || fields[0]["title"] != "Bridge" {
+		t.Fatalf("expected a single Bridge field, got %v", fields)
+	}
+	if fields[0]["value"] != "obfs4 192.0.2.1:443 (CONNECTREFUSED)" {
+		t.Errorf("unexpected Bridge value %v", fields[0]["value"])
+	}
+}
+
 func TestWebhook_FormatPayload_UnknownTemplate(t *testing.T) {
 	webhook := NewWebhook("https://example.com", Template("unknown"))
 
@@ -0,0 +1,383 @@
+// Package onion publishes onion services through Tor's ADD_ONION command
+// and keeps their keys in the data directory so each service keeps its
+// address across Tor restarts.
+package onion
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// DirName is the directory inside Tor's DataDirectory holding service keys.
+const DirName = "torarr-onions"
+
+var (
+	// ErrNotFound is returned for a service name that does not exist.
+	ErrNotFound = errors.New("onion service not found")
+	// ErrExists is returned when creating a service whose name is taken.
+	ErrExists = errors.New("onion service already exists")
+)
+
+// validName keeps service names usable as file names.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Spec describes a service to create.
+type Spec struct {
+	Name       string          `json:"name"`
+	Ports      []tor.OnionPort `json:"ports"`
+	ClientAuth []string        `json:"client_auth,omitempty"` // Base32 x25519 public keys
+}
+
+// Validate checks the name, ports and client authorization keys.
+func (s Spec) Validate() error {
+	if !validName.MatchString(s.Name) {
+		return fmt.Errorf("invalid name %q: use 1-63 lowercase letters, digits, '-' or '_'", s.Name)
+	}
+	return s.request(tor.OnionKeyNew).Validate()
+}
+
+// request returns the ADD_ONION request publishing the service with key.
+func (s Spec) request(key string) tor.AddOnionRequest {
+	return tor.AddOnionRequest{Key: key, Ports: s.Ports, ClientAuth: s.ClientAuth}
+}
+
+// Service is a published onion service and the health of its descriptor.
+type Service struct {
+	Name       string          `json:"name"`
+	Address    string          `json:"address"`
+	Ports      []tor.OnionPort `json:"ports"`
+	ClientAuth []string        `json:"client_auth,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+
+	Running       bool       `json:"running"`   // Tor is serving the service
+	Published     bool       `json:"published"` // A descriptor was uploaded since Tor started serving it
+	LastPublished *time.Time `json:"last_published,omitempty"`
+	LastFailure   string     `json:"last_failure,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// ServiceID returns the onion address without its ".onion" suffix, as used
+// by the control protocol.
+func (s Service) ServiceID() string {
+	return strings.TrimSuffix(s.Address, ".onion")
+}
+
+// record is a service's file in the key directory.
+type record struct {
+	Name       string          `json:"name"`
+	ServiceID  string          `json:"service_id"`
+	PrivateKey string          `json:"private_key"`
+	Ports      []tor.OnionPort `json:"ports"`
+	ClientAuth []string        `json:"client_auth,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Manager creates, restores and removes onion services.
+type Manager struct {
+	client *tor.Client
+	dir    string
+
+	mu       sync.Mutex // Serializes changes and protects services
+	services map[string]*Service
+	keys     map[string]string // Private keys by service name
+	loaded   bool
+}
+
+// NewManager returns a manager storing keys under dataDirectory/DirName.
+// Stored services are read on first use.
+func NewManager(client *tor.Client, dataDirectory string) *Manager {
+	return &Manager{
+		client:   client,
+		dir:      filepath.Join(dataDirectory, DirName),
+		services: make(map[string]*Service),
+		keys:     make(map[string]string),
+	}
+}
+
+// List returns every service sorted by name.
+func (m *Manager) List() ([]Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0, len(m.services))
+	for _, service := range m.services {
+		services = append(services, *service)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// Get returns the named service.
+func (m *Manager) Get(name string) (Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return Service{}, err
+	}
+	service, ok := m.services[name]
+	if !ok {
+		return Service{}, ErrNotFound
+	}
+	return *service, nil
+}
+
+// Create publishes a new service with a fresh key and stores the key. The
+// service is removed from Tor again if the key cannot be stored, so no
+// address is handed out that would change on the next restart.
+func (m *Manager) Create(spec Spec) (Service, error) {
+	if err := spec.Validate(); err != nil {
+		return Service{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return Service{}, err
+	}
+	if _, ok := m.services[spec.Name]; ok {
+		return Service{}, fmt.Errorf("%w: %s", ErrExists, spec.Name)
+	}
+
+	created, err := m.client.AddOnion(spec.request(tor.OnionKeyNew))
+	if err != nil {
+		return Service{}, err
+	}
+
+	rec := record{
+		Name:       spec.Name,
+		ServiceID:  created.ServiceID,
+		PrivateKey: created.PrivateKey,
+		Ports:      spec.Ports,
+		ClientAuth: spec.ClientAuth,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := m.save(rec); err != nil {
+		if delErr := m.client.DelOnion(created.ServiceID); delErr != nil {
+			slog.Error("Failed to remove unsaved onion service", "name", spec.Name, "error", delErr)
+		}
+		return Service{}, err
+	}
+
+	service := newService(rec)
+	service.Running = true
+	m.services[rec.Name] = service
+	m.keys[rec.Name] = rec.PrivateKey
+
+	slog.Info("Created onion service", "name", rec.Name, "address", service.Address)
+	return *service, nil
+}
+
+// Remove stops the named service and deletes its key, permanently giving up
+// its address.
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+	service, ok := m.services[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if err := m.client.DelOnion(service.ServiceID()); err != nil && !errors.Is(err, tor.ErrUnknownOnion) {
+		return err
+	}
+	if err := os.Remove(m.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete onion service key: %w", err)
+	}
+
+	delete(m.services, name)
+	delete(m.keys, name)
+
+	slog.Info("Removed onion service", "name", name, "address", service.Address)
+	return nil
+}
+
+// Restore re-adds stored services that Tor is not serving, such as after
+// Tor restarted. It is cheap when every service is running, so callers can
+// run it whenever the control connection may have been re-established.
+func (m *Manager) Restore() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+	if len(m.services) == 0 {
+		return nil
+	}
+
+	detached, err := m.client.GetDetachedOnions()
+	if err != nil {
+		return err
+	}
+	running := make(map[string]bool, len(detached))
+	for _, id := range detached {
+		running[id] = true
+	}
+
+	var errs []error
+	for name, service := range m.services {
+		if running[service.ServiceID()] {
+			service.Running = true
+			continue
+		}
+
+		// Descriptors must be uploaded again for the new Tor instance
+		service.Running = false
+		service.Published = false
+
+		spec := Spec{Name: name, Ports: service.Ports, ClientAuth: service.ClientAuth}
+		_, err := m.client.AddOnion(spec.request(m.keys[name]))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore onion service %s: %w", name, err))
+			continue
+		}
+		service.Running = true
+		slog.Info("Restored onion service", "name", name, "address", service.Address)
+	}
+
+	return errors.Join(errs...)
+}
+
+// HandleDescEvent records descriptor uploads and upload failures reported
+// by HS_DESC events. It returns the affected service, if any.
+func (m *Manager) HandleDescEvent(desc tor.HSDescEvent) (Service, bool) {
+	if !desc.Uploading {
+		return Service{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, service := range m.services {
+		if service.ServiceID() != desc.Address {
+			continue
+		}
+
+		now := time.Now()
+		switch desc.Action {
+		case "UPLOADED":
+			service.Published = true
+			service.LastPublished = &now
+		case "FAILED":
+			service.LastFailure = desc.Reason
+			service.LastFailureAt = &now
+		}
+		return *service, true
+	}
+	return Service{}, false
+}
+
+// GenerateClientAuth creates an x25519 key pair for onion service client
+// authorization. The public key goes in Spec.ClientAuth; the private key is
+// what the client needs and is never stored.
+func GenerateClientAuth() (publicKey, privateKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate client authorization key: %w", err)
+	}
+	return tor.EncodeClientAuthKey(key.PublicKey().Bytes()), tor.EncodeClientAuthKey(key.Bytes()), nil
+}
+
+// ClientAuthLine formats a private key as a line of a client's
+// ClientOnionAuthDir "<name>.auth_private" file.
+func ClientAuthLine(serviceID, privateKey string) string {
+	return serviceID + ":descriptor:x25519:" + privateKey
+}
+
+func newService(rec record) *Service {
+	return &Service{
+		Name:       rec.Name,
+		Address:    rec.ServiceID + ".onion",
+		Ports:      rec.Ports,
+		ClientAuth: rec.ClientAuth,
+		CreatedAt:  rec.CreatedAt,
+	}
+}
+
+// load reads the stored services once. The caller must hold m.mu.
+func (m *Manager) load() error {
+	if m.loaded {
+		return nil
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read onion service keys: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read onion service key: %w", err)
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("failed to parse onion service key %s: %w", entry.Name(), err)
+		}
+		m.services[rec.Name] = newService(rec)
+		m.keys[rec.Name] = rec.PrivateKey
+	}
+
+	m.loaded = true
+	return nil
+}
+
+// save writes a service's key file, readable only by the owner.
+func (m *Manager) save(rec record) error {
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create onion service key directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode onion service key: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(m.dir, ".onion-*")
+	if err != nil {
+		return fmt.Errorf("failed to write onion service key: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write onion service key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write onion service key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path(rec.Name)); err != nil {
+		return fmt.Errorf("failed to write onion service key: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name+".json")
+}
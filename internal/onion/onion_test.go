@@ -0,0 +1,326 @@
+package onion
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+// fakeTor serves ADD_ONION, DEL_ONION and GETINFO onions/detached from an
+// in-memory set of running services.
+type fakeTor struct {
+	mu       sync.Mutex
+	running  map[string]bool
+	commands []string
+	next     int
+}
+
+func startFakeTor(t *testing.T) (*fakeTor, *tor.Client) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	fake := &fakeTor{running: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if _, err := conn.Write([]byte(fake.respond(strings.TrimRight(line, "\r\n")))); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	client := tor.NewClient(listener.Addr().String(), "")
+	t.Cleanup(func() { _ = client.Close() })
+	return fake, client
+}
+
+func (f *fakeTor) respond(cmd string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+		return "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250 OK\r\n"
+	case cmd == "AUTHENTICATE":
+		return "250 OK\r\n"
+	}
+
+	f.commands = append(f.commands, cmd)
+	switch {
+	case strings.HasPrefix(cmd, "ADD_ONION NEW:"):
+		f.next++
+		id := fmt.Sprintf("%056d", f.next)
+		f.running[id] = true
+		return "250-ServiceID=" + id + "\r\n250-PrivateKey=" + testKey(f.next) + "\r\n250 OK\r\n"
+	case strings.HasPrefix(cmd, "ADD_ONION ED25519-V3:"):
+		key := strings.Fields(cmd)[1]
+		for i := 1; i <= f.next; i++ {
+			if testKey(i) == key {
+				id := fmt.Sprintf("%056d", i)
+				f.running[id] = true
+				return "250-ServiceID=" + id + "\r\n250 OK\r\n"
+			}
+		}
+		return "512 Bad key\r\n"
+	case strings.HasPrefix(cmd, "DEL_ONION "):
+		id := strings.TrimPrefix(cmd, "DEL_ONION ")
+		if !f.running[id] {
+			return "552 Unknown Onion Service id\r\n"
+		}
+		delete(f.running, id)
+		return "250 OK\r\n"
+	case cmd == "GETINFO onions/detached":
+		if len(f.running) == 0 {
+			return "551 No onion services of the specified type.\r\n"
+		}
+		resp := "250+onions/detached=\r\n"
+		for id := range f.running {
+			resp += id + "\r\n"
+		}
+		return resp + ".\r\n250 OK\r\n"
+	}
+	return "510 Unrecognized command\r\n"
+}
+
+// restart forgets every running service, as a Tor restart would.
+func (f *fakeTor) restart() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = make(map[string]bool)
+}
+
+func (f *fakeTor) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func (f *fakeTor) isRunning(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running[id]
+}
+
+func testKey(n int) string {
+	key := make([]byte, 64)
+	key[0] = byte(n)
+	return "ED25519-V3:" + base64.StdEncoding.EncodeToString(key)
+}
+
+var sonarr = Spec{
+	Name:  "sonarr",
+	Ports: []tor.OnionPort{{Virtual: 80, Target: "sonarr:8989"}},
+}
+
+func TestSpec_Validate(t *testing.T) {
+	clientKey, _, err := GenerateClientAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{name: "valid", spec: sonarr},
+		{name: "client auth", spec: Spec{Name: "sonarr", Ports: sonarr.Ports, ClientAuth: []string{clientKey}}},
+		{name: "empty name", spec: Spec{Ports: sonarr.Ports}, wantErr: true},
+		{name: "path in name", spec: Spec{Name: "../sonarr", Ports: sonarr.Ports}, wantErr: true},
+		{name: "uppercase name", spec: Spec{Name: "Sonarr", Ports: sonarr.Ports}, wantErr: true},
+		{name: "no ports", spec: Spec{Name: "sonarr"}, wantErr: true},
+		{name: "bad client key", spec: Spec{Name: "sonarr", Ports: sonarr.Ports, ClientAuth: []string{"abc"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.spec.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_CreateStoresKey(t *testing.T) {
+	_, client := startFakeTor(t)
+	dir := t.TempDir()
+	manager := NewManager(client, dir)
+
+	service, err := manager.Create(sonarr)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasSuffix(service.Address, ".onion") || !service.Running || service.Published {
+		t.Errorf("unexpected service: %+v", service)
+	}
+
+	path := filepath.Join(dir, DirName, "sonarr.json")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected a key file: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected key file mode 0600, got %v", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), testKey(1)) {
+		t.Errorf("expected the private key to be stored, got:\n%s", data)
+	}
+
+	if _, err := manager.Create(sonarr); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists for a duplicate name, got %v", err)
+	}
+
+	// A new manager, as after a restart of the health server, reads the key back
+	services, err := NewManager(client, dir).List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(services) != 1 || services[0].Address != service.Address {
+		t.Errorf("expected the stored service, got %+v", services)
+	}
+}
+
+func TestManager_Restore(t *testing.T) {
+	fake, client := startFakeTor(t)
+	dir := t.TempDir()
+
+	service, err := NewManager(client, dir).Create(sonarr)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	fake.restart()
+	manager := NewManager(client, dir)
+	if err := manager.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !fake.isRunning(service.ServiceID()) {
+		t.Fatal("expected the service to be re-added with its stored key")
+	}
+
+	restored, err := manager.Get("sonarr")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if restored.Address != service.Address || !restored.Running {
+		t.Errorf("expected the same address running again, got %+v", restored)
+	}
+
+	// Restoring again leaves running services alone
+	before := len(fake.sent())
+	if err := manager.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	for _, cmd := range fake.sent()[before:] {
+		if strings.HasPrefix(cmd, "ADD_ONION") {
+			t.Errorf("expected no ADD_ONION for a running service, got %q", cmd)
+		}
+	}
+}
+
+func TestManager_Remove(t *testing.T) {
+	fake, client := startFakeTor(t)
+	dir := t.TempDir()
+	manager := NewManager(client, dir)
+
+	service, err := manager.Create(sonarr)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := manager.Remove("sonarr"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if fake.isRunning(service.ServiceID()) {
+		t.Error("expected the service to be removed from Tor")
+	}
+	if _, err := os.Stat(filepath.Join(dir, DirName, "sonarr.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the key file to be deleted, got %v", err)
+	}
+	if err := manager.Remove("sonarr"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// A service Tor has already dropped is still removed
+	if _, err := manager.Create(sonarr); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	fake.restart()
+	if err := manager.Remove("sonarr"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+}
+
+func TestManager_HandleDescEvent(t *testing.T) {
+	_, client := startFakeTor(t)
+	manager := NewManager(client, t.TempDir())
+
+	service, err := manager.Create(sonarr)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	id := service.ServiceID()
+
+	if _, ok := manager.HandleDescEvent(tor.HSDescEvent{Action: "RECEIVED", Address: id}); ok {
+		t.Error("expected fetch events to be ignored")
+	}
+	if _, ok := manager.HandleDescEvent(tor.HSDescEvent{Action: "UPLOADED", Address: "other", Uploading: true}); ok {
+		t.Error("expected events for other services to be ignored")
+	}
+
+	failed, ok := manager.HandleDescEvent(tor.HSDescEvent{Action: "FAILED", Address: id, Reason: "UPLOAD_REJECTED", Uploading: true})
+	if !ok || failed.Published || failed.LastFailure != "UPLOAD_REJECTED" || failed.LastFailureAt == nil {
+		t.Errorf("expected the failure to be recorded, got %+v", failed)
+	}
+
+	published, ok := manager.HandleDescEvent(tor.HSDescEvent{Action: "UPLOADED", Address: id, Uploading: true})
+	if !ok || !published.Published || published.LastPublished == nil {
+		t.Errorf("expected the service to be published, got %+v", published)
+	}
+}
+
+func TestGenerateClientAuth(t *testing.T) {
+	public, private, err := GenerateClientAuth()
+	if err != nil {
+		t.Fatalf("GenerateClientAuth() error = %v", err)
+	}
+	if err := tor.ValidateClientAuthKey(public); err != nil {
+		t.Errorf("expected a valid public key: %v", err)
+	}
+	if public == private {
+		t.Error("expected distinct public and private keys")
+	}
+	if line := ClientAuthLine("abc", private); line != "abc:descriptor:x25519:"+private {
+		t.Errorf("unexpected client auth line %q", line)
+	}
+}
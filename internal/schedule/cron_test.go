@@ -0,0 +1,82 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@fortnightly",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("expected %q to be rejected", expr)
+			}
+		})
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.May, 1, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.May, 1, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.May, 1, 12, 45, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2024, time.May, 1, 18, 0, 0, 0, time.UTC)},
+		{"30 12 * * *", time.Date(2024, time.May, 2, 12, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.May, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 1", time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)},
+		{"5,10 3 1 6 *", time.Date(2024, time.June, 1, 3, 5, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.May, 1, 13, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cron.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarliest(t *testing.T) {
+	from := time.Date(2024, time.May, 1, 12, 30, 0, 0, time.UTC)
+	cron, err := ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := Earliest(from, Every(time.Hour), cron); !got.Equal(from.Add(30 * time.Minute)) {
+		t.Errorf("expected cron to fire first, got %v", got)
+	}
+	if got := Earliest(from, Every(10*time.Minute), cron); !got.Equal(from.Add(10 * time.Minute)) {
+		t.Errorf("expected interval to fire first, got %v", got)
+	}
+	if got := Earliest(from); !got.IsZero() {
+		t.Errorf("expected zero time without schedules, got %v", got)
+	}
+}
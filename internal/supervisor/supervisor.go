@@ -0,0 +1,209 @@
+// Package supervisor runs Tor as a child of the health server, restarting
+// it with backoff when it exits unexpectedly.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/tor"
+)
+
+const (
+	// minBackoff and maxBackoff bound the delay before restarting Tor; the
+	// delay doubles after each crash.
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// stableAfter is how long Tor must run before the backoff is reset.
+	stableAfter = time.Minute
+	// stopTimeout is how long Tor may take to exit after SIGTERM before it
+	// is killed.
+	stopTimeout = 10 * time.Second
+	// ownershipInterval is how often the control port is polled until Tor
+	// accepts TAKEOWNERSHIP.
+	ownershipInterval = 500 * time.Millisecond
+)
+
+// Supervisor launches and monitors a Tor process.
+type Supervisor struct {
+	binary string
+	args   []string
+	client *tor.Client // Dedicated connection that owns the Tor process
+
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	stableAfter time.Duration
+	stopTimeout time.Duration
+
+	mu      sync.Mutex
+	process *os.Process
+}
+
+// New returns a supervisor that runs the configured Tor binary on the
+// configured torrc.
+func New(cfg *config.Config) *Supervisor {
+	return &Supervisor{
+		binary: cfg.TorBinary,
+		// __OwningControllerProcess makes Tor exit if the health server dies
+		// before TAKEOWNERSHIP has been sent.
+		args: []string{"-f", cfg.TorrcFile, "__OwningControllerProcess", strconv.Itoa(os.Getpid())},
+		client: tor.NewClientWithAuth(cfg.TorControlAddress, tor.Auth{
+			Method:        tor.AuthMethod(cfg.TorControlAuth),
+			Password:      cfg.TorControlPassword,
+			CookieFile:    cfg.TorControlCookieFile,
+			DataDirectory: cfg.TorDataDirectory,
+		}),
+		minBackoff:  minBackoff,
+		maxBackoff:  maxBackoff,
+		stableAfter: stableAfter,
+		stopTimeout: stopTimeout,
+	}
+}
+
+// Run starts Tor and restarts it whenever it exits until ctx is cancelled,
+// at which point Tor is stopped and Run returns nil. An error is returned
+// only if the Tor binary cannot be started.
+func (s *Supervisor) Run(ctx context.Context) error {
+	backoff := s.minBackoff
+
+	for {
+		started := time.Now()
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var startErr *startError
+		if errors.As(err, &startErr) {
+			return err
+		}
+
+		if time.Since(started) >= s.stableAfter {
+			backoff = s.minBackoff
+		}
+		slog.Error("Tor exited unexpectedly; restarting", "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// Signal forwards sig to the running Tor process, if any.
+func (s *Supervisor) Signal(sig os.Signal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.process == nil {
+		return errors.New("tor is not running")
+	}
+	return s.process.Signal(sig)
+}
+
+type startError struct {
+	err error
+}
+
+func (e *startError) Error() string {
+	return fmt.Sprintf("failed to start tor: %v", e.err)
+}
+
+func (e *startError) Unwrap() error {
+	return e.err
+}
+
+// runOnce runs a single Tor process until it exits or ctx is cancelled.
+func (s *Supervisor) runOnce(ctx context.Context) error {
+	cmd := exec.Command(s.binary, s.args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return &startError{err: err}
+	}
+	slog.Info("Started Tor", "pid", cmd.Process.Pid, "binary", s.binary)
+
+	s.mu.Lock()
+	s.process = cmd.Process
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.process = nil
+		s.mu.Unlock()
+	}()
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ownCtx, stopOwning := context.WithCancel(ctx)
+	owning := make(chan struct{})
+	go func() {
+		defer close(owning)
+		s.takeOwnership(ownCtx)
+	}()
+	defer func() {
+		stopOwning()
+		<-owning
+		_ = s.client.Close()
+	}()
+
+	select {
+	case err := <-exited:
+		if err == nil {
+			return errors.New("tor exited with status 0")
+		}
+		return err
+	case <-ctx.Done():
+		s.stop(cmd.Process, exited)
+		return nil
+	}
+}
+
+// takeOwnership polls the control port until Tor accepts TAKEOWNERSHIP.
+func (s *Supervisor) takeOwnership(ctx context.Context) {
+	ticker := time.NewTicker(ownershipInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.client.TakeOwnership()
+		if err == nil {
+			slog.Debug("Took ownership of Tor process")
+			return
+		}
+		slog.Debug("Waiting for Tor control port to take ownership", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop sends SIGTERM and kills Tor if it has not exited within stopTimeout.
+func (s *Supervisor) stop(process *os.Process, exited <-chan error) {
+	slog.Info("Stopping Tor", "pid", process.Pid)
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		slog.Debug("Failed to signal Tor", "error", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(s.stopTimeout):
+		slog.Warn("Tor did not exit in time; killing it", "timeout", s.stopTimeout)
+		if err := process.Kill(); err != nil {
+			slog.Error("Failed to kill Tor", "error", err)
+		}
+		<-exited
+	}
+}
@@ -0,0 +1,207 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/eslutz/torarr/internal/config"
+)
+
+// TestMain lets the test binary stand in for Tor: when FAKE_TOR_MODE is set
+// it behaves as a fake tor process instead of running the tests.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("FAKE_TOR_MODE"); mode != "" {
+		runFakeTor(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeTor records what happens to it in FAKE_TOR_LOG. In "crash" mode it
+// exits immediately with status 1; in "serve" mode it answers the control
+// protocol on FAKE_TOR_CONTROL until it receives SIGTERM.
+func runFakeTor(mode string) {
+	record := func(line string) {
+		f, err := os.OpenFile(os.Getenv("FAKE_TOR_LOG"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			os.Exit(3)
+		}
+		_, _ = fmt.Fprintln(f, line)
+		_ = f.Close()
+	}
+
+	record("start " + strings.Join(os.Args[1:], " "))
+	if mode == "crash" {
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGUSR1)
+
+	listener, err := net.Listen("tcp", os.Getenv("FAKE_TOR_CONTROL"))
+	if err != nil {
+		os.Exit(3)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.TrimSpace(line)
+					if strings.HasPrefix(cmd, "PROTOCOLINFO") {
+						_, _ = fmt.Fprint(conn, "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250 OK\r\n")
+						continue
+					}
+					if cmd == "TAKEOWNERSHIP" {
+						record(cmd)
+					}
+					_, _ = fmt.Fprint(conn, "250 OK\r\n")
+				}
+			}()
+		}
+	}()
+
+	for sig := range signals {
+		record(sig.String())
+		if sig == syscall.SIGTERM {
+			os.Exit(0)
+		}
+	}
+}
+
+func newTestSupervisor(t *testing.T, mode string) (*Supervisor, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlAddress := listener.Addr().String()
+	_ = listener.Close()
+
+	logPath := filepath.Join(t.TempDir(), "fake-tor.log")
+	t.Setenv("FAKE_TOR_MODE", mode)
+	t.Setenv("FAKE_TOR_LOG", logPath)
+	t.Setenv("FAKE_TOR_CONTROL", controlAddress)
+
+	s := New(&config.Config{
+		TorBinary:         os.Args[0],
+		TorrcFile:         "/etc/tor/torrc",
+		TorControlAddress: controlAddress,
+		TorControlAuth:    "null",
+	})
+	s.minBackoff = 10 * time.Millisecond
+	s.maxBackoff = 40 * time.Millisecond
+	s.stopTimeout = 5 * time.Second
+
+	return s, logPath
+}
+
+// waitForLog waits until the fake Tor has recorded at least count lines
+// starting with prefix.
+func waitForLog(t *testing.T, path, prefix string, count int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		var matches []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, prefix) {
+				matches = append(matches, line)
+			}
+		}
+		if len(matches) >= count {
+			return matches
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d %q lines, log:\n%s", count, prefix, data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func runSupervisor(s *Supervisor) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	return cancel, done
+}
+
+func waitForRun(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Run to return")
+		return nil
+	}
+}
+
+func TestRun_RestartsAfterCrash(t *testing.T) {
+	s, logPath := newTestSupervisor(t, "crash")
+
+	cancel, done := runSupervisor(s)
+	starts := waitForLog(t, logPath, "start ", 3)
+	cancel()
+
+	if err := waitForRun(t, done); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	want := fmt.Sprintf("start -f /etc/tor/torrc __OwningControllerProcess %d", os.Getpid())
+	if starts[0] != want {
+		t.Errorf("expected %q, got %q", want, starts[0])
+	}
+}
+
+func TestRun_TakesOwnershipAndStopsTor(t *testing.T) {
+	s, logPath := newTestSupervisor(t, "serve")
+
+	cancel, done := runSupervisor(s)
+	waitForLog(t, logPath, "TAKEOWNERSHIP", 1)
+
+	if err := s.Signal(syscall.SIGUSR1); err != nil {
+		t.Fatalf("Signal() error = %v", err)
+	}
+	waitForLog(t, logPath, "user defined signal 1", 1)
+
+	cancel()
+	if err := waitForRun(t, done); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	waitForLog(t, logPath, "terminated", 1)
+
+	if starts := waitForLog(t, logPath, "start ", 1); len(starts) != 1 {
+		t.Errorf("expected Tor to be started once, got %d starts", len(starts))
+	}
+	if err := s.Signal(syscall.SIGUSR1); err == nil {
+		t.Error("expected signalling a stopped Tor to fail")
+	}
+}
+
+func TestRun_MissingBinary(t *testing.T) {
+	s := New(&config.Config{TorBinary: filepath.Join(t.TempDir(), "tor"), TorrcFile: "/etc/tor/torrc"})
+
+	_, done := runSupervisor(s)
+	if err := waitForRun(t, done); err == nil {
+		t.Error("expected an error when Tor cannot be started")
+	}
+}
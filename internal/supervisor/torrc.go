@@ -0,0 +1,220 @@
+package supervisor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/eslutz/torarr/internal/config"
+	"github.com/eslutz/torarr/internal/tor"
+	"github.com/eslutz/torarr/internal/torrc"
+)
+
+// Markers around the options PrepareTorrc writes into an existing torrc, so
+// options whose setting was removed can be removed on the next start.
+const (
+	managedBegin = "# BEGIN torarr: written from the configuration on every start"
+	managedEnd   = "# END torarr"
+)
+
+// Tor's stdout log lines with and without TOR_LOG_RELAY.
+const (
+	logNoticeStdout = "Log notice stdout"
+	logErrStdout    = "Log err stdout"
+)
+
+// PrepareTorrc writes the Tor settings held in the configuration to the
+// torrc at path. With TORRC_GENERATE the whole file is rendered from the
+// configuration; otherwise the existing file is edited in place. The options
+// Torarr sets are kept in a marked block that is rewritten on every start,
+// replacing any earlier values so restarts do not accumulate duplicates and
+// unset options do not linger.
+func PrepareTorrc(path string, cfg *config.Config) error {
+	if cfg.TorrcGenerate {
+		c, err := TorrcConfig(cfg)
+		if err != nil {
+			return err
+		}
+		return torrc.Write(path, c)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read torrc: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	lines, previous := cutManaged(lines)
+	var managed []string
+
+	if cfg.TorControlPassword != "" {
+		// A fresh salt would rewrite torrc on every start, so a hash that
+		// still matches the password is kept
+		hashed, ok := findOption(slices.Concat(previous, lines), "HashedControlPassword")
+		if !ok || !tor.CheckHashedPassword(hashed, cfg.TorControlPassword) {
+			var err error
+			if hashed, err = tor.HashPassword(cfg.TorControlPassword); err != nil {
+				return err
+			}
+		}
+		lines = removeOption(lines, "HashedControlPassword")
+		managed = append(managed, "HashedControlPassword "+hashed)
+	}
+
+	if cfg.TorExitNodes != "" {
+		lines = removeOption(lines, "ExitNodes")
+		lines = removeOption(lines, "StrictNodes")
+		managed = append(managed, "ExitNodes "+cfg.TorExitNodes, "StrictNodes 1")
+	}
+
+	// Named listeners need every SocksPort line, so the default one is
+	// rewritten from TOR_SOCKS_PORT alongside them. Once Torarr writes the
+	// SocksPort lines it keeps doing so, or removing the last listener would
+	// leave none.
+	if _, wrote := findOption(previous, "SocksPort"); wrote || len(cfg.TorSocksListeners) > 0 {
+		lines = removeOption(lines, "SocksPort")
+		for _, port := range socksPorts(cfg) {
+			managed = append(managed, "SocksPort "+port.Value())
+		}
+	}
+
+	if cfg.TorUseBridges {
+		for _, key := range []string{"UseBridges", "ClientTransportPlugin", "Bridge"} {
+			lines = removeOption(lines, key)
+		}
+		managed = append(managed, "UseBridges 1")
+		for _, plugin := range cfg.TorTransportPlugins {
+			managed = append(managed, "ClientTransportPlugin "+plugin)
+		}
+		for _, bridge := range cfg.TorBridges {
+			managed = append(managed, "Bridge "+bridge)
+		}
+	}
+
+	// Relayed log messages replace Tor's own notices; errors stay on stdout
+	// because they can occur before the control connection is up. The
+	// notice line moves into the block and is put back once relaying stops.
+	if cfg.TorLogRelay {
+		if slices.Contains(lines, logNoticeStdout) || slices.Contains(previous, logErrStdout) {
+			lines = slices.DeleteFunc(lines, func(line string) bool { return line == logNoticeStdout })
+			managed = append(managed, logErrStdout)
+		}
+	} else if slices.Contains(previous, logErrStdout) && !slices.Contains(lines, logNoticeStdout) {
+		lines = append(lines, logNoticeStdout)
+	}
+
+	if len(managed) > 0 {
+		lines = append(lines, managedBegin)
+		lines = append(lines, managed...)
+		lines = append(lines, managedEnd)
+	}
+
+	// Leave the file alone when nothing changed so read-only mounts work.
+	edited := []byte(strings.Join(lines, "\n") + "\n")
+	if bytes.Equal(edited, data) {
+		return nil
+	}
+	return torrc.WriteFile(path, edited)
+}
+
+// TorrcConfig maps the configuration to the torrc options Torarr renders.
+func TorrcConfig(cfg *config.Config) (torrc.Config, error) {
+	c := torrc.Config{
+		SocksPorts:           socksPorts(cfg),
+		CookieAuthentication: true,
+		DataDirectory:        cfg.TorDataDirectory,
+		LogLevel:             "notice",
+		ExitNodes:            cfg.TorExitNodes,
+		ExcludeNodes:         cfg.TorExcludeNodes,
+		ExcludeExitNodes:     cfg.TorExcludeExitNodes,
+		StrictNodes:          cfg.TorExitNodes != "",
+		NumEntryGuards:       cfg.TorNumEntryGuards,
+		BandwidthRate:        cfg.TorBandwidthRate,
+		BandwidthBurst:       cfg.TorBandwidthBurst,
+		AccountingMax:        cfg.TorAccountingMax,
+		AccountingStart:      cfg.TorAccountingStart,
+	}
+
+	if cfg.TorUseBridges {
+		c.UseBridges = true
+		c.Bridges = cfg.TorBridges
+		c.ClientTransportPlugins = cfg.TorTransportPlugins
+	}
+
+	if socket, ok := strings.CutPrefix(cfg.TorControlAddress, "unix:"); ok {
+		c.ControlPort = "0"
+		c.ControlSocket = socket
+	} else {
+		c.ControlPort = cfg.TorControlAddress
+	}
+
+	if cfg.TorControlPassword != "" {
+		hashed, err := tor.HashPassword(cfg.TorControlPassword)
+		if err != nil {
+			return torrc.Config{}, err
+		}
+		c.HashedControlPassword = hashed
+	}
+
+	// Relayed log messages replace Tor's own notices; see PrepareTorrc.
+	if cfg.TorLogRelay {
+		c.LogLevel = "err"
+	}
+
+	return c, nil
+}
+
+// socksPorts returns the default SOCKS listener followed by the named ones.
+func socksPorts(cfg *config.Config) []torrc.SocksPort {
+	listeners := cfg.SocksListeners()
+	ports := make([]torrc.SocksPort, len(listeners))
+	for i, listener := range listeners {
+		ports[i] = listener.SocksPort()
+	}
+	return ports
+}
+
+// cutManaged splits the block written by an earlier PrepareTorrc from the
+// rest of the torrc.
+func cutManaged(lines []string) (rest, managed []string) {
+	begin := slices.Index(lines, managedBegin)
+	if begin < 0 {
+		return lines, nil
+	}
+	end := slices.Index(lines[begin:], managedEnd)
+	if end < 0 {
+		// A truncated block runs to the end of the file
+		return slices.Clone(lines[:begin]), slices.Clone(lines[begin+1:])
+	}
+	end += begin
+
+	return slices.Concat(lines[:begin], lines[end+1:]), slices.Clone(lines[begin+1 : end])
+}
+
+// findOption returns the value of the first line setting key.
+func findOption(lines []string, key string) (string, bool) {
+	for _, line := range lines {
+		if strings.EqualFold(optionKey(line), key) {
+			_, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+func removeOption(lines []string, key string) []string {
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.EqualFold(optionKey(line), key) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+func optionKey(line string) string {
+	key, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	return key
+}
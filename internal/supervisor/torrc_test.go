@@ -0,0 +1,255 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eslutz/torarr/internal/config"
+)
+
+const baseTorrc = `SocksPort 0.0.0.0:9050
+ControlPort 0.0.0.0:9051
+CookieAuthentication 1
+DataDirectory /var/lib/tor
+Log notice stdout
+`
+
+func TestPrepareTorrc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte(baseTorrc+"ExitNodes {de}\nStrictNodes 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		TorControlPassword:  "secret",
+		TorExitNodes:        "{us},{ca}",
+		TorLogRelay:         true,
+		TorUseBridges:       true,
+		TorBridges:          []string{"obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0"},
+		TorTransportPlugins: []string{"obfs4 exec /usr/bin/lyrebird"},
+		TorSocksPort:        "0.0.0.0:9050",
+		TorSocksListeners:   []config.SocksListener{{Name: "sonarr", Address: "0.0.0.0:9060", Flags: []string{"isolatedestaddr"}}},
+	}
+	// Preparing twice must not duplicate settings
+	for range 2 {
+		if err := PrepareTorrc(path, cfg); err != nil {
+			t.Fatalf("PrepareTorrc() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrc := string(data)
+
+	for _, line := range []string{"ExitNodes {us},{ca}", "StrictNodes 1", "Log err stdout", "HashedControlPassword 16:",
+		"UseBridges 1", "ClientTransportPlugin obfs4 exec", "Bridge obfs4 192.0.2.1:443",
+		"SocksPort 0.0.0.0:9050\n", "SocksPort 0.0.0.0:9060 IsolateDestAddr\n"} {
+		if count := strings.Count(torrc, line); count != 1 {
+			t.Errorf("expected %q once, found %d times in:\n%s", line, count, torrc)
+		}
+	}
+	for _, line := range []string{"ExitNodes {de}", "Log notice stdout"} {
+		if strings.Contains(torrc, line) {
+			t.Errorf("expected %q to be replaced in:\n%s", line, torrc)
+		}
+	}
+}
+
+func TestPrepareTorrc_RemovedSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte(baseTorrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	cfg := &config.Config{
+		TorControlPassword: "secret",
+		TorExitNodes:       "{us}",
+		TorSocksPort:       "0.0.0.0:9050",
+		TorSocksListeners:  []config.SocksListener{{Name: "sonarr", Address: "0.0.0.0:9060"}},
+	}
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+	prepared := read()
+
+	// The password hash is kept, so an unchanged configuration leaves the file alone
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+	if torrc := read(); torrc != prepared {
+		t.Errorf("expected an unchanged configuration to keep torrc, got:\n%s\nwant:\n%s", torrc, prepared)
+	}
+
+	cfg.TorExitNodes = ""
+	cfg.TorSocksListeners = nil
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+	torrc := read()
+	for _, line := range []string{"ExitNodes", "StrictNodes", "SocksPort 0.0.0.0:9060"} {
+		if strings.Contains(torrc, line) {
+			t.Errorf("expected %q to be removed with its setting in:\n%s", line, torrc)
+		}
+	}
+	if count := strings.Count(torrc, "SocksPort 0.0.0.0:9050\n"); count != 1 {
+		t.Errorf("expected the default SocksPort once, found %d times in:\n%s", count, torrc)
+	}
+}
+
+func TestPrepareTorrc_LogRelay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte(baseTorrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	cfg := &config.Config{TorLogRelay: true}
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+	if torrc := read(); !strings.Contains(torrc, managedBegin+"\nLog err stdout\n"+managedEnd) || strings.Contains(torrc, "Log notice stdout") {
+		t.Errorf("expected the notice log line to move into the managed block, got:\n%s", torrc)
+	}
+
+	cfg.TorLogRelay = false
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+	if torrc := read(); torrc != baseTorrc {
+		t.Errorf("expected the notice log line to be restored, got:\n%s\nwant:\n%s", torrc, baseTorrc)
+	}
+}
+
+func TestPrepareTorrc_KeepsCustomOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	custom := baseTorrc + "ExitNodes {de}\nStrictNodes 1\n"
+	if err := os.WriteFile(path, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PrepareTorrc(path, &config.Config{}); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != custom {
+		t.Errorf("expected options Torarr did not write to be kept, got:\n%s", data)
+	}
+}
+
+func TestPrepareTorrc_Unchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte(baseTorrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PrepareTorrc(path, &config.Config{}); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != baseTorrc {
+		t.Errorf("expected torrc to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestPrepareTorrc_MissingFile(t *testing.T) {
+	if err := PrepareTorrc(filepath.Join(t.TempDir(), "torrc"), &config.Config{}); err == nil {
+		t.Error("expected an error for a missing torrc")
+	}
+}
+
+func TestPrepareTorrc_Generate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte(baseTorrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		TorrcGenerate:     true,
+		TorControlAddress: "127.0.0.1:9051",
+		TorDataDirectory:  "/var/lib/tor",
+		TorSocksPort:      "0.0.0.0:9050",
+		TorSocksIsolation: []string{"IsolateDestAddr"},
+		TorSocksListeners: []config.SocksListener{{Name: "radarr", Address: "9061", Flags: []string{"IsolateSOCKSAuth"}}},
+		TorExitNodes:      "{us}",
+		TorUseBridges:     true,
+		TorBridges:        []string{"snowflake 192.0.2.3:80 2B280B23E1107BB62ABFC40DDCC8824814F80A72 fronts=a.example,b.example"},
+		TorTransportPlugins: []string{
+			"obfs4,webtunnel exec /usr/bin/lyrebird",
+			"snowflake exec /usr/bin/snowflake-client",
+		},
+	}
+	if err := PrepareTorrc(path, cfg); err != nil {
+		t.Fatalf("PrepareTorrc() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrc := string(data)
+	if !strings.HasPrefix(torrc, "# Generated by torarr") {
+		t.Errorf("expected a generated torrc, got:\n%s", torrc)
+	}
+	for _, line := range []string{"SocksPort 0.0.0.0:9050 IsolateDestAddr\n", "SocksPort 9061 IsolateSOCKSAuth\n", "ControlPort 127.0.0.1:9051\n", "ExitNodes {us}\n", "StrictNodes 1\n",
+		"UseBridges 1\n", "ClientTransportPlugin snowflake exec /usr/bin/snowflake-client\n", "Bridge snowflake 192.0.2.3:80 "} {
+		if !strings.Contains(torrc, line) {
+			t.Errorf("expected %q in:\n%s", line, torrc)
+		}
+	}
+}
+
+func TestTorrcConfig(t *testing.T) {
+	c, err := TorrcConfig(&config.Config{
+		TorControlAddress:  "unix:/var/lib/tor/control.sock",
+		TorControlPassword: "secret",
+		TorDataDirectory:   "/var/lib/tor",
+		TorSocksPort:       "9050",
+		TorLogRelay:        true,
+	})
+	if err != nil {
+		t.Fatalf("TorrcConfig() error = %v", err)
+	}
+
+	if c.ControlPort != "0" || c.ControlSocket != "/var/lib/tor/control.sock" {
+		t.Errorf("expected a control socket only, got port=%q socket=%q", c.ControlPort, c.ControlSocket)
+	}
+	if !strings.HasPrefix(c.HashedControlPassword, "16:") {
+		t.Errorf("expected a hashed password, got %q", c.HashedControlPassword)
+	}
+	if c.LogLevel != "err" {
+		t.Errorf("expected relayed logs to leave only errors on stdout, got %q", c.LogLevel)
+	}
+	if c.StrictNodes {
+		t.Error("expected StrictNodes to stay off without exit nodes")
+	}
+	if c.UseBridges || len(c.ClientTransportPlugins) != 0 {
+		t.Error("expected bridges and transport plugins to stay off without bridges")
+	}
+}
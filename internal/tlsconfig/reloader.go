@@ -0,0 +1,197 @@
+// Package tlsconfig serves TLS certificates from disk and picks up renewed
+// certificates without restarting the server.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClientAuth modes accepted by HEALTH_TLS_CLIENT_AUTH.
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// Reloader holds the current server certificate and client CA pool and
+// reloads them when their files change.
+type Reloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+
+	mu        sync.RWMutex // Protects cert, clientCAs and modTimes
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// NewReloader loads the certificate, key and optional client CA bundle. The
+// initial load must succeed; later reload failures keep the previous files.
+func NewReloader(certFile, keyFile, caFile, clientAuth string) (*Reloader, error) {
+	mode, err := parseClientAuth(clientAuth, caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		clientAuth: mode,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func parseClientAuth(clientAuth, caFile string) (tls.ClientAuthType, error) {
+	if caFile == "" {
+		if clientAuth != "" && clientAuth != ClientAuthNone {
+			return tls.NoClientCert, fmt.Errorf("client auth %q requires a client CA bundle", clientAuth)
+		}
+		return tls.NoClientCert, nil
+	}
+
+	switch clientAuth {
+	case "", ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("invalid client auth %q", clientAuth)
+	}
+}
+
+// TLSConfig returns a server configuration that always uses the most
+// recently loaded certificate and client CA pool.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// Watch polls the files every interval and reloads them when any
+// modification time changes. It blocks until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !r.changed() {
+			continue
+		}
+
+		if err := r.reload(); err != nil {
+			slog.Error("Failed to reload TLS certificates; keeping previous ones", "error", err)
+			continue
+		}
+		slog.Info("Reloaded TLS certificates", "cert_file", r.certFile, "client_ca_file", r.caFile)
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
+
+// changed reports whether any file's modification time differs from the
+// last successful load. Files that cannot be read count as unchanged until
+// they reappear, which covers editors and tools that replace files.
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, clientCAs, err := r.load()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Remember the attempt even when it fails so a broken file is reported
+	// once rather than on every poll; the next write triggers a retry.
+	r.modTimes = modTimes
+	if err != nil {
+		return err
+	}
+	r.cert = cert
+	r.clientCAs = clientCAs
+
+	return nil
+}
+
+func (r *Reloader) load() (*tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	if r.caFile == "" {
+		return &cert, nil, nil
+	}
+
+	pem, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("no certificates found in client CA bundle %s", r.caFile)
+	}
+
+	return &cert, clientCAs, nil
+}
@@ -0,0 +1,284 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	pem    []byte
+	keyPEM []byte
+}
+
+// newTestCert issues a certificate signed by parent, or a self-signed CA
+// when parent is nil.
+func newTestCert(t *testing.T, serial int64, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "torarr-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:   cert,
+		key:    key,
+		pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	pair, err := tls.X509KeyPair(c.pem, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testFiles struct {
+	ca       *testCert
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+func newTestFiles(t *testing.T) *testFiles {
+	t.Helper()
+
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, nil, 0)
+	server := newTestCert(t, 2, ca, x509.ExtKeyUsageServerAuth)
+	f := &testFiles{
+		ca:       ca,
+		certFile: filepath.Join(dir, "cert.pem"),
+		keyFile:  filepath.Join(dir, "key.pem"),
+		caFile:   filepath.Join(dir, "ca.pem"),
+	}
+
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, f.certFile, server.pem, modTime)
+	writeFile(t, f.keyFile, server.keyPEM, modTime)
+	writeFile(t, f.caFile, ca.pem, modTime)
+
+	return f
+}
+
+func startServer(t *testing.T, r *Reloader) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(roots *x509.CertPool, certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		},
+	}
+}
+
+func get(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestReloader_ServesCertificate(t *testing.T) {
+	f := newTestFiles(t)
+
+	r, err := NewReloader(f.certFile, f.keyFile, "", "")
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	srv := startServer(t, r)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(f.ca.cert)
+	if err := get(newClient(roots), srv.URL); err != nil {
+		t.Errorf("expected TLS request to succeed, got %v", err)
+	}
+}
+
+func TestReloader_ClientAuth(t *testing.T) {
+	f := newTestFiles(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(f.ca.cert)
+	client := newTestCert(t, 3, f.ca, x509.ExtKeyUsageClientAuth).tlsCertificate(t)
+	untrusted := newTestCert(t, 4, newTestCert(t, 5, nil, 0), x509.ExtKeyUsageClientAuth).tlsCertificate(t)
+
+	tests := []struct {
+		mode    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{
+		{mode: ClientAuthRequire, certs: []tls.Certificate{client}},
+		{mode: ClientAuthRequire, wantErr: true},
+		{mode: ClientAuthRequire, certs: []tls.Certificate{untrusted}, wantErr: true},
+		{mode: ClientAuthOptional},
+		{mode: ClientAuthOptional, certs: []tls.Certificate{client}},
+		{mode: ClientAuthOptional, certs: []tls.Certificate{untrusted}, wantErr: true},
+		{mode: ClientAuthNone},
+	}
+
+	for _, tt := range tests {
+		r, err := NewReloader(f.certFile, f.keyFile, f.caFile, tt.mode)
+		if err != nil {
+			t.Fatalf("NewReloader(%s) error = %v", tt.mode, err)
+		}
+		srv := startServer(t, r)
+
+		err = get(newClient(roots, tt.certs...), srv.URL)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("mode %s with %d client certs: error = %v, wantErr %v", tt.mode, len(tt.certs), err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewReloader_Errors(t *testing.T) {
+	f := newTestFiles(t)
+
+	tests := []struct {
+		name                string
+		cert, key, ca, mode string
+		wantErr             string
+	}{
+		{name: "missing cert", cert: "/nonexistent/cert.pem", key: f.keyFile, wantErr: "failed to stat"},
+		{name: "mismatched key", cert: f.caFile, key: f.keyFile, wantErr: "failed to load TLS key pair"},
+		{name: "invalid CA bundle", cert: f.certFile, key: f.keyFile, ca: f.keyFile, wantErr: "no certificates found"},
+		{name: "client auth without CA", cert: f.certFile, key: f.keyFile, mode: ClientAuthRequire, wantErr: "requires a client CA"},
+		{name: "invalid client auth", cert: f.certFile, key: f.keyFile, ca: f.caFile, mode: "sometimes", wantErr: "invalid client auth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReloader(tt.cert, tt.key, tt.ca, tt.mode)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	f := newTestFiles(t)
+
+	r, err := NewReloader(f.certFile, f.keyFile, "", "")
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	srv := startServer(t, r)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(f.ca.cert)
+	serverSerial := func() int64 {
+		t.Helper()
+		conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	waitForSerial := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for serverSerial() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for certificate serial %d", want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if got := serverSerial(); got != 2 {
+		t.Fatalf("expected initial serial 2, got %d", got)
+	}
+
+	// A broken key must not replace the working certificate.
+	writeFile(t, f.keyFile, []byte("not a key"), time.Now())
+	time.Sleep(50 * time.Millisecond)
+	if got := serverSerial(); got != 2 {
+		t.Fatalf("expected serial 2 to survive a failed reload, got %d", got)
+	}
+
+	renewed := newTestCert(t, 6, f.ca, x509.ExtKeyUsageServerAuth)
+	modTime := time.Now().Add(time.Second)
+	writeFile(t, f.certFile, renewed.pem, modTime)
+	writeFile(t, f.keyFile, renewed.keyPEM, modTime)
+	waitForSerial(6)
+}
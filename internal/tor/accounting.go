@@ -0,0 +1,122 @@
+package tor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountingTimeLayout is the format of GETINFO accounting/interval-end.
+const accountingTimeLayout = "2006-01-02 15:04:05"
+
+// TrafficLimits are the bandwidth and accounting options applied together
+// by SetTrafficLimits. Values use Tor's units, e.g. "1 MBytes"; an empty
+// value leaves the option as Tor has it, such as from a custom torrc.
+type TrafficLimits struct {
+	BandwidthRate   string
+	BandwidthBurst  string
+	AccountingMax   string
+	AccountingStart string
+}
+
+// Accounting is Tor's traffic accounting state for the current period.
+type Accounting struct {
+	Enabled      bool      `json:"enabled"`
+	Hibernating  string    `json:"hibernating,omitempty"` // awake, soft or hard
+	BytesRead    int64     `json:"bytes_read"`
+	BytesWritten int64     `json:"bytes_written"`
+	ReadLeft     int64     `json:"read_left"`
+	WrittenLeft  int64     `json:"written_left"`
+	IntervalEnd  time.Time `json:"interval_end,omitzero"`
+}
+
+// LimitReached reports whether Tor has stopped, or is about to stop,
+// accepting traffic until the accounting period ends.
+func (a *Accounting) LimitReached() bool {
+	return a.Enabled && (a.Hibernating == "soft" || a.Hibernating == "hard")
+}
+
+// SetTrafficLimits applies the limits that are set with a single SETCONF.
+// Nothing is sent when no limit is set.
+func (c *Client) SetTrafficLimits(limits TrafficLimits) error {
+	options := []struct{ key, value string }{
+		{"BandwidthRate", limits.BandwidthRate},
+		{"BandwidthBurst", limits.BandwidthBurst},
+		{"AccountingMax", limits.AccountingMax},
+		{"AccountingStart", limits.AccountingStart},
+	}
+
+	args := make([]string, 0, len(options))
+	for _, option := range options {
+		if strings.ContainsAny(option.value, "\r\n") {
+			return fmt.Errorf("invalid %s %q", option.key, option.value)
+		}
+		if option.value != "" {
+			args = append(args, option.key+"="+quote(option.value))
+		}
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+	return c.confCommand("SETCONF " + strings.Join(args, " "))
+}
+
+// GetAccounting returns Tor's accounting state. When AccountingMax is not
+// set, only Enabled is reported.
+func (c *Client) GetAccounting() (*Accounting, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo("accounting/enabled")
+	if err != nil {
+		return nil, err
+	}
+	if info["accounting/enabled"] != "1" {
+		return &Accounting{}, nil
+	}
+
+	info, err = c.GetInfo("accounting/hibernating", "accounting/bytes", "accounting/bytes-left", "accounting/interval-end")
+	if err != nil {
+		return nil, err
+	}
+	return ParseAccounting(info)
+}
+
+// ParseAccounting parses the GETINFO accounting/* values of an enabled
+// accounting period.
+func ParseAccounting(info map[string]string) (*Accounting, error) {
+	accounting := &Accounting{Enabled: true, Hibernating: info["accounting/hibernating"]}
+
+	var err error
+	if accounting.BytesRead, accounting.BytesWritten, err = parseByteCounts(info["accounting/bytes"]); err != nil {
+		return nil, fmt.Errorf("malformed accounting/bytes: %w", err)
+	}
+	if accounting.ReadLeft, accounting.WrittenLeft, err = parseByteCounts(info["accounting/bytes-left"]); err != nil {
+		return nil, fmt.Errorf("malformed accounting/bytes-left: %w", err)
+	}
+	if end := info["accounting/interval-end"]; end != "" {
+		if accounting.IntervalEnd, err = time.ParseInLocation(accountingTimeLayout, end, time.UTC); err != nil {
+			return nil, fmt.Errorf("malformed accounting/interval-end: %w", err)
+		}
+	}
+
+	return accounting, nil
+}
+
+// parseByteCounts parses a "read written" pair of byte counts.
+func parseByteCounts(value string) (read, written int64, err error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("expected two byte counts, got %q", value)
+	}
+	if read, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if written, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	return read, written, nil
+}
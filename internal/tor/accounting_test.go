@@ -0,0 +1,86 @@
+package tor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetTrafficLimits_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.SetTrafficLimits(TrafficLimits{BandwidthRate: "1 MBytes", AccountingMax: "100 GBytes"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	expected := `SETCONF BandwidthRate="1 MBytes" AccountingMax="100 GBytes"`
+	if got := commands[len(commands)-1]; got != expected {
+		t.Errorf("expected only the set limits in %q, got %q", expected, got)
+	}
+
+	if err := client.SetTrafficLimits(TrafficLimits{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fake.Commands(); len(got) != len(commands) {
+		t.Errorf("expected no command without limits, got %q", got[len(commands):])
+	}
+
+	if err := client.SetTrafficLimits(TrafficLimits{AccountingStart: "day 00:00\r\nSocksPort 0"}); err == nil {
+		t.Error("expected an error for a value with a line break")
+	}
+}
+
+func TestGetAccounting_FakeTor(t *testing.T) {
+	enabled := "1"
+	fake := newFakeTor(t, func(cmd string) string {
+		switch {
+		case cmd == "GETINFO accounting/enabled":
+			return "250-accounting/enabled=" + enabled + "\r\n250 OK\r\n"
+		case strings.HasPrefix(cmd, "GETINFO accounting/hibernating"):
+			return "250-accounting/hibernating=soft\r\n" +
+				"250-accounting/bytes=1000 2000\r\n" +
+				"250-accounting/bytes-left=0 500\r\n" +
+				"250-accounting/interval-end=2026-11-01 00:00:00\r\n" +
+				"250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	accounting, err := client.GetAccounting()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Accounting{
+		Enabled:      true,
+		Hibernating:  "soft",
+		BytesRead:    1000,
+		BytesWritten: 2000,
+		ReadLeft:     0,
+		WrittenLeft:  500,
+		IntervalEnd:  time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if *accounting != expected {
+		t.Errorf("expected %+v, got %+v", expected, *accounting)
+	}
+	if !accounting.LimitReached() {
+		t.Error("expected a hibernating Tor to have reached its limit")
+	}
+
+	enabled = "0"
+	if accounting, err := client.GetAccounting(); err != nil || accounting.Enabled || accounting.LimitReached() {
+		t.Errorf("expected accounting to be disabled, got %+v, %v", accounting, err)
+	}
+}
+
+func TestParseAccounting_Malformed(t *testing.T) {
+	if _, err := ParseAccounting(map[string]string{"accounting/bytes": "1000"}); err == nil {
+		t.Error("expected an error for a malformed byte count")
+	}
+}
@@ -0,0 +1,320 @@
+package tor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AuthMethod selects how the client authenticates to the control port.
+type AuthMethod string
+
+const (
+	AuthAuto       AuthMethod = "auto"
+	AuthNull       AuthMethod = "null"
+	AuthPassword   AuthMethod = "password"
+	AuthCookie     AuthMethod = "cookie"
+	AuthSafeCookie AuthMethod = "safecookie"
+)
+
+// cookieFileName is the name Tor uses for the cookie inside its DataDirectory.
+const cookieFileName = "control_auth_cookie"
+
+// passwordHashSpecifier is the S2K count byte Tor uses for
+// HashedControlPassword, selecting 65536 bytes of hashed input.
+const passwordHashSpecifier = 0x60
+
+const (
+	safeCookieServerKey = "Tor safe cookie authentication server-to-controller hash"
+	safeCookieClientKey = "Tor safe cookie authentication controller-to-server hash"
+)
+
+// Auth describes the credentials used to authenticate to the control port.
+type Auth struct {
+	Method        AuthMethod
+	Password      string
+	CookieFile    string // Overrides the COOKIEFILE advertised by PROTOCOLINFO
+	DataDirectory string // Fallback location of the cookie when neither is known
+}
+
+// protocolInfo is the parsed reply to PROTOCOLINFO.
+type protocolInfo struct {
+	methods    map[string]bool
+	cookieFile string
+	version    string
+}
+
+func (c *Client) protocolInfo() (*protocolInfo, error) {
+	resp, err := c.roundTrip("PROTOCOLINFO 1")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code != 250 {
+		return nil, fmt.Errorf("protocolinfo failed: %s", resp.message())
+	}
+
+	return parseProtocolInfo(resp), nil
+}
+
+func parseProtocolInfo(resp *reply) *protocolInfo {
+	info := &protocolInfo{methods: make(map[string]bool)}
+
+	for _, line := range resp.lines {
+		keyword, rest, _ := strings.Cut(line.text, " ")
+		_, args := parseArgs(rest)
+
+		switch keyword {
+		case "AUTH":
+			for _, method := range strings.Split(args["METHODS"], ",") {
+				if method != "" {
+					info.methods[method] = true
+				}
+			}
+			info.cookieFile = args["COOKIEFILE"]
+		case "VERSION":
+			info.version = args["Tor"]
+		}
+	}
+
+	return info
+}
+
+// authenticate negotiates an authentication method via PROTOCOLINFO and
+// sends the matching AUTHENTICATE command. Callers must hold c.mu.
+func (c *Client) authenticate() error {
+	info, err := c.protocolInfo()
+	if err != nil {
+		return err
+	}
+
+	method, err := c.chooseAuthMethod(info)
+	if err != nil {
+		return err
+	}
+
+	var cmd string
+	switch method {
+	case AuthNull:
+		cmd = "AUTHENTICATE"
+	case AuthPassword:
+		cmd = fmt.Sprintf("AUTHENTICATE %s", quote(c.password))
+	case AuthCookie:
+		cookie, err := c.readCookie(info)
+		if err != nil {
+			return err
+		}
+		cmd = fmt.Sprintf("AUTHENTICATE %s", hex.EncodeToString(cookie))
+	case AuthSafeCookie:
+		clientHash, err := c.safeCookieChallenge(info)
+		if err != nil {
+			return err
+		}
+		cmd = fmt.Sprintf("AUTHENTICATE %s", hex.EncodeToString(clientHash))
+	}
+
+	resp, err := c.roundTrip(cmd)
+	if err != nil {
+		return err
+	}
+
+	if resp.code != 250 {
+		return fmt.Errorf("authentication failed: %s", resp.message())
+	}
+
+	return nil
+}
+
+// chooseAuthMethod resolves the configured method against what Tor offers.
+// In auto mode, NULL is preferred, then a configured password, then
+// SAFECOOKIE and finally plain COOKIE.
+func (c *Client) chooseAuthMethod(info *protocolInfo) (AuthMethod, error) {
+	offered := func(method AuthMethod) bool {
+		switch method {
+		case AuthNull:
+			return info.methods["NULL"]
+		case AuthPassword:
+			return info.methods["HASHEDPASSWORD"]
+		case AuthCookie:
+			return info.methods["COOKIE"]
+		case AuthSafeCookie:
+			return info.methods["SAFECOOKIE"]
+		}
+		return false
+	}
+
+	switch c.authMethod {
+	case AuthNull, AuthPassword, AuthCookie, AuthSafeCookie:
+		if !offered(c.authMethod) {
+			return "", fmt.Errorf("authentication failed: tor does not accept %s authentication (offered: %s)", c.authMethod, info.methodList())
+		}
+		if c.authMethod == AuthPassword && c.password == "" {
+			return "", fmt.Errorf("authentication failed: password authentication selected but no password configured")
+		}
+		return c.authMethod, nil
+	case AuthAuto, "":
+		switch {
+		case offered(AuthNull):
+			return AuthNull, nil
+		case offered(AuthPassword) && c.password != "":
+			return AuthPassword, nil
+		case offered(AuthSafeCookie):
+			return AuthSafeCookie, nil
+		case offered(AuthCookie):
+			return AuthCookie, nil
+		}
+		return "", fmt.Errorf("authentication failed: no usable authentication method (offered: %s)", info.methodList())
+	default:
+		return "", fmt.Errorf("authentication failed: unknown authentication method %q", c.authMethod)
+	}
+}
+
+func (info *protocolInfo) methodList() string {
+	methods := make([]string, 0, len(info.methods))
+	for _, method := range []string{"NULL", "HASHEDPASSWORD", "COOKIE", "SAFECOOKIE"} {
+		if info.methods[method] {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return "none"
+	}
+	return strings.Join(methods, ",")
+}
+
+// cookiePath returns the configured cookie file, the one advertised by Tor,
+// or the default location inside the data directory, in that order.
+func (c *Client) cookiePath(info *protocolInfo) string {
+	switch {
+	case c.cookieFile != "":
+		return c.cookieFile
+	case info.cookieFile != "":
+		return info.cookieFile
+	case c.dataDirectory != "":
+		return filepath.Join(c.dataDirectory, cookieFileName)
+	}
+	return ""
+}
+
+func (c *Client) readCookie(info *protocolInfo) ([]byte, error) {
+	path := c.cookiePath(info)
+	if path == "" {
+		return nil, fmt.Errorf("authentication failed: cookie file location unknown")
+	}
+
+	cookie, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth cookie: %w", err)
+	}
+
+	if len(cookie) != 32 {
+		return nil, fmt.Errorf("invalid auth cookie %s: expected 32 bytes, got %d", path, len(cookie))
+	}
+
+	return cookie, nil
+}
+
+// safeCookieChallenge performs the AUTHCHALLENGE exchange and returns the
+// controller-to-server hash to send with AUTHENTICATE.
+func (c *Client) safeCookieChallenge(info *protocolInfo) ([]byte, error) {
+	cookie, err := c.readCookie(info)
+	if err != nil {
+		return nil, err
+	}
+
+	clientNonce := make([]byte, 32)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate client nonce: %w", err)
+	}
+
+	resp, err := c.roundTrip(fmt.Sprintf("AUTHCHALLENGE SAFECOOKIE %s", hex.EncodeToString(clientNonce)))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code != 250 {
+		return nil, fmt.Errorf("authchallenge failed: %s", resp.message())
+	}
+
+	_, args := parseArgs(strings.TrimPrefix(resp.message(), "AUTHCHALLENGE "))
+
+	serverHash, err := hex.DecodeString(args["SERVERHASH"])
+	if err != nil {
+		return nil, fmt.Errorf("malformed authchallenge server hash: %w", err)
+	}
+
+	serverNonce, err := hex.DecodeString(args["SERVERNONCE"])
+	if err != nil {
+		return nil, fmt.Errorf("malformed authchallenge server nonce: %w", err)
+	}
+
+	expected := safeCookieHash(safeCookieServerKey, cookie, clientNonce, serverNonce)
+	if !hmac.Equal(serverHash, expected) {
+		return nil, fmt.Errorf("authentication failed: tor sent an invalid safecookie server hash")
+	}
+
+	return safeCookieHash(safeCookieClientKey, cookie, clientNonce, serverNonce), nil
+}
+
+func safeCookieHash(key string, cookie, clientNonce, serverNonce []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(cookie)
+	mac.Write(clientNonce)
+	mac.Write(serverNonce)
+	return mac.Sum(nil)
+}
+
+// quote encodes s as a control protocol QuotedString.
+func quote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// HashPassword returns the HashedControlPassword value for password, as
+// produced by "tor --hash-password".
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate password salt: %w", err)
+	}
+	return hashPassword(password, salt), nil
+}
+
+// CheckHashedPassword reports whether hashed is a HashedControlPassword
+// value for password, so an existing hash can be kept instead of re-salted.
+func CheckHashedPassword(hashed, password string) bool {
+	encoded, ok := strings.CutPrefix(hashed, "16:")
+	if !ok {
+		return false
+	}
+	key, err := hex.DecodeString(encoded)
+	if err != nil || len(key) != 8+1+sha1.Size || key[8] != passwordHashSpecifier {
+		return false
+	}
+	return hmac.Equal([]byte(hashPassword(password, key[:8])), []byte(strings.ToUpper(hashed)))
+}
+
+// hashPassword applies the OpenPGP iterated and salted S2K with SHA-1
+// (RFC 4880 section 3.7.1.3) and encodes it the way Tor expects.
+func hashPassword(password string, salt []byte) string {
+	input := append(slices.Clone(salt), password...)
+	count := (16 + (passwordHashSpecifier & 15)) << ((passwordHashSpecifier >> 4) + 6)
+
+	h := sha1.New()
+	for count > 0 {
+		n := min(count, len(input))
+		h.Write(input[:n])
+		count -= n
+	}
+
+	key := append(slices.Clone(salt), passwordHashSpecifier)
+	key = h.Sum(key)
+	return "16:" + strings.ToUpper(hex.EncodeToString(key))
+}
@@ -0,0 +1,256 @@
+package tor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProtocolInfo(t *testing.T) {
+	reply := protocolInfoReply("COOKIE,SAFECOOKIE,HASHEDPASSWORD", "/var/lib/tor/control_auth_cookie")
+	resp, err := readReply(bufio.NewReader(strings.NewReader(reply)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info := parseProtocolInfo(resp)
+
+	for _, method := range []string{"COOKIE", "SAFECOOKIE", "HASHEDPASSWORD"} {
+		if !info.methods[method] {
+			t.Errorf("expected method %s to be offered", method)
+		}
+	}
+	if info.methods["NULL"] {
+		t.Error("expected NULL not to be offered")
+	}
+	if info.cookieFile != "/var/lib/tor/control_auth_cookie" {
+		t.Errorf("expected cookie file to be parsed, got %q", info.cookieFile)
+	}
+	if info.version != "0.4.8.10" {
+		t.Errorf("expected version 0.4.8.10, got %q", info.version)
+	}
+}
+
+func TestChooseAuthMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   AuthMethod
+		password string
+		offered  []string
+		expected AuthMethod
+		wantErr  bool
+	}{
+		{name: "Auto prefers NULL", method: AuthAuto, offered: []string{"NULL", "SAFECOOKIE"}, expected: AuthNull},
+		{name: "Auto uses configured password", method: AuthAuto, password: "secret", offered: []string{"HASHEDPASSWORD", "SAFECOOKIE"}, expected: AuthPassword},
+		{name: "Auto skips password when unset", method: AuthAuto, offered: []string{"HASHEDPASSWORD", "COOKIE", "SAFECOOKIE"}, expected: AuthSafeCookie},
+		{name: "Auto falls back to cookie", method: AuthAuto, offered: []string{"COOKIE"}, expected: AuthCookie},
+		{name: "Empty method behaves as auto", method: "", offered: []string{"NULL"}, expected: AuthNull},
+		{name: "Auto with nothing usable", method: AuthAuto, offered: []string{"HASHEDPASSWORD"}, wantErr: true},
+		{name: "Explicit cookie offered", method: AuthCookie, offered: []string{"COOKIE", "SAFECOOKIE"}, expected: AuthCookie},
+		{name: "Explicit method not offered", method: AuthSafeCookie, offered: []string{"HASHEDPASSWORD"}, wantErr: true},
+		{name: "Explicit password without password", method: AuthPassword, offered: []string{"HASHEDPASSWORD"}, wantErr: true},
+		{name: "Unknown method", method: "kerberos", offered: []string{"NULL"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithAuth("127.0.0.1:9051", Auth{Method: tt.method, Password: tt.password})
+			info := &protocolInfo{methods: make(map[string]bool)}
+			for _, m := range tt.offered {
+				info.methods[m] = true
+			}
+
+			method, err := client.chooseAuthMethod(info)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got method %q", method)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if method != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, method)
+			}
+		})
+	}
+}
+
+func TestCookiePath_Precedence(t *testing.T) {
+	advertised := &protocolInfo{cookieFile: "/run/tor/cookie"}
+	none := &protocolInfo{}
+
+	client := NewClientWithAuth("", Auth{CookieFile: "/custom/cookie", DataDirectory: "/data"})
+	if got := client.cookiePath(advertised); got != "/custom/cookie" {
+		t.Errorf("expected configured cookie file to win, got %q", got)
+	}
+
+	client = NewClientWithAuth("", Auth{DataDirectory: "/data"})
+	if got := client.cookiePath(advertised); got != "/run/tor/cookie" {
+		t.Errorf("expected advertised cookie file, got %q", got)
+	}
+	if got := client.cookiePath(none); got != filepath.Join("/data", cookieFileName) {
+		t.Errorf("expected data directory fallback, got %q", got)
+	}
+}
+
+func TestAuthenticate_Cookie(t *testing.T) {
+	cookie := bytes.Repeat([]byte{0xAB}, 32)
+	cookieFile := writeCookie(t, cookie)
+
+	fake := newFakeTor(t, func(cmd string) string {
+		switch {
+		case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+			return protocolInfoReply("COOKIE", cookieFile)
+		case cmd == "AUTHENTICATE "+hex.EncodeToString(cookie):
+			return "250 OK\r\n"
+		case strings.HasPrefix(cmd, "AUTHENTICATE"):
+			return "515 Authentication failed: Wrong length on authentication cookie.\r\n"
+		}
+		return "250 OK\r\n"
+	})
+
+	client := NewClientWithAuth(fake.Addr(), Auth{Method: AuthCookie})
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("expected cookie authentication to succeed, got %v", err)
+	}
+}
+
+func TestAuthenticate_SafeCookie(t *testing.T) {
+	cookie := bytes.Repeat([]byte{0x42}, 32)
+	cookieFile := writeCookie(t, cookie)
+	serverNonce := bytes.Repeat([]byte{0x07}, 32)
+
+	var clientNonce []byte
+	fake := newFakeTor(t, func(cmd string) string {
+		switch {
+		case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+			return protocolInfoReply("COOKIE,SAFECOOKIE", cookieFile)
+		case strings.HasPrefix(cmd, "AUTHCHALLENGE SAFECOOKIE "):
+			nonce, err := hex.DecodeString(strings.TrimPrefix(cmd, "AUTHCHALLENGE SAFECOOKIE "))
+			if err != nil {
+				return "513 Invalid nonce\r\n"
+			}
+			clientNonce = nonce
+			serverHash := safeCookieHash(safeCookieServerKey, cookie, clientNonce, serverNonce)
+			return "250 AUTHCHALLENGE SERVERHASH=" + strings.ToUpper(hex.EncodeToString(serverHash)) +
+				" SERVERNONCE=" + hex.EncodeToString(serverNonce) + "\r\n"
+		case strings.HasPrefix(cmd, "AUTHENTICATE "):
+			expected := safeCookieHash(safeCookieClientKey, cookie, clientNonce, serverNonce)
+			if strings.TrimPrefix(cmd, "AUTHENTICATE ") == hex.EncodeToString(expected) {
+				return "250 OK\r\n"
+			}
+			return "515 Authentication failed: Safe cookie response did not match expected value.\r\n"
+		}
+		return "250 OK\r\n"
+	})
+
+	client := NewClientWithAuth(fake.Addr(), Auth{Method: AuthAuto})
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("expected safecookie authentication to succeed, got %v", err)
+	}
+
+	commands := fake.Commands()
+	if len(commands) < 2 || !strings.HasPrefix(commands[1], "AUTHCHALLENGE SAFECOOKIE ") {
+		t.Errorf("expected auto mode to prefer SAFECOOKIE, got %v", commands)
+	}
+}
+
+func TestAuthenticate_SafeCookieBadServerHash(t *testing.T) {
+	cookieFile := writeCookie(t, bytes.Repeat([]byte{0x42}, 32))
+
+	fake := newFakeTor(t, func(cmd string) string {
+		switch {
+		case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+			return protocolInfoReply("SAFECOOKIE", cookieFile)
+		case strings.HasPrefix(cmd, "AUTHCHALLENGE"):
+			return "250 AUTHCHALLENGE SERVERHASH=" + strings.Repeat("00", 32) +
+				" SERVERNONCE=" + strings.Repeat("11", 32) + "\r\n"
+		}
+		return "250 OK\r\n"
+	})
+
+	client := NewClientWithAuth(fake.Addr(), Auth{Method: AuthSafeCookie})
+
+	err := client.Connect()
+	if err == nil {
+		_ = client.Close()
+		t.Fatal("expected mismatched server hash to fail authentication")
+	}
+	if !strings.Contains(err.Error(), "server hash") {
+		t.Errorf("expected server hash error, got %v", err)
+	}
+	for _, cmd := range fake.Commands() {
+		if strings.HasPrefix(cmd, "AUTHENTICATE") {
+			t.Errorf("expected no AUTHENTICATE after a bad server hash, got %q", cmd)
+		}
+	}
+}
+
+func TestReadCookie_InvalidLength(t *testing.T) {
+	cookieFile := writeCookie(t, []byte("short"))
+
+	client := NewClientWithAuth("", Auth{CookieFile: cookieFile})
+	if _, err := client.readCookie(&protocolInfo{}); err == nil {
+		t.Error("expected error for cookie of wrong length")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote(`pa"ss\word`); got != `"pa\"ss\\word"` {
+		t.Errorf("unexpected quoted string: %s", got)
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	salt := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	expected := "16:010203040506070860E7FFDF52CE2082EAC77C46023680FFE53C67399C"
+	if got := hashPassword("torarr", salt); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	first, err := HashPassword("torarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := HashPassword("torarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(first, "16:") || len(first) != 3+2*29 {
+		t.Errorf("unexpected hash format: %s", first)
+	}
+	if first == second {
+		t.Error("expected a fresh salt for each hash")
+	}
+
+	if !CheckHashedPassword(first, "torarr") || !CheckHashedPassword(strings.ToLower(expected), "torarr") {
+		t.Error("expected the hashes to verify against their password")
+	}
+	for _, hashed := range []string{"", expected[3:], "16:0102", "16:ZZ" + expected[5:]} {
+		if CheckHashedPassword(hashed, "torarr") {
+			t.Errorf("expected %q not to verify", hashed)
+		}
+	}
+	if CheckHashedPassword(first, "other") {
+		t.Error("expected a different password not to verify")
+	}
+}
+
+func writeCookie(t *testing.T, cookie []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), cookieFileName)
+	if err := os.WriteFile(path, cookie, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
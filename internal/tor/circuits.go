@@ -0,0 +1,283 @@
+package tor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timeCreatedLayout is the ISOTime2Frac format used by TIME_CREATED.
+const timeCreatedLayout = "2006-01-02T15:04:05.999999999"
+
+// ErrUnknownCircuit is returned when Tor does not know the circuit.
+var ErrUnknownCircuit = errors.New("unknown circuit")
+
+// ErrRejected is returned when Tor rejects a request's arguments, such as a
+// relay it does not know.
+var ErrRejected = errors.New("rejected by tor")
+
+// validID matches circuit and stream IDs, which are 1 to 16 alphanumerics.
+var validID = regexp.MustCompile(`^[A-Za-z0-9]{1,16}$`)
+
+// validRelay matches a relay as "$fingerprint", "$fingerprint~nickname" or
+// a nickname.
+var validRelay = regexp.MustCompile(`^(\$[A-Fa-f0-9]{40}([~=][A-Za-z0-9]{1,19})?|[A-Za-z0-9]{1,19})$`)
+
+// Circuit is a single entry from GETINFO circuit-status or a CIRC event.
+type Circuit struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	Path        []Relay   `json:"path"`
+	BuildFlags  []string  `json:"build_flags,omitempty"`
+	Purpose     string    `json:"purpose,omitempty"`
+	TimeCreated time.Time `json:"time_created,omitzero"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Relay identifies a hop in a circuit path.
+type Relay struct {
+	Fingerprint string `json:"fingerprint"`
+	Nickname    string `json:"nickname,omitempty"`
+}
+
+// Exit returns the last hop of the circuit, if any.
+func (c Circuit) Exit() (Relay, bool) {
+	if len(c.Path) == 0 {
+		return Relay{}, false
+	}
+	return c.Path[len(c.Path)-1], true
+}
+
+// GetCircuits returns all circuits Tor currently knows about.
+func (c *Client) GetCircuits() ([]Circuit, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo("circuit-status")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCircuitStatus(info["circuit-status"])
+}
+
+// ValidFingerprint reports whether fingerprint is a relay identity
+// fingerprint, with or without a leading "$".
+func ValidFingerprint(fingerprint string) bool {
+	fingerprint = strings.TrimPrefix(fingerprint, "$")
+	return validRelay.MatchString("$"+fingerprint) && len(fingerprint) == 40
+}
+
+// ValidID reports whether id is a well-formed circuit or stream ID.
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+// ValidRelay reports whether relay is a well-formed "$fingerprint",
+// "$fingerprint~nickname" or nickname for ExtendCircuit.
+func ValidRelay(relay string) bool {
+	return validRelay.MatchString(relay)
+}
+
+// CloseCircuit closes a circuit and the streams on it; Tor attaches new
+// streams to other circuits. With ifUnused, the circuit is only closed if
+// no streams are using it. It returns ErrUnknownCircuit if the circuit does
+// not exist.
+func (c *Client) CloseCircuit(id string, ifUnused bool) error {
+	if !ValidID(id) {
+		return fmt.Errorf("invalid circuit id %q", id)
+	}
+
+	cmd := "CLOSECIRCUIT " + id
+	if ifUnused {
+		cmd += " IfUnused"
+	}
+	return c.idCommand(cmd, ErrUnknownCircuit, id)
+}
+
+// ExtendCircuit extends the circuit through the given relays, or builds a
+// new circuit when id is "0", and returns the circuit's ID. Relays are
+// "$fingerprint" or nicknames; with none, Tor picks the path itself.
+func (c *Client) ExtendCircuit(id string, path []string) (string, error) {
+	if !ValidID(id) {
+		return "", fmt.Errorf("invalid circuit id %q", id)
+	}
+	for _, relay := range path {
+		if !ValidRelay(relay) {
+			return "", fmt.Errorf("invalid relay %q", relay)
+		}
+	}
+
+	if err := c.Connect(); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cmd := "EXTENDCIRCUIT " + id
+	if len(path) > 0 {
+		cmd += " " + strings.Join(path, ",")
+	}
+	resp, err := c.roundTrip(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	switch resp.code {
+	case 250:
+	case 552:
+		// Tor also answers 552 for a relay in the path it does not know
+		if strings.HasPrefix(resp.message(), "Unknown circuit") {
+			return "", fmt.Errorf("extendcircuit failed: %w: %s", ErrUnknownCircuit, id)
+		}
+		return "", fmt.Errorf("extendcircuit failed: %w: %s", ErrRejected, resp.message())
+	default:
+		return "", fmt.Errorf("extendcircuit failed: %s", resp.message())
+	}
+
+	// The reply is "250 EXTENDED CircuitID"
+	fields := strings.Fields(resp.message())
+	if len(fields) != 2 || fields[0] != "EXTENDED" {
+		return "", fmt.Errorf("unexpected extendcircuit reply: %q", resp.message())
+	}
+	return fields[1], nil
+}
+
+// idCommand sends a command addressing a circuit or stream by ID, mapping
+// Tor's 552 reply to unknown.
+func (c *Client) idCommand(cmd string, unknown error, id string) error {
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(cmd)
+	if err != nil {
+		return err
+	}
+
+	verb := strings.ToLower(strings.Fields(cmd)[0])
+	switch resp.code {
+	case 250:
+		return nil
+	case 552:
+		return fmt.Errorf("%s failed: %w: %s", verb, unknown, id)
+	default:
+		return fmt.Errorf("%s failed: %s", verb, resp.message())
+	}
+}
+
+// ParseCircuitStatus parses the multi-line value of GETINFO circuit-status.
+func ParseCircuitStatus(data string) ([]Circuit, error) {
+	circuits := []Circuit{}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		circuit, err := ParseCircuit(line)
+		if err != nil {
+			return nil, err
+		}
+		circuits = append(circuits, circuit)
+	}
+
+	return circuits, nil
+}
+
+// ParseCircuitEvent parses a CIRC event, which shares the circuit-status format.
+func ParseCircuitEvent(evt Event) (Circuit, error) {
+	return ParseCircuit(evt.Data)
+}
+
+// ParseCircuit parses a single circuit description:
+//
+//	CircuitID SP CircStatus [SP Path] [SP KEYWORD=Value]...
+func ParseCircuit(line string) (Circuit, error) {
+	tokens := splitQuoted(line)
+	if len(tokens) < 2 {
+		return Circuit{}, fmt.Errorf("malformed circuit: %q", line)
+	}
+
+	circuit := Circuit{
+		ID:     tokens[0],
+		Status: tokens[1],
+		Path:   []Relay{},
+	}
+
+	rest := tokens[2:]
+	// The path is optional and, since LongNames may contain "=", it is
+	// recognised by its leading "$" rather than by the absence of a keyword.
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "$") {
+		circuit.Path = parsePath(rest[0])
+		rest = rest[1:]
+	}
+
+	_, args := parseArgs(strings.Join(rest, " "))
+	circuit.Purpose = args["PURPOSE"]
+	circuit.Reason = args["REASON"]
+
+	if flags := args["BUILD_FLAGS"]; flags != "" {
+		circuit.BuildFlags = strings.Split(flags, ",")
+	}
+
+	if created := args["TIME_CREATED"]; created != "" {
+		t, err := time.ParseInLocation(timeCreatedLayout, created, time.UTC)
+		if err != nil {
+			return Circuit{}, fmt.Errorf("malformed circuit TIME_CREATED %q: %w", created, err)
+		}
+		circuit.TimeCreated = t
+	}
+
+	return circuit, nil
+}
+
+// parsePath parses a comma-separated list of LongNames ("$FP~nick", "$FP=nick" or "$FP").
+func parsePath(path string) []Relay {
+	hops := strings.Split(path, ",")
+	relays := make([]Relay, 0, len(hops))
+
+	for _, hop := range hops {
+		hop = strings.TrimPrefix(hop, "$")
+		fingerprint, nickname, found := strings.Cut(hop, "~")
+		if !found {
+			fingerprint, nickname, _ = strings.Cut(hop, "=")
+		}
+		relays = append(relays, Relay{Fingerprint: fingerprint, Nickname: nickname})
+	}
+
+	return relays
+}
+
+// ThroughExit returns the circuits whose last hop is the relay with the
+// given fingerprint, with or without a leading "$".
+func ThroughExit(circuits []Circuit, fingerprint string) []Circuit {
+	fingerprint = strings.TrimPrefix(fingerprint, "$")
+
+	matched := []Circuit{}
+	for _, circuit := range circuits {
+		if exit, ok := circuit.Exit(); ok && strings.EqualFold(exit.Fingerprint, fingerprint) {
+			matched = append(matched, circuit)
+		}
+	}
+	return matched
+}
+
+// CountBuilt returns the number of circuits in the BUILT state.
+func CountBuilt(circuits []Circuit) int {
+	built := 0
+	for _, circuit := range circuits {
+		if circuit.Status == "BUILT" {
+			built++
+		}
+	}
+	return built
+}
@@ -0,0 +1,242 @@
+package tor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCircuit(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantID      string
+		wantStatus  string
+		wantPath    []Relay
+		wantFlags   []string
+		wantPurpose string
+		wantCreated time.Time
+		wantReason  string
+		wantErr     bool
+	}{
+		{
+			name:       "Built general circuit",
+			line:       "12 BUILT $AAAA~guard,$BBBB~middle,$CCCC~exit BUILD_FLAGS=NEED_CAPACITY,NEED_UPTIME PURPOSE=GENERAL TIME_CREATED=2024-05-01T12:30:45.123456",
+			wantID:     "12",
+			wantStatus: "BUILT",
+			wantPath: []Relay{
+				{Fingerprint: "AAAA", Nickname: "guard"},
+				{Fingerprint: "BBBB", Nickname: "middle"},
+				{Fingerprint: "CCCC", Nickname: "exit"},
+			},
+			wantFlags:   []string{"NEED_CAPACITY", "NEED_UPTIME"},
+			wantPurpose: "GENERAL",
+			wantCreated: time.Date(2024, 5, 1, 12, 30, 45, 123456000, time.UTC),
+		},
+		{
+			name:       "Legacy equals LongName and bare fingerprint",
+			line:       "3 EXTENDED $AAAA=guard,$BBBB PURPOSE=GENERAL",
+			wantID:     "3",
+			wantStatus: "EXTENDED",
+			wantPath: []Relay{
+				{Fingerprint: "AAAA", Nickname: "guard"},
+				{Fingerprint: "BBBB"},
+			},
+			wantPurpose: "GENERAL",
+		},
+		{
+			name:        "Launched circuit without path",
+			line:        "7 LAUNCHED BUILD_FLAGS=NEED_CAPACITY PURPOSE=GENERAL",
+			wantID:      "7",
+			wantStatus:  "LAUNCHED",
+			wantPath:    []Relay{},
+			wantFlags:   []string{"NEED_CAPACITY"},
+			wantPurpose: "GENERAL",
+		},
+		{
+			name:       "Failed circuit with reason",
+			line:       "9 FAILED $AAAA~guard REASON=TIMEOUT",
+			wantID:     "9",
+			wantStatus: "FAILED",
+			wantPath:   []Relay{{Fingerprint: "AAAA", Nickname: "guard"}},
+			wantReason: "TIMEOUT",
+		},
+		{
+			name:    "Missing status",
+			line:    "12",
+			wantErr: true,
+		},
+		{
+			name:    "Bad timestamp",
+			line:    "1 BUILT TIME_CREATED=yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circuit, err := ParseCircuit(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if circuit.ID != tt.wantID {
+				t.Errorf("expected ID %q, got %q", tt.wantID, circuit.ID)
+			}
+			if circuit.Status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, circuit.Status)
+			}
+			if len(circuit.Path) != len(tt.wantPath) {
+				t.Fatalf("expected %d hops, got %d", len(tt.wantPath), len(circuit.Path))
+			}
+			for i, hop := range tt.wantPath {
+				if circuit.Path[i] != hop {
+					t.Errorf("expected hop %d to be %+v, got %+v", i, hop, circuit.Path[i])
+				}
+			}
+			if strings.Join(circuit.BuildFlags, ",") != strings.Join(tt.wantFlags, ",") {
+				t.Errorf("expected flags %v, got %v", tt.wantFlags, circuit.BuildFlags)
+			}
+			if circuit.Purpose != tt.wantPurpose {
+				t.Errorf("expected purpose %q, got %q", tt.wantPurpose, circuit.Purpose)
+			}
+			if !circuit.TimeCreated.Equal(tt.wantCreated) {
+				t.Errorf("expected time created %v, got %v", tt.wantCreated, circuit.TimeCreated)
+			}
+			if circuit.Reason != tt.wantReason {
+				t.Errorf("expected reason %q, got %q", tt.wantReason, circuit.Reason)
+			}
+		})
+	}
+}
+
+func TestParseCircuitStatus(t *testing.T) {
+	data := "1 BUILT $AAAA~a,$BBBB~b,$CCCC~c PURPOSE=GENERAL\n" +
+		"2 EXTENDED $AAAA~a PURPOSE=GENERAL\n" +
+		"\n" +
+		"3 BUILT $DDDD~d,$EEEE~e,$FFFF~f PURPOSE=CONFLUX_LINKED"
+
+	circuits, err := ParseCircuitStatus(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(circuits) != 3 {
+		t.Fatalf("expected 3 circuits, got %d", len(circuits))
+	}
+	if built := CountBuilt(circuits); built != 2 {
+		t.Errorf("expected 2 built circuits, got %d", built)
+	}
+
+	exit, ok := circuits[0].Exit()
+	if !ok || exit.Fingerprint != "CCCC" {
+		t.Errorf("expected exit CCCC, got %+v", exit)
+	}
+}
+
+func TestParseCircuitStatus_Empty(t *testing.T) {
+	circuits, err := ParseCircuitStatus("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if circuits == nil || len(circuits) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", circuits)
+	}
+}
+
+func TestCircuitExit_NoPath(t *testing.T) {
+	if _, ok := (Circuit{ID: "1", Status: "LAUNCHED"}).Exit(); ok {
+		t.Error("expected no exit for circuit without a path")
+	}
+}
+
+func TestParseCircuitEvent(t *testing.T) {
+	circuit, err := ParseCircuitEvent(Event{Type: EventCirc, Data: "5 FAILED $AAAA~guard REASON=DESTROYED"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if circuit.ID != "5" || circuit.Status != "FAILED" || circuit.Reason != "DESTROYED" {
+		t.Errorf("unexpected circuit: %+v", circuit)
+	}
+}
+
+func TestThroughExit(t *testing.T) {
+	exit := strings.Repeat("B", 40)
+	circuits := []Circuit{
+		{ID: "1", Path: []Relay{{Fingerprint: "AAAA"}, {Fingerprint: exit}}},
+		{ID: "2", Path: []Relay{{Fingerprint: exit}, {Fingerprint: "CCCC"}}},
+		{ID: "3"},
+		{ID: "4", Path: []Relay{{Fingerprint: exit, Nickname: "exit"}}},
+	}
+
+	matched := ThroughExit(circuits, "$"+strings.ToLower(exit))
+	if len(matched) != 2 || matched[0].ID != "1" || matched[1].ID != "4" {
+		t.Errorf("expected circuits 1 and 4, got %+v", matched)
+	}
+}
+
+func TestCloseCircuit_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "CLOSECIRCUIT 99" {
+			return "552 Unknown circuit \"99\"\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.CloseCircuit("5", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.CloseCircuit("6", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.CloseCircuit("99", false); !errors.Is(err, ErrUnknownCircuit) {
+		t.Errorf("expected ErrUnknownCircuit, got %v", err)
+	}
+	if err := client.CloseCircuit("5 IfUnused", false); err == nil {
+		t.Error("expected an error for an invalid circuit id")
+	}
+
+	commands := fake.Commands()
+	if got := commands[len(commands)-2]; got != "CLOSECIRCUIT 6 IfUnused" {
+		t.Errorf("expected IfUnused to be sent, got %q", got)
+	}
+}
+
+func TestExtendCircuit_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "EXTENDCIRCUIT ") {
+			return "250 EXTENDED 12\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	guard, exit := "$"+strings.Repeat("A", 40), "$"+strings.Repeat("B", 40)+"~exit"
+	id, err := client.ExtendCircuit("0", []string{guard, "middle", exit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "12" {
+		t.Errorf("expected circuit 12, got %q", id)
+	}
+	commands := fake.Commands()
+	if expected := "EXTENDCIRCUIT 0 " + guard + ",middle," + exit; commands[len(commands)-1] != expected {
+		t.Errorf("expected %q, got %q", expected, commands[len(commands)-1])
+	}
+
+	if _, err := client.ExtendCircuit("0", []string{"bad relay"}); err == nil {
+		t.Error("expected an error for an invalid relay")
+	}
+}
@@ -0,0 +1,255 @@
+package tor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GetConf returns the current values of the given configuration options.
+// Options that are unset (at their default) map to an empty slice; options
+// that may repeat, such as Bridge, map to every configured value.
+func (c *Client) GetConf(keys ...string) (map[string][]string, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(fmt.Sprintf("GETCONF %s", strings.Join(keys, " ")))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code != 250 {
+		return nil, fmt.Errorf("getconf failed: %s", resp.message())
+	}
+
+	return parseConfReply(resp), nil
+}
+
+func parseConfReply(resp *reply) map[string][]string {
+	result := make(map[string][]string)
+
+	for _, line := range resp.lines {
+		if line.text == "OK" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line.text, "=")
+		if _, ok := result[key]; !ok {
+			result[key] = []string{}
+		}
+		if found {
+			result[key] = append(result[key], unquote(value))
+		}
+	}
+
+	return result
+}
+
+// SetConf changes configuration options at runtime. Keys are sent in sorted
+// order so multi-option updates are applied deterministically.
+func (c *Client) SetConf(settings map[string]string) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", key, quote(settings[key])))
+	}
+
+	return c.confCommand("SETCONF " + strings.Join(args, " "))
+}
+
+// ResetConf restores the given options to their defaults.
+func (c *Client) ResetConf(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.confCommand("RESETCONF " + strings.Join(keys, " "))
+}
+
+// SaveConf writes the running configuration back to the torrc file.
+func (c *Client) SaveConf() error {
+	return c.confCommand("SAVECONF")
+}
+
+func (c *Client) confCommand(cmd string) error {
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(cmd)
+	if err != nil {
+		return err
+	}
+
+	if resp.code != 250 {
+		verb := strings.ToLower(strings.Fields(cmd)[0])
+		return fmt.Errorf("%s failed: %s", verb, resp.message())
+	}
+
+	return nil
+}
+
+// ExitPolicy is the subset of node selection options that decides which
+// countries Tor may use as exits. Countries are ISO 3166-1 alpha-2 codes in
+// lower case without braces; "??" selects relays with no known country.
+type ExitPolicy struct {
+	ExitNodes        []string `json:"exit_nodes"`
+	ExcludeExitNodes []string `json:"exclude_exit_nodes"`
+	StrictNodes      bool     `json:"strict_nodes"`
+}
+
+// GetExitPolicy reads ExitNodes, ExcludeExitNodes and StrictNodes. Router
+// set entries that are not country codes (such as fingerprints configured in
+// torrc) are returned verbatim.
+func (c *Client) GetExitPolicy() (*ExitPolicy, error) {
+	conf, err := c.GetConf("ExitNodes", "ExcludeExitNodes", "StrictNodes")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExitPolicy{
+		ExitNodes:        parseRouterSet(conf["ExitNodes"]),
+		ExcludeExitNodes: parseRouterSet(conf["ExcludeExitNodes"]),
+		StrictNodes:      slices.Contains(conf["StrictNodes"], "1"),
+	}, nil
+}
+
+// SetExitPolicy validates the policy and applies it with a single SETCONF so
+// Tor never runs with a half-applied selection.
+func (c *Client) SetExitPolicy(policy ExitPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	strict := "0"
+	if policy.StrictNodes {
+		strict = "1"
+	}
+
+	return c.SetConf(map[string]string{
+		"ExitNodes":        formatCountries(policy.ExitNodes),
+		"ExcludeExitNodes": formatCountries(policy.ExcludeExitNodes),
+		"StrictNodes":      strict,
+	})
+}
+
+// ExitPolicyUpdate is a partial change to the exit policy; nil fields are
+// left as Tor has them.
+type ExitPolicyUpdate struct {
+	ExitNodes        *[]string
+	ExcludeExitNodes *[]string
+	StrictNodes      *bool
+}
+
+// UpdateExitPolicy validates the supplied fields and applies them with a
+// single SETCONF. Options that are not supplied keep their current value,
+// including router set entries that are not country codes.
+func (c *Client) UpdateExitPolicy(update ExitPolicyUpdate) error {
+	if err := update.Validate(); err != nil {
+		return err
+	}
+
+	settings := map[string]string{}
+	if update.ExitNodes != nil {
+		settings["ExitNodes"] = formatCountries(*update.ExitNodes)
+	}
+	if update.ExcludeExitNodes != nil {
+		settings["ExcludeExitNodes"] = formatCountries(*update.ExcludeExitNodes)
+	}
+	if update.StrictNodes != nil {
+		settings["StrictNodes"] = "0"
+		if *update.StrictNodes {
+			settings["StrictNodes"] = "1"
+		}
+	}
+	return c.SetConf(settings)
+}
+
+// Validate checks the supplied country lists.
+func (u ExitPolicyUpdate) Validate() error {
+	var policy ExitPolicy
+	if u.ExitNodes != nil {
+		policy.ExitNodes = *u.ExitNodes
+	}
+	if u.ExcludeExitNodes != nil {
+		policy.ExcludeExitNodes = *u.ExcludeExitNodes
+	}
+	return policy.Validate()
+}
+
+// Validate checks that every entry is a country code. An empty ExitNodes
+// list means any exit; StrictNodes without ExitNodes is allowed because it
+// still makes ExcludeExitNodes mandatory.
+func (p ExitPolicy) Validate() error {
+	for _, code := range p.ExitNodes {
+		if !ValidCountryCode(code) {
+			return fmt.Errorf("invalid exit country code %q", code)
+		}
+	}
+	for _, code := range p.ExcludeExitNodes {
+		if !ValidCountryCode(code) {
+			return fmt.Errorf("invalid excluded exit country code %q", code)
+		}
+	}
+	return nil
+}
+
+// ValidCountryCode reports whether code is a two letter country code, or
+// "??" for relays Tor cannot geolocate.
+func ValidCountryCode(code string) bool {
+	if code == "??" {
+		return true
+	}
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRouterSet splits a router set such as "{us},{ca}" into its entries,
+// stripping the braces from country codes.
+func parseRouterSet(values []string) []string {
+	entries := []string{}
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if strings.HasPrefix(entry, "{") && strings.HasSuffix(entry, "}") {
+				entry = strings.ToLower(entry[1 : len(entry)-1])
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func formatCountries(codes []string) string {
+	entries := make([]string, 0, len(codes))
+	for _, code := range codes {
+		entries = append(entries, "{"+code+"}")
+	}
+	return strings.Join(entries, ",")
+}
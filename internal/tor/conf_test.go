@@ -0,0 +1,205 @@
+package tor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetConf_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes Bridge" {
+			return "250-ExitNodes={us},{ca}\r\n" +
+				"250-ExcludeExitNodes\r\n" +
+				"250-StrictNodes=1\r\n" +
+				"250-Bridge=obfs4 192.0.2.1:443 AAAA cert=x iat-mode=0\r\n" +
+				"250 Bridge=obfs4 192.0.2.2:443 BBBB cert=y iat-mode=0\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	conf, err := client.GetConf("ExitNodes", "ExcludeExitNodes", "StrictNodes", "Bridge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(conf["ExitNodes"], "|") != "{us},{ca}" {
+		t.Errorf("unexpected ExitNodes: %v", conf["ExitNodes"])
+	}
+	if values, ok := conf["ExcludeExitNodes"]; !ok || len(values) != 0 {
+		t.Errorf("expected unset ExcludeExitNodes to be present and empty, got %v (present=%v)", values, ok)
+	}
+	if strings.Join(conf["StrictNodes"], "|") != "1" {
+		t.Errorf("unexpected StrictNodes: %v", conf["StrictNodes"])
+	}
+	if len(conf["Bridge"]) != 2 {
+		t.Errorf("expected 2 Bridge values, got %v", conf["Bridge"])
+	}
+}
+
+func TestSetResetSaveConf_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.SetConf(map[string]string{"StrictNodes": "1", "ExitNodes": "{de},{nl}"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.ResetConf("ExcludeExitNodes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.SaveConf(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	expected := []string{
+		`SETCONF ExitNodes="{de},{nl}" StrictNodes="1"`,
+		"RESETCONF ExcludeExitNodes",
+		"SAVECONF",
+	}
+	got := commands[len(commands)-len(expected):]
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected commands %v, got %v", expected, got)
+	}
+}
+
+func TestSetConf_Rejected(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "SETCONF") {
+			return "552 Unrecognized option: Unknown option 'Bogus'.  Failing.\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	err := client.SetConf(map[string]string{"Bogus": "1"})
+	if err == nil {
+		t.Fatal("expected SETCONF rejection to return an error")
+	}
+	if !strings.HasPrefix(err.Error(), "setconf failed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetConf_Empty(t *testing.T) {
+	client := NewClient("127.0.0.1:1", "")
+
+	if err := client.SetConf(nil); err != nil {
+		t.Errorf("expected empty SETCONF to be a no-op, got %v", err)
+	}
+	if err := client.ResetConf(); err != nil {
+		t.Errorf("expected empty RESETCONF to be a no-op, got %v", err)
+	}
+}
+
+func TestGetExitPolicy_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETCONF ExitNodes ExcludeExitNodes StrictNodes" {
+			return "250-ExitNodes={US},{ca},$AAAA\r\n" +
+				"250-ExcludeExitNodes\r\n" +
+				"250 StrictNodes=1\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	policy, err := client.GetExitPolicy()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(policy.ExitNodes, "|") != "us|ca|$AAAA" {
+		t.Errorf("unexpected ExitNodes: %v", policy.ExitNodes)
+	}
+	if policy.ExcludeExitNodes == nil || len(policy.ExcludeExitNodes) != 0 {
+		t.Errorf("expected empty ExcludeExitNodes, got %v", policy.ExcludeExitNodes)
+	}
+	if !policy.StrictNodes {
+		t.Error("expected StrictNodes to be true")
+	}
+}
+
+func TestSetExitPolicy_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	err := client.SetExitPolicy(ExitPolicy{
+		ExitNodes:        []string{"de", "nl"},
+		ExcludeExitNodes: []string{"??"},
+		StrictNodes:      true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	expected := `SETCONF ExcludeExitNodes="{??}" ExitNodes="{de},{nl}" StrictNodes="1"`
+	if got := commands[len(commands)-1]; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestExitPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ExitPolicy
+		wantErr bool
+	}{
+		{name: "Empty", policy: ExitPolicy{}},
+		{name: "Countries", policy: ExitPolicy{ExitNodes: []string{"us", "ca"}, ExcludeExitNodes: []string{"??"}}},
+		{name: "Upper case", policy: ExitPolicy{ExitNodes: []string{"US"}}, wantErr: true},
+		{name: "Braces", policy: ExitPolicy{ExitNodes: []string{"{us}"}}, wantErr: true},
+		{name: "Fingerprint", policy: ExitPolicy{ExcludeExitNodes: []string{"$AAAA"}}, wantErr: true},
+		{name: "Three letters", policy: ExitPolicy{ExitNodes: []string{"usa"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetExitPolicy_Invalid(t *testing.T) {
+	client := NewClient("127.0.0.1:1", "")
+
+	if err := client.SetExitPolicy(ExitPolicy{ExitNodes: []string{"xyz"}}); err == nil {
+		t.Error("expected invalid country code to be rejected before connecting")
+	}
+}
+
+func TestUpdateExitPolicy_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	exclude := []string{"ru"}
+	if err := client.UpdateExitPolicy(ExitPolicyUpdate{ExcludeExitNodes: &exclude}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	expected := `SETCONF ExcludeExitNodes="{ru}"`
+	if got := commands[len(commands)-1]; got != expected {
+		t.Errorf("expected only the supplied option to be set, got %q", got)
+	}
+
+	invalid := []string{"$AAAA"}
+	if err := client.UpdateExitPolicy(ExitPolicyUpdate{ExitNodes: &invalid}); err == nil {
+		t.Error("expected a supplied fingerprint to be rejected")
+	}
+}
@@ -3,6 +3,7 @@ package tor
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
@@ -10,11 +11,45 @@ import (
 	"time"
 )
 
+// commandTimeout bounds how long a command waits for its synchronous reply.
+const commandTimeout = 10 * time.Second
+
 type Client struct {
-	address  string
-	password string
-	conn     net.Conn
-	mu       sync.Mutex
+	address       string
+	password      string
+	authMethod    AuthMethod
+	cookieFile    string
+	dataDirectory string
+	conn          *controlConn
+	mu            sync.Mutex // Serializes commands and protects conn
+
+	subs  map[*Subscription]struct{}
+	subMu sync.Mutex // Protects subs
+}
+
+// controlConn is a single control port connection. A reader goroutine owns the
+// read side and splits asynchronous 650 events from synchronous replies.
+type controlConn struct {
+	net.Conn
+	replies chan *reply
+	done    chan struct{} // Closed when the reader goroutine exits
+	err     error         // Read error, valid once done is closed
+	closed  chan struct{} // Closed by Close to release a blocked reader
+	once    sync.Once
+}
+
+func newControlConn(conn net.Conn) *controlConn {
+	return &controlConn{
+		Conn:    conn,
+		replies: make(chan *reply, 1),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (cc *controlConn) Close() error {
+	cc.once.Do(func() { close(cc.closed) })
+	return cc.Conn.Close()
 }
 
 type Status struct {
@@ -30,10 +65,40 @@ type TrafficStats struct {
 	BytesWritten int64
 }
 
+// reply is a complete control port reply, possibly spanning several lines.
+type reply struct {
+	code  int
+	lines []replyLine
+}
+
+type replyLine struct {
+	text string   // Text following the status code and separator
+	data []string // Data block for "NNN+" lines, dot-unstuffed
+}
+
+// message returns the text of the final reply line.
+func (r *reply) message() string {
+	if len(r.lines) == 0 {
+		return ""
+	}
+	return r.lines[len(r.lines)-1].text
+}
+
+// NewClient creates a client that auto-detects the authentication method,
+// using password if Tor requires HASHEDPASSWORD.
 func NewClient(address, password string) *Client {
+	return NewClientWithAuth(address, Auth{Method: AuthAuto, Password: password})
+}
+
+// NewClientWithAuth creates a client with explicit authentication settings.
+func NewClientWithAuth(address string, auth Auth) *Client {
 	return &Client{
-		address:  address,
-		password: password,
+		address:       address,
+		password:      auth.Password,
+		authMethod:    auth.Method,
+		cookieFile:    auth.CookieFile,
+		dataDirectory: auth.DataDirectory,
+		subs:          make(map[*Subscription]struct{}),
 	}
 }
 
@@ -42,30 +107,60 @@ func (c *Client) Connect() error {
 	defer c.mu.Unlock()
 
 	if c.conn != nil {
-		return nil
+		select {
+		case <-c.conn.done:
+			// Reader exited (Tor closed the connection); drop it and redial
+			_ = c.conn.Close()
+			c.conn = nil
+		default:
+			return nil
+		}
 	}
 
-	conn, err := net.DialTimeout("tcp", c.address, 5*time.Second)
+	network, address := controlNetwork(c.address)
+	conn, err := net.DialTimeout(network, address, 5*time.Second)
 	if err != nil {
 		return fmt.Errorf("failed to connect to tor control port: %w", err)
 	}
 
-	c.conn = conn
+	c.conn = newControlConn(conn)
+	go c.readLoop(c.conn)
 
-	if c.password != "" {
-		if err := c.authenticate(); err != nil {
-			closeErr := c.conn.Close()
-			c.conn = nil
-			if closeErr != nil {
-				return fmt.Errorf("authentication failed: %w (close error: %v)", err, closeErr)
-			}
-			return err
+	if err := c.authenticate(); err != nil {
+		return c.abort(err)
+	}
+
+	if c.hasSubscriptions() {
+		if err := c.setEvents(); err != nil {
+			return c.abort(err)
 		}
 	}
 
 	return nil
 }
 
+// controlNetwork maps a control address to a dial network. Addresses of the
+// form "unix:/path" select a ControlSocket; anything else is host:port TCP.
+func controlNetwork(address string) (string, string) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", address
+}
+
+// abort closes a half-initialized connection and returns err. Callers must hold c.mu.
+func (c *Client) abort(err error) error {
+	if c.conn == nil {
+		return err
+	}
+	closeErr := c.conn.Close()
+	c.conn = nil
+	if closeErr != nil {
+		return fmt.Errorf("%w (close error: %v)", err, closeErr)
+	}
+	return err
+}
+
 func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -78,90 +173,143 @@ func (c *Client) Close() error {
 	return nil
 }
 
-func (c *Client) authenticate() error {
-	cmd := fmt.Sprintf("AUTHENTICATE \"%s\"\r\n", c.password)
-	if _, err := c.conn.Write([]byte(cmd)); err != nil {
-		return fmt.Errorf("failed to send authenticate command: %w", err)
-	}
+// readLoop reads replies until the connection fails, dispatching 650 events to
+// subscribers and handing everything else to the command waiting in roundTrip.
+func (c *Client) readLoop(conn *controlConn) {
+	defer close(conn.done)
 
-	reader := bufio.NewReader(c.conn)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return fmt.Errorf("failed to read authenticate response: %w", err)
-	}
+	reader := bufio.NewReader(conn)
+	for {
+		resp, err := readReply(reader)
+		if err != nil {
+			conn.err = err
+			return
+		}
 
-	if !strings.HasPrefix(response, "250") {
-		return fmt.Errorf("authentication failed: %s", strings.TrimSpace(response))
-	}
+		if resp.code == 650 {
+			c.dispatch(newEvent(resp))
+			continue
+		}
 
-	return nil
+		select {
+		case conn.replies <- resp:
+		case <-conn.closed:
+			conn.err = net.ErrClosed
+			return
+		}
+	}
 }
 
-func (c *Client) GetInfo(keys ...string) (map[string]string, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+// roundTrip sends a single command and waits for its synchronous reply.
+// Callers must hold c.mu. On transport errors the connection is closed.
+func (c *Client) roundTrip(cmd string) (*reply, error) {
 	if c.conn == nil {
 		return nil, fmt.Errorf("not connected")
 	}
 
-	cmd := fmt.Sprintf("GETINFO %s\r\n", strings.Join(keys, " "))
-	if _, err := c.conn.Write([]byte(cmd)); err != nil {
-		closeErr := c.conn.Close()
-		c.conn = nil
-		if closeErr != nil {
-			return nil, fmt.Errorf("failed to send getinfo command: %w (close error: %v)", err, closeErr)
-		}
-		return nil, fmt.Errorf("failed to send getinfo command: %w", err)
+	verb := strings.ToLower(strings.Fields(cmd)[0])
+
+	if _, err := io.WriteString(c.conn, cmd+"\r\n"); err != nil {
+		return nil, c.abort(fmt.Errorf("failed to send %s command: %w", verb, err))
 	}
 
-	reader := bufio.NewReader(c.conn)
-	result := make(map[string]string)
+	timer := time.NewTimer(commandTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-c.conn.replies:
+		return resp, nil
+	case <-c.conn.done:
+		return nil, c.abort(fmt.Errorf("failed to read %s response: %w", verb, c.conn.err))
+	case <-timer.C:
+		return nil, c.abort(fmt.Errorf("failed to read %s response: timed out after %v", verb, commandTimeout))
+	}
+}
+
+// readReply reads one complete reply as described in section 2.3 of the Tor
+// control protocol specification.
+func readReply(reader *bufio.Reader) (*reply, error) {
+	resp := &reply{}
 
 	for {
-		line, err := reader.ReadString('\n')
+		line, err := readLine(reader)
 		if err != nil {
-			closeErr := c.conn.Close()
-			c.conn = nil
-			if closeErr != nil {
-				return nil, fmt.Errorf("failed to read getinfo response: %w (close error: %v)", err, closeErr)
-			}
-			return nil, fmt.Errorf("failed to read getinfo response: %w", err)
+			return nil, err
 		}
 
-		line = strings.TrimSpace(line)
+		if len(line) < 4 {
+			return nil, fmt.Errorf("malformed reply line: %q", line)
+		}
 
-		if strings.HasPrefix(line, "250 OK") {
-			break
+		code, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return nil, fmt.Errorf("malformed reply status: %q", line)
 		}
 
-		if strings.HasPrefix(line, "250-") {
-			line = strings.TrimPrefix(line, "250-")
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				result[parts[0]] = parts[1]
-			}
-		} else if strings.HasPrefix(line, "250+") {
-			key := strings.TrimSuffix(strings.TrimPrefix(line, "250+"), "=")
-			var value strings.Builder
+		rl := replyLine{text: line[4:]}
+
+		switch line[3] {
+		case ' ':
+			resp.code = code
+			resp.lines = append(resp.lines, rl)
+			return resp, nil
+		case '-':
+			resp.lines = append(resp.lines, rl)
+		case '+':
 			for {
-				dataLine, err := reader.ReadString('\n')
+				dataLine, err := readLine(reader)
 				if err != nil {
-					closeErr := c.conn.Close()
-					c.conn = nil
-					if closeErr != nil {
-						return nil, fmt.Errorf("failed to read multiline data: %w (close error: %v)", err, closeErr)
-					}
 					return nil, fmt.Errorf("failed to read multiline data: %w", err)
 				}
-				dataLine = strings.TrimSpace(dataLine)
 				if dataLine == "." {
 					break
 				}
-				value.WriteString(dataLine)
+				rl.data = append(rl.data, strings.TrimPrefix(dataLine, "."))
+			}
+			resp.lines = append(resp.lines, rl)
+		default:
+			return nil, fmt.Errorf("malformed reply line: %q", line)
+		}
+	}
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *Client) GetInfo(keys ...string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(fmt.Sprintf("GETINFO %s", strings.Join(keys, " ")))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code != 250 {
+		return nil, fmt.Errorf("getinfo failed: %s", resp.message())
+	}
+
+	result := make(map[string]string)
+	for _, line := range resp.lines {
+		parts := strings.SplitN(line.text, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if line.data != nil {
+			var value strings.Builder
+			for _, dataLine := range line.data {
+				value.WriteString(strings.TrimSpace(dataLine))
 				value.WriteString("\n")
 			}
-			result[key] = strings.TrimSpace(value.String())
+			result[parts[0]] = strings.TrimSpace(value.String())
+		} else {
+			result[parts[0]] = parts[1]
 		}
 	}
 
@@ -179,6 +327,7 @@ func (c *Client) GetStatus() (*Status, error) {
 		"status/circuit-established",
 		"traffic/read",
 		"traffic/written",
+		"circuit-status",
 	)
 	if err != nil {
 		return nil, err
@@ -217,6 +366,10 @@ func (c *Client) GetStatus() (*Status, error) {
 		}
 	}
 
+	if circuits, err := ParseCircuitStatus(info["circuit-status"]); err == nil {
+		status.NumCircuits = CountBuilt(circuits)
+	}
+
 	return status, nil
 }
 
@@ -229,36 +382,42 @@ func (c *Client) IsReady() bool {
 }
 
 func (c *Client) Signal(sig string) error {
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.conn == nil {
-		return fmt.Errorf("not connected")
+	resp, err := c.roundTrip(fmt.Sprintf("SIGNAL %s", sig))
+	if err != nil {
+		return err
 	}
 
-	cmd := fmt.Sprintf("SIGNAL %s\r\n", sig)
-	if _, err := c.conn.Write([]byte(cmd)); err != nil {
-		closeErr := c.conn.Close()
-		c.conn = nil
-		if closeErr != nil {
-			return fmt.Errorf("failed to send signal: %w (close error: %v)", err, closeErr)
-		}
-		return fmt.Errorf("failed to send signal: %w", err)
+	if resp.code != 250 {
+		return fmt.Errorf("signal failed: %s", resp.message())
 	}
 
-	reader := bufio.NewReader(c.conn)
-	response, err := reader.ReadString('\n')
+	return nil
+}
+
+// TakeOwnership makes Tor exit when this control connection closes, so a
+// Tor process launched by the health server cannot outlive it.
+func (c *Client) TakeOwnership() error {
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip("TAKEOWNERSHIP")
 	if err != nil {
-		closeErr := c.conn.Close()
-		c.conn = nil
-		if closeErr != nil {
-			return fmt.Errorf("failed to read signal response: %w (close error: %v)", err, closeErr)
-		}
-		return fmt.Errorf("failed to read signal response: %w", err)
+		return err
 	}
 
-	if !strings.HasPrefix(response, "250") {
-		return fmt.Errorf("signal failed: %s", strings.TrimSpace(response))
+	if resp.code != 250 {
+		return fmt.Errorf("takeownership failed: %s", resp.message())
 	}
 
 	return nil
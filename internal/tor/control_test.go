@@ -1,8 +1,12 @@
 package tor
 
 import (
+	"bufio"
+	"net"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -259,3 +263,417 @@ if err == nil {
 t.Error("expected error when sending signal without connection")
 }
 }
+
+func TestTakeOwnership(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+	client := NewClientWithAuth(fake.Addr(), Auth{Method: AuthNull})
+	defer func() { _ = client.Close() }()
+
+	if err := client.TakeOwnership(); err != nil {
+		t.Fatalf("TakeOwnership() error = %v", err)
+	}
+
+	commands := fake.Commands()
+	if commands[len(commands)-1] != "TAKEOWNERSHIP" {
+		t.Errorf("expected TAKEOWNERSHIP to be sent, got %v", commands)
+	}
+}
+
+// fakeTor is a minimal stand-in for Tor's control port. Each command line is
+// passed to handle, whose return value is written back verbatim.
+type fakeTor struct {
+	t        *testing.T
+	listener net.Listener
+	handle   func(cmd string) string
+
+	mu       sync.Mutex
+	conn     net.Conn
+	commands []string
+}
+
+func newFakeTor(t *testing.T, handle func(cmd string) string) *fakeTor {
+	t.Helper()
+	return newFakeTorOn(t, "tcp", "127.0.0.1:0", handle)
+}
+
+// newFakeTorUnix listens on a unix socket, like Tor's ControlSocket.
+func newFakeTorUnix(t *testing.T, handle func(cmd string) string) *fakeTor {
+	t.Helper()
+	return newFakeTorOn(t, "unix", filepath.Join(t.TempDir(), "control.sock"), handle)
+}
+
+func newFakeTorOn(t *testing.T, network, address string, handle func(cmd string) string) *fakeTor {
+	t.Helper()
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	f := &fakeTor{t: t, listener: listener, handle: handle}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		f.mu.Lock()
+		if f.conn != nil {
+			_ = f.conn.Close()
+		}
+		f.mu.Unlock()
+	})
+
+	go f.serve()
+	return f
+}
+
+// Addr returns the address to pass to NewClient, including the "unix:"
+// prefix for socket listeners.
+func (f *fakeTor) Addr() string {
+	if f.listener.Addr().Network() == "unix" {
+		return "unix:" + f.listener.Addr().String()
+	}
+	return f.listener.Addr().String()
+}
+
+func (f *fakeTor) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.conn = conn
+		f.mu.Unlock()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+
+			f.mu.Lock()
+			f.commands = append(f.commands, cmd)
+			f.mu.Unlock()
+
+			f.write(f.handle(cmd))
+		}
+	}
+}
+
+// Emit writes an unsolicited reply, such as a 650 event, to the client.
+func (f *fakeTor) Emit(raw string) {
+	f.write(raw)
+}
+
+func (f *fakeTor) write(raw string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn == nil || raw == "" {
+		return
+	}
+	if _, err := f.conn.Write([]byte(raw)); err != nil {
+		f.t.Logf("fake tor write failed: %v", err)
+	}
+}
+
+func (f *fakeTor) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+// okHandler offers NULL authentication and answers every other command with 250 OK.
+func okHandler(cmd string) string {
+	if strings.HasPrefix(cmd, "PROTOCOLINFO") {
+		return protocolInfoReply("NULL", "")
+	}
+	return "250 OK\r\n"
+}
+
+func protocolInfoReply(methods, cookieFile string) string {
+	auth := "250-AUTH METHODS=" + methods
+	if cookieFile != "" {
+		auth += " COOKIEFILE=" + quote(cookieFile)
+	}
+	return "250-PROTOCOLINFO 1\r\n" + auth + "\r\n250-VERSION Tor=\"0.4.8.10\"\r\n250 OK\r\n"
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantCode  int
+		wantLines int
+		wantData  []string
+		wantErr   bool
+	}{
+		{
+			name:      "Single line",
+			input:     "250 OK\r\n",
+			wantCode:  250,
+			wantLines: 1,
+		},
+		{
+			name:      "Mid reply lines",
+			input:     "250-version=0.4.8.10\r\n250-traffic/read=10\r\n250 OK\r\n",
+			wantCode:  250,
+			wantLines: 3,
+		},
+		{
+			name:      "Data block with dot-stuffing",
+			input:     "250+circuit-status=\r\n1 BUILT\r\n..hidden\r\n.\r\n250 OK\r\n",
+			wantCode:  250,
+			wantLines: 2,
+			wantData:  []string{"1 BUILT", ".hidden"},
+		},
+		{
+			name:      "Error reply",
+			input:     "552 Unrecognized key\r\n",
+			wantCode:  552,
+			wantLines: 1,
+		},
+		{
+			name:    "Malformed line",
+			input:   "25\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "Non-numeric status",
+			input:   "abc OK\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "Truncated reply",
+			input:   "250-version=1\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.code != tt.wantCode {
+				t.Errorf("expected code %d, got %d", tt.wantCode, resp.code)
+			}
+			if len(resp.lines) != tt.wantLines {
+				t.Errorf("expected %d lines, got %d", tt.wantLines, len(resp.lines))
+			}
+			if tt.wantData != nil {
+				data := resp.lines[0].data
+				if strings.Join(data, "|") != strings.Join(tt.wantData, "|") {
+					t.Errorf("expected data %v, got %v", tt.wantData, data)
+				}
+			}
+		})
+	}
+}
+
+func TestGetStatus_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		switch {
+		case strings.HasPrefix(cmd, "PROTOCOLINFO"):
+			return protocolInfoReply("HASHEDPASSWORD", "")
+		case strings.HasPrefix(cmd, "AUTHENTICATE"):
+			return "250 OK\r\n"
+		case strings.HasPrefix(cmd, "GETINFO"):
+			return "250-version=0.4.8.10\r\n" +
+				"250-status/bootstrap-phase=NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY=\"Done\"\r\n" +
+				"250-status/circuit-established=1\r\n" +
+				"250-traffic/read=1024\r\n" +
+				"250-traffic/written=2048\r\n" +
+				"250+circuit-status=\r\n" +
+				"1 BUILT $AAAA~a,$BBBB~b,$CCCC~c PURPOSE=GENERAL\r\n" +
+				"2 EXTENDED $AAAA~a PURPOSE=GENERAL\r\n" +
+				"3 BUILT $DDDD~d,$EEEE~e,$FFFF~f PURPOSE=GENERAL\r\n" +
+				".\r\n" +
+				"250 OK\r\n"
+		}
+		return "510 Unrecognized command\r\n"
+	})
+
+	client := NewClient(fake.Addr(), "secret")
+	defer func() { _ = client.Close() }()
+
+	status, err := client.GetStatus()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status.Version != "0.4.8.10" {
+		t.Errorf("expected version '0.4.8.10', got '%s'", status.Version)
+	}
+	if status.BootstrapPhase != 100 {
+		t.Errorf("expected bootstrap 100, got %d", status.BootstrapPhase)
+	}
+	if !status.CircuitEstablished {
+		t.Error("expected circuit to be established")
+	}
+	if status.Traffic.BytesRead != 1024 || status.Traffic.BytesWritten != 2048 {
+		t.Errorf("unexpected traffic stats: %+v", status.Traffic)
+	}
+	if status.NumCircuits != 2 {
+		t.Errorf("expected NumCircuits to count 2 BUILT circuits, got %d", status.NumCircuits)
+	}
+
+	commands := fake.Commands()
+	if len(commands) < 2 || commands[1] != "AUTHENTICATE \"secret\"" {
+		t.Errorf("expected password AUTHENTICATE after PROTOCOLINFO, got %v", commands)
+	}
+}
+
+func TestGetInfo_MultilineValue(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO circuit-status" {
+			return "250+circuit-status=\r\n1 BUILT $AAAA~relay1\r\n2 EXTENDED $BBBB~relay2\r\n.\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := client.GetInfo("circuit-status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1 BUILT $AAAA~relay1\n2 EXTENDED $BBBB~relay2"
+	if info["circuit-status"] != expected {
+		t.Errorf("expected %q, got %q", expected, info["circuit-status"])
+	}
+}
+
+func TestAuthenticate_Rejected(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "PROTOCOLINFO") {
+			return protocolInfoReply("HASHEDPASSWORD", "")
+		}
+		return "515 Authentication failed: Password did not match\r\n"
+	})
+
+	client := NewClient(fake.Addr(), "wrong")
+
+	err := client.Connect()
+	if err == nil {
+		t.Fatal("expected authentication error")
+	}
+	if !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("expected authentication failure, got %v", err)
+	}
+	if client.conn != nil {
+		t.Error("expected connection to be dropped after failed authentication")
+	}
+}
+
+func TestSignal_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "SIGNAL") && cmd != "SIGNAL NEWNYM" {
+			return "552 Unrecognized signal\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Signal("NEWNYM"); err != nil {
+		t.Errorf("expected NEWNYM to succeed, got %v", err)
+	}
+
+	if err := client.Signal("BOGUS"); err == nil {
+		t.Error("expected unrecognized signal to fail")
+	}
+}
+
+func TestConnect_ReconnectsAfterDrop(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Simulate Tor dropping the control connection
+	fake.mu.Lock()
+	_ = fake.conn.Close()
+	fake.mu.Unlock()
+
+	client.mu.Lock()
+	done := client.conn.done
+	client.mu.Unlock()
+	<-done
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("expected reconnect to succeed, got %v", err)
+	}
+	if err := client.Signal("NEWNYM"); err != nil {
+		t.Errorf("expected signal on new connection to succeed, got %v", err)
+	}
+}
+
+func TestControlNetwork(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+	}{
+		{address: "127.0.0.1:9051", wantNetwork: "tcp", wantAddress: "127.0.0.1:9051"},
+		{address: "localhost:9051", wantNetwork: "tcp", wantAddress: "localhost:9051"},
+		{address: "unix:/var/lib/tor/control.sock", wantNetwork: "unix", wantAddress: "/var/lib/tor/control.sock"},
+		{address: "unix:relative.sock", wantNetwork: "unix", wantAddress: "relative.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			network, address := controlNetwork(tt.address)
+			if network != tt.wantNetwork || address != tt.wantAddress {
+				t.Errorf("expected %s %s, got %s %s", tt.wantNetwork, tt.wantAddress, network, address)
+			}
+		})
+	}
+}
+
+func TestConnect_UnixSocket(t *testing.T) {
+	fake := newFakeTorUnix(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("expected unix socket connect to succeed, got %v", err)
+	}
+
+	if err := client.Signal("NEWNYM"); err != nil {
+		t.Errorf("expected signal over unix socket to succeed, got %v", err)
+	}
+}
+
+func TestConnect_MissingUnixSocket(t *testing.T) {
+	client := NewClient("unix:"+filepath.Join(t.TempDir(), "missing.sock"), "")
+
+	if err := client.Connect(); err == nil {
+		_ = client.Close()
+		t.Error("expected error when control socket does not exist")
+	}
+}
@@ -0,0 +1,331 @@
+package tor
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is an asynchronous event name accepted by SETEVENTS.
+type EventType string
+
+const (
+	EventStatusClient EventType = "STATUS_CLIENT"
+	EventCirc         EventType = "CIRC"
+	EventStream       EventType = "STREAM"
+	EventBW           EventType = "BW"
+	EventNotice       EventType = "NOTICE"
+	EventWarn         EventType = "WARN"
+	EventErr          EventType = "ERR"
+	EventHSDesc       EventType = "HS_DESC"
+)
+
+// subscriptionBuffer is the number of events queued per subscriber before
+// further events are dropped.
+const subscriptionBuffer = 64
+
+// Event is an asynchronous "650" reply from Tor.
+type Event struct {
+	Type     EventType
+	Data     string   // Remainder of the first line after the event name
+	Lines    []string // Additional lines of a multi-line event
+	Received time.Time
+}
+
+// StatusEvent is a parsed STATUS_GENERAL, STATUS_CLIENT or STATUS_SERVER event.
+type StatusEvent struct {
+	Severity string
+	Action   string
+	Args     map[string]string
+}
+
+// LogEvent is a Tor log message delivered as a NOTICE, WARN or ERR event.
+type LogEvent struct {
+	Severity string // Tor's severity name: notice, warn or err
+	Message  string
+}
+
+// BandwidthEvent is a parsed BW event with bytes transferred in the last second.
+type BandwidthEvent struct {
+	Read    int64
+	Written int64
+}
+
+// Subscription delivers events of the requested types until closed.
+type Subscription struct {
+	client *Client
+	types  map[EventType]struct{}
+	ch     chan Event
+}
+
+func newEvent(resp *reply) Event {
+	evt := Event{Received: time.Now()}
+
+	first := resp.lines[0].text
+	name, data, _ := strings.Cut(first, " ")
+	evt.Type = EventType(name)
+	evt.Data = data
+
+	// Long log messages arrive as a data block ("650+WARN") on the first line.
+	evt.Lines = append(evt.Lines, resp.lines[0].data...)
+
+	for _, line := range resp.lines[1:] {
+		if line.text == "OK" && line.data == nil {
+			continue
+		}
+		evt.Lines = append(evt.Lines, line.text)
+		evt.Lines = append(evt.Lines, line.data...)
+	}
+
+	return evt
+}
+
+// Subscribe registers interest in the given event types. If the client is
+// connected, SETEVENTS is issued immediately; otherwise it is issued on the
+// next successful Connect. Subscriptions survive reconnects.
+func (c *Client) Subscribe(types ...EventType) (*Subscription, error) {
+	sub := &Subscription{
+		client: c,
+		types:  make(map[EventType]struct{}, len(types)),
+		ch:     make(chan Event, subscriptionBuffer),
+	}
+	for _, t := range types {
+		sub.types[t] = struct{}{}
+	}
+
+	c.subMu.Lock()
+	c.subs[sub] = struct{}{}
+	c.subMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return sub, nil
+	}
+
+	if err := c.setEvents(); err != nil {
+		c.subMu.Lock()
+		delete(c.subs, sub)
+		c.subMu.Unlock()
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Events returns the channel events are delivered on. It is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close unregisters the subscription and narrows SETEVENTS accordingly.
+func (s *Subscription) Close() error {
+	c := s.client
+
+	c.subMu.Lock()
+	if _, ok := c.subs[s]; !ok {
+		c.subMu.Unlock()
+		return nil
+	}
+	delete(c.subs, s)
+	close(s.ch)
+	c.subMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.setEvents()
+}
+
+func (c *Client) hasSubscriptions() bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	return len(c.subs) > 0
+}
+
+// setEvents issues SETEVENTS for the union of all subscribed event types.
+// Callers must hold c.mu.
+func (c *Client) setEvents() error {
+	c.subMu.Lock()
+	wanted := make(map[EventType]struct{})
+	for sub := range c.subs {
+		for t := range sub.types {
+			wanted[t] = struct{}{}
+		}
+	}
+	c.subMu.Unlock()
+
+	names := make([]string, 0, len(wanted))
+	for t := range wanted {
+		names = append(names, string(t))
+	}
+	sort.Strings(names)
+
+	cmd := strings.TrimSpace("SETEVENTS " + strings.Join(names, " "))
+	resp, err := c.roundTrip(cmd)
+	if err != nil {
+		return err
+	}
+
+	if resp.code != 250 {
+		return fmt.Errorf("setevents failed: %s", resp.message())
+	}
+
+	return nil
+}
+
+// dispatch fans an event out to interested subscribers without blocking the
+// reader goroutine; events are dropped for subscribers that fall behind.
+func (c *Client) dispatch(evt Event) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for sub := range c.subs {
+		if _, ok := sub.types[evt.Type]; !ok {
+			continue
+		}
+		select {
+		case sub.ch <- evt:
+		default:
+			slog.Debug("Dropping Tor event for slow subscriber", "event", evt.Type)
+		}
+	}
+}
+
+// ParseStatusEvent parses the "Severity Action Arguments" form shared by the
+// STATUS_* events.
+func ParseStatusEvent(evt Event) (StatusEvent, error) {
+	positional, args := parseArgs(evt.Data)
+	if len(positional) < 2 {
+		return StatusEvent{}, fmt.Errorf("malformed %s event: %q", evt.Type, evt.Data)
+	}
+
+	return StatusEvent{
+		Severity: positional[0],
+		Action:   positional[1],
+		Args:     args,
+	}, nil
+}
+
+// ParseLogEvent extracts the message of a NOTICE, WARN or ERR event. Lines
+// of a multi-line message are joined with newlines.
+func ParseLogEvent(evt Event) LogEvent {
+	var lines []string
+	if evt.Data != "" {
+		lines = append(lines, evt.Data)
+	}
+	lines = append(lines, evt.Lines...)
+
+	return LogEvent{
+		Severity: strings.ToLower(string(evt.Type)),
+		Message:  strings.Join(lines, "\n"),
+	}
+}
+
+// ParseBandwidthEvent parses a BW event of the form "BytesRead BytesWritten".
+func ParseBandwidthEvent(evt Event) (BandwidthEvent, error) {
+	fields := strings.Fields(evt.Data)
+	if len(fields) < 2 {
+		return BandwidthEvent{}, fmt.Errorf("malformed BW event: %q", evt.Data)
+	}
+
+	read, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return BandwidthEvent{}, fmt.Errorf("malformed BW read value: %w", err)
+	}
+
+	written, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return BandwidthEvent{}, fmt.Errorf("malformed BW written value: %w", err)
+	}
+
+	return BandwidthEvent{Read: read, Written: written}, nil
+}
+
+// parseArgs splits a control protocol argument string into positional values
+// and KEY=VALUE pairs. Quoted values may contain spaces and backslash escapes.
+func parseArgs(s string) ([]string, map[string]string) {
+	var positional []string
+	args := make(map[string]string)
+
+	for _, token := range splitQuoted(s) {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			positional = append(positional, token)
+			continue
+		}
+		args[key] = unquote(value)
+	}
+
+	return positional, args
+}
+
+// splitQuoted splits s on spaces that are not inside double quotes.
+func splitQuoted(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && inQuotes:
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ' ' && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// unquote strips surrounding quotes and resolves backslash escapes.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var out strings.Builder
+	escaped := false
+	for _, r := range s[1 : len(s)-1] {
+		if escaped {
+			switch r {
+			case 'n':
+				out.WriteRune('\n')
+			case 't':
+				out.WriteRune('\t')
+			default:
+				out.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
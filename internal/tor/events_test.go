@@ -0,0 +1,311 @@
+package tor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatusEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantSeverity string
+		wantAction   string
+		wantArgs     map[string]string
+		wantErr      bool
+	}{
+		{
+			name:         "Bootstrap progress",
+			data:         `NOTICE BOOTSTRAP PROGRESS=75 TAG=enough_dirinfo SUMMARY="Loaded enough directory info to build circuits"`,
+			wantSeverity: "NOTICE",
+			wantAction:   "BOOTSTRAP",
+			wantArgs: map[string]string{
+				"PROGRESS": "75",
+				"TAG":      "enough_dirinfo",
+				"SUMMARY":  "Loaded enough directory info to build circuits",
+			},
+		},
+		{
+			name:         "Circuit established",
+			data:         "NOTICE CIRCUIT_ESTABLISHED",
+			wantSeverity: "NOTICE",
+			wantAction:   "CIRCUIT_ESTABLISHED",
+			wantArgs:     map[string]string{},
+		},
+		{
+			name:         "Escaped quote in value",
+			data:         `WARN BOOTSTRAP WARNING="say \"hi\"" RECOMMENDATION=warn`,
+			wantSeverity: "WARN",
+			wantAction:   "BOOTSTRAP",
+			wantArgs: map[string]string{
+				"WARNING":        `say "hi"`,
+				"RECOMMENDATION": "warn",
+			},
+		},
+		{
+			name:    "Missing action",
+			data:    "NOTICE",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := ParseStatusEvent(Event{Type: EventStatusClient, Data: tt.data})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if status.Severity != tt.wantSeverity {
+				t.Errorf("expected severity %q, got %q", tt.wantSeverity, status.Severity)
+			}
+			if status.Action != tt.wantAction {
+				t.Errorf("expected action %q, got %q", tt.wantAction, status.Action)
+			}
+			if len(status.Args) != len(tt.wantArgs) {
+				t.Errorf("expected %d args, got %d (%v)", len(tt.wantArgs), len(status.Args), status.Args)
+			}
+			for k, v := range tt.wantArgs {
+				if status.Args[k] != v {
+					t.Errorf("expected arg %s=%q, got %q", k, v, status.Args[k])
+				}
+			}
+		})
+	}
+}
+
+func TestParseBandwidthEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantRead    int64
+		wantWritten int64
+		wantErr     bool
+	}{
+		{name: "Valid", data: "1024 2048", wantRead: 1024, wantWritten: 2048},
+		{name: "Extra fields", data: "10 20 TCP=0", wantRead: 10, wantWritten: 20},
+		{name: "Missing written", data: "1024", wantErr: true},
+		{name: "Non-numeric", data: "abc 20", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bw, err := ParseBandwidthEvent(Event{Type: EventBW, Data: tt.data})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bw.Read != tt.wantRead || bw.Written != tt.wantWritten {
+				t.Errorf("expected %d/%d, got %d/%d", tt.wantRead, tt.wantWritten, bw.Read, bw.Written)
+			}
+		})
+	}
+}
+
+func TestNewEvent_MultiLine(t *testing.T) {
+	resp := &reply{
+		code: 650,
+		lines: []replyLine{
+			{text: "NS"},
+			{text: "", data: []string{"r relay1 AAAA", "s Fast Running"}},
+			{text: "OK"},
+		},
+	}
+
+	evt := newEvent(resp)
+	if evt.Type != "NS" {
+		t.Errorf("expected type NS, got %q", evt.Type)
+	}
+	if evt.Data != "" {
+		t.Errorf("expected empty data, got %q", evt.Data)
+	}
+	if len(evt.Lines) != 3 {
+		t.Errorf("expected 3 lines, got %v", evt.Lines)
+	}
+}
+
+func TestParseLogEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		evt          Event
+		wantSeverity string
+		wantMessage  string
+	}{
+		{
+			name:         "single line",
+			evt:          Event{Type: EventWarn, Data: "Problem bootstrapping. Stuck at 10%."},
+			wantSeverity: "warn",
+			wantMessage:  "Problem bootstrapping. Stuck at 10%.",
+		},
+		{
+			name:         "data block",
+			evt:          Event{Type: EventNotice, Lines: []string{"Bootstrapped 100% (done)", "Done"}},
+			wantSeverity: "notice",
+			wantMessage:  "Bootstrapped 100% (done)\nDone",
+		},
+		{
+			name:         "error",
+			evt:          Event{Type: EventErr, Data: "Could not bind to 0.0.0.0:9050"},
+			wantSeverity: "err",
+			wantMessage:  "Could not bind to 0.0.0.0:9050",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := ParseLogEvent(tt.evt)
+			if msg.Severity != tt.wantSeverity {
+				t.Errorf("expected severity %q, got %q", tt.wantSeverity, msg.Severity)
+			}
+			if msg.Message != tt.wantMessage {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, msg.Message)
+			}
+		})
+	}
+}
+
+func TestNewEvent_DataBlock(t *testing.T) {
+	resp := &reply{
+		code: 650,
+		lines: []replyLine{
+			{text: "WARN", data: []string{"first line", "second line"}},
+			{text: "OK"},
+		},
+	}
+
+	evt := newEvent(resp)
+	if evt.Type != EventWarn {
+		t.Errorf("expected type WARN, got %q", evt.Type)
+	}
+	if msg := ParseLogEvent(evt).Message; msg != "first line\nsecond line" {
+		t.Errorf("expected data block lines, got %q", msg)
+	}
+}
+
+func TestSubscribe_DeliversAsyncEvents(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	sub, err := client.Subscribe(EventStatusClient, EventBW)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = sub.Close() }()
+
+	// Subscribing before connecting defers SETEVENTS until Connect
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	if len(commands) != 3 || commands[2] != "SETEVENTS BW STATUS_CLIENT" {
+		t.Fatalf("expected SETEVENTS after authenticating, got %v", commands)
+	}
+
+	fake.Emit("650 CIRC 1 BUILT\r\n")
+	fake.Emit("650 STATUS_CLIENT NOTICE CIRCUIT_ESTABLISHED\r\n")
+	fake.Emit("650 BW 100 200\r\n")
+
+	// Synchronous commands still work while events are interleaved
+	if err := client.Signal("NEWNYM"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EventType{EventStatusClient, EventBW}
+	for _, wantType := range want {
+		select {
+		case evt := <-sub.Events():
+			if evt.Type != wantType {
+				t.Errorf("expected %s event, got %s", wantType, evt.Type)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s event", wantType)
+		}
+	}
+
+	select {
+	case evt := <-sub.Events():
+		t.Errorf("unexpected extra event: %+v", evt)
+	default:
+	}
+}
+
+func TestSubscription_CloseNarrowsEvents(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	circ, err := client.Subscribe(EventCirc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stream, err := client.Subscribe(EventStream)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := circ.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := <-circ.Events(); ok {
+		t.Error("expected events channel to be closed")
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"PROTOCOLINFO 1",
+		"AUTHENTICATE",
+		"SETEVENTS CIRC",
+		"SETEVENTS CIRC STREAM",
+		"SETEVENTS STREAM",
+		"SETEVENTS",
+	}
+	if got := fake.Commands(); strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected commands %v, got %v", expected, got)
+	}
+}
+
+func TestSubscribe_Rejected(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "SETEVENTS") {
+			return "552 Unrecognized event \"BOGUS\"\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.Subscribe("BOGUS"); err == nil {
+		t.Fatal("expected SETEVENTS rejection to fail Subscribe")
+	}
+	if client.hasSubscriptions() {
+		t.Error("expected rejected subscription to be removed")
+	}
+}
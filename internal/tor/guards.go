@@ -0,0 +1,75 @@
+package tor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EntryGuard is an entry in Tor's guard list. With UseBridges the guards are
+// the configured bridges.
+type EntryGuard struct {
+	Fingerprint string
+	Nickname    string
+	Status      string // up, never-connected, down, unusable or unlisted
+	Since       string // When the status last changed, if Tor reports it
+}
+
+// Up reports whether Tor currently has a working connection to the guard.
+func (g EntryGuard) Up() bool {
+	return g.Status == "up"
+}
+
+// GetEntryGuards lists Tor's entry guards via GETINFO entry-guards.
+func (c *Client) GetEntryGuards() ([]EntryGuard, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo("entry-guards")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEntryGuards(info["entry-guards"])
+}
+
+// ParseEntryGuards parses the entry-guards lines, each of the form
+//
+//	$Fingerprint~Nickname Status [ISOTime]
+//
+// Older Tor versions separate the nickname with "=" or give only one of
+// the fingerprint and nickname.
+func ParseEntryGuards(data string) ([]EntryGuard, error) {
+	var guards []EntryGuard
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed entry guard line: %q", line)
+		}
+
+		guard := EntryGuard{Status: fields[1]}
+		if len(fields) > 2 {
+			guard.Since = strings.Join(fields[2:], " ")
+		}
+
+		id := fields[0]
+		if fingerprint, ok := strings.CutPrefix(id, "$"); ok {
+			fingerprint, nickname, _ := strings.Cut(fingerprint, "~")
+			if fp, nick, ok := strings.Cut(fingerprint, "="); ok {
+				fingerprint, nickname = fp, nick
+			}
+			guard.Fingerprint = strings.ToUpper(fingerprint)
+			guard.Nickname = nickname
+		} else {
+			guard.Nickname = id
+		}
+
+		guards = append(guards, guard)
+	}
+
+	return guards, nil
+}
@@ -0,0 +1,53 @@
+package tor
+
+import "testing"
+
+func TestParseEntryGuards(t *testing.T) {
+	data := "$0123456789ABCDEF0123456789ABCDEF01234567~bridge1 up\n" +
+		"$89abcdef0123456789abcdef0123456789ABCDEF=bridge2 down 2024-05-01 11:22:33\n" +
+		"oldguard never-connected"
+
+	guards, err := ParseEntryGuards(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(guards) != 3 {
+		t.Fatalf("expected 3 guards, got %d", len(guards))
+	}
+
+	if guards[0].Fingerprint != "0123456789ABCDEF0123456789ABCDEF01234567" || guards[0].Nickname != "bridge1" || !guards[0].Up() {
+		t.Errorf("unexpected first guard: %+v", guards[0])
+	}
+	if guards[1].Fingerprint != "89ABCDEF0123456789ABCDEF0123456789ABCDEF" || guards[1].Status != "down" || guards[1].Since != "2024-05-01 11:22:33" {
+		t.Errorf("unexpected second guard: %+v", guards[1])
+	}
+	if guards[2].Fingerprint != "" || guards[2].Nickname != "oldguard" || guards[2].Up() {
+		t.Errorf("unexpected third guard: %+v", guards[2])
+	}
+
+	if _, err := ParseEntryGuards("$ABCD~lonely"); err == nil {
+		t.Error("expected an error for a guard without a status")
+	}
+}
+
+func TestGetEntryGuards_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO entry-guards" {
+			return "250+entry-guards=\r\n" +
+				"$0123456789ABCDEF0123456789ABCDEF01234567~bridge1 up\r\n" +
+				".\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	guards, err := client.GetEntryGuards()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(guards) != 1 || guards[0].Nickname != "bridge1" || !guards[0].Up() {
+		t.Errorf("unexpected guards: %+v", guards)
+	}
+}
@@ -0,0 +1,283 @@
+package tor
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// OnionKeyNew asks ADD_ONION to generate a new v3 service key.
+const OnionKeyNew = "NEW:ED25519-V3"
+
+// onionKeyPrefix marks an existing v3 service key.
+const onionKeyPrefix = "ED25519-V3:"
+
+// ErrUnknownOnion is returned by DelOnion when Tor is not running the service.
+var ErrUnknownOnion = errors.New("unknown onion service")
+
+// base32NoPad is the unpadded RFC 4648 alphabet Tor uses for v3 client keys.
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OnionPort maps a virtual port of an onion service to a local target.
+type OnionPort struct {
+	Virtual int    `json:"virtual"`
+	Target  string `json:"target,omitempty"` // host:port or unix:/path; empty means 127.0.0.1:Virtual
+}
+
+// AddOnionRequest describes a service for ADD_ONION.
+type AddOnionRequest struct {
+	Key        string // OnionKeyNew, or "ED25519-V3:<base64>" to reuse a key
+	Ports      []OnionPort
+	ClientAuth []string // Base32 x25519 public keys of authorized clients
+}
+
+// OnionService is Tor's reply to ADD_ONION.
+type OnionService struct {
+	ServiceID  string // Onion address without the ".onion" suffix
+	PrivateKey string // "ED25519-V3:<base64>", only set when Tor generated the key
+}
+
+// Validate checks the request before it is sent, so values cannot inject
+// extra arguments into the command.
+func (r AddOnionRequest) Validate() error {
+	if r.Key != OnionKeyNew {
+		blob, ok := strings.CutPrefix(r.Key, onionKeyPrefix)
+		if !ok {
+			return fmt.Errorf("unsupported onion key type: expected %s or an %s key", OnionKeyNew, strings.TrimSuffix(onionKeyPrefix, ":"))
+		}
+		if key, err := base64.StdEncoding.DecodeString(blob); err != nil || len(key) != 64 {
+			return errors.New("invalid ED25519-V3 onion key")
+		}
+	}
+
+	if len(r.Ports) == 0 {
+		return errors.New("at least one port is required")
+	}
+	for _, port := range r.Ports {
+		if err := port.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range r.ClientAuth {
+		if err := ValidateClientAuthKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the virtual port and target. Target host names are
+// resolved by Tor.
+func (p OnionPort) Validate() error {
+	if p.Virtual < 1 || p.Virtual > 65535 {
+		return fmt.Errorf("invalid virtual port %d", p.Virtual)
+	}
+	if p.Target == "" {
+		return nil
+	}
+	if strings.ContainsAny(p.Target, " \t\r\n,\"") {
+		return fmt.Errorf("invalid port target %q", p.Target)
+	}
+	if path, ok := strings.CutPrefix(p.Target, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("invalid port target %q: missing socket path", p.Target)
+		}
+		return nil
+	}
+	host, portStr, err := net.SplitHostPort(p.Target)
+	if err != nil {
+		return fmt.Errorf("invalid port target %q: %v", p.Target, err)
+	}
+	if port, err := strconv.Atoi(portStr); err != nil || port < 1 || port > 65535 || host == "" {
+		return fmt.Errorf("invalid port target %q", p.Target)
+	}
+	return nil
+}
+
+// ValidateClientAuthKey checks a base32 x25519 public key as used by
+// ClientAuthV3.
+func ValidateClientAuthKey(key string) error {
+	decoded, err := base32NoPad.DecodeString(strings.ToUpper(key))
+	if err != nil || len(decoded) != 32 {
+		return fmt.Errorf("invalid client authorization key %q: expected a base32 x25519 public key", key)
+	}
+	return nil
+}
+
+// EncodeClientAuthKey encodes an x25519 key in the base32 form used by
+// ClientAuthV3 and client .auth_private files.
+func EncodeClientAuthKey(key []byte) string {
+	return base32NoPad.EncodeToString(key)
+}
+
+// AddOnion creates an onion service. Services are detached from the control
+// connection so they keep running when it reconnects; they end when Tor
+// exits or DelOnion is called.
+func (c *Client) AddOnion(req AddOnionRequest) (*OnionService, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	flags := "Detach"
+	if len(req.ClientAuth) > 0 {
+		flags += ",V3Auth"
+	}
+	args := []string{req.Key, "Flags=" + flags}
+	for _, port := range req.Ports {
+		arg := "Port=" + strconv.Itoa(port.Virtual)
+		if port.Target != "" {
+			arg += "," + port.Target
+		}
+		args = append(args, arg)
+	}
+	for _, key := range req.ClientAuth {
+		args = append(args, "ClientAuthV3="+strings.ToUpper(key))
+	}
+
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip("ADD_ONION " + strings.Join(args, " "))
+	if err != nil {
+		return nil, err
+	}
+	if resp.code != 250 {
+		return nil, fmt.Errorf("add_onion failed: %s", resp.message())
+	}
+
+	service := &OnionService{}
+	for _, line := range resp.lines {
+		key, value, _ := strings.Cut(line.text, "=")
+		switch key {
+		case "ServiceID":
+			service.ServiceID = value
+		case "PrivateKey":
+			service.PrivateKey = value
+		}
+	}
+	if service.ServiceID == "" {
+		return nil, errors.New("add_onion failed: no ServiceID in reply")
+	}
+
+	return service, nil
+}
+
+// DelOnion removes an onion service. It returns ErrUnknownOnion if Tor is
+// not running the service.
+func (c *Client) DelOnion(serviceID string) error {
+	if serviceID == "" || strings.ContainsAny(serviceID, " \t\r\n") {
+		return fmt.Errorf("invalid onion service id %q", serviceID)
+	}
+
+	if err := c.Connect(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip("DEL_ONION " + serviceID)
+	if err != nil {
+		return err
+	}
+	switch resp.code {
+	case 250:
+		return nil
+	case 552:
+		return fmt.Errorf("del_onion failed: %w: %s", ErrUnknownOnion, serviceID)
+	default:
+		return fmt.Errorf("del_onion failed: %s", resp.message())
+	}
+}
+
+// GetDetachedOnions lists the service IDs of detached onion services via
+// GETINFO onions/detached.
+func (c *Client) GetDetachedOnions() ([]string, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip("GETINFO onions/detached")
+	if err != nil {
+		return nil, err
+	}
+	// Tor reports an empty list as an error
+	if resp.code == 551 && strings.HasPrefix(resp.message(), "No onion services") {
+		return nil, nil
+	}
+	if resp.code != 250 {
+		return nil, fmt.Errorf("getinfo failed: %s", resp.message())
+	}
+
+	var ids []string
+	for _, line := range resp.lines {
+		value, ok := strings.CutPrefix(line.text, "onions/detached=")
+		if !ok {
+			continue
+		}
+		values := line.data
+		if line.data == nil {
+			values = []string{value}
+		}
+		for _, id := range values {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// HSDescEvent is a parsed HS_DESC event reporting onion service descriptor
+// uploads and fetches.
+type HSDescEvent struct {
+	Action    string // REQUESTED, UPLOAD, RECEIVED, UPLOADED, IGNORE, FAILED or CREATED
+	Address   string // Onion address without ".onion", or UNKNOWN
+	AuthType  string
+	HSDir     string // Fingerprint of the directory, without "$" or nickname
+	Reason    string // Set on FAILED
+	Uploading bool   // Whether the event concerns publishing rather than fetching
+}
+
+// ParseHSDescEvent parses an HS_DESC event of the form
+//
+//	Action HSAddress AuthType HsDir [DescriptorID] [REASON=...] [...]
+func ParseHSDescEvent(evt Event) (HSDescEvent, error) {
+	positional, args := parseArgs(evt.Data)
+	if len(positional) < 4 {
+		return HSDescEvent{}, fmt.Errorf("malformed HS_DESC event: %q", evt.Data)
+	}
+
+	hsDir := strings.TrimPrefix(positional[3], "$")
+	hsDir, _, _ = strings.Cut(hsDir, "~")
+
+	desc := HSDescEvent{
+		Action:   positional[0],
+		Address:  positional[1],
+		AuthType: positional[2],
+		HSDir:    hsDir,
+		Reason:   args["REASON"],
+	}
+	// Upload failures carry REASON=UPLOAD_REJECTED; other failures are fetches
+	switch desc.Action {
+	case "UPLOAD", "UPLOADED", "CREATED":
+		desc.Uploading = true
+	case "FAILED":
+		desc.Uploading = desc.Reason == "UPLOAD_REJECTED"
+	}
+
+	return desc, nil
+}
@@ -0,0 +1,203 @@
+package tor
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testOnionKey is a syntactically valid ED25519-V3 key blob (64 zero bytes).
+var testOnionKey = "ED25519-V3:" + strings.Repeat("A", 86) + "=="
+
+// testClientKey is a valid base32 x25519 public key (32 zero bytes).
+var testClientKey = strings.Repeat("A", 52)
+
+func TestAddOnionRequest_Validate(t *testing.T) {
+	valid := func() AddOnionRequest {
+		return AddOnionRequest{Key: OnionKeyNew, Ports: []OnionPort{{Virtual: 80, Target: "sonarr:8989"}}}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *AddOnionRequest)
+		wantErr string
+	}{
+		{name: "valid", modify: func(r *AddOnionRequest) {}},
+		{name: "existing key", modify: func(r *AddOnionRequest) { r.Key = testOnionKey }},
+		{name: "unix target", modify: func(r *AddOnionRequest) { r.Ports[0].Target = "unix:/run/app.sock" }},
+		{name: "default target", modify: func(r *AddOnionRequest) { r.Ports[0].Target = "" }},
+		{name: "client auth", modify: func(r *AddOnionRequest) { r.ClientAuth = []string{strings.ToLower(testClientKey)} }},
+		{name: "rsa key", modify: func(r *AddOnionRequest) { r.Key = "RSA1024:AAAA" }, wantErr: "unsupported onion key type"},
+		{name: "short key", modify: func(r *AddOnionRequest) { r.Key = "ED25519-V3:AAAA" }, wantErr: "invalid ED25519-V3"},
+		{name: "no ports", modify: func(r *AddOnionRequest) { r.Ports = nil }, wantErr: "at least one port"},
+		{name: "bad virtual port", modify: func(r *AddOnionRequest) { r.Ports[0].Virtual = 0 }, wantErr: "invalid virtual port"},
+		{name: "target injection", modify: func(r *AddOnionRequest) { r.Ports[0].Target = "127.0.0.1:80 Flags=DiscardPK" }, wantErr: "invalid port target"},
+		{name: "target without port", modify: func(r *AddOnionRequest) { r.Ports[0].Target = "sonarr" }, wantErr: "invalid port target"},
+		{name: "bad client key", modify: func(r *AddOnionRequest) { r.ClientAuth = []string{"not-a-key"} }, wantErr: "invalid client authorization key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			err := req.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAddOnion_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "ADD_ONION NEW:") {
+			return "250-ServiceID=abcdefghijklmnopqrstuvwxyz234567abcdefghijklmnopqrstuvwx\r\n" +
+				"250-PrivateKey=" + testOnionKey + "\r\n" +
+				"250 OK\r\n"
+		}
+		if strings.HasPrefix(cmd, "ADD_ONION ED25519-V3:") {
+			return "250-ServiceID=abcdefghijklmnopqrstuvwxyz234567abcdefghijklmnopqrstuvwx\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	service, err := client.AddOnion(AddOnionRequest{
+		Key:        OnionKeyNew,
+		Ports:      []OnionPort{{Virtual: 80, Target: "127.0.0.1:8989"}, {Virtual: 443}},
+		ClientAuth: []string{strings.ToLower(testClientKey)},
+	})
+	if err != nil {
+		t.Fatalf("AddOnion() error = %v", err)
+	}
+	if service.ServiceID != "abcdefghijklmnopqrstuvwxyz234567abcdefghijklmnopqrstuvwx" || service.PrivateKey != testOnionKey {
+		t.Errorf("unexpected service: %+v", service)
+	}
+
+	restored, err := client.AddOnion(AddOnionRequest{Key: testOnionKey, Ports: []OnionPort{{Virtual: 80}}})
+	if err != nil {
+		t.Fatalf("AddOnion() with existing key error = %v", err)
+	}
+	if restored.PrivateKey != "" {
+		t.Errorf("expected no private key for an existing key, got %q", restored.PrivateKey)
+	}
+
+	want := "ADD_ONION NEW:ED25519-V3 Flags=Detach,V3Auth Port=80,127.0.0.1:8989 Port=443 ClientAuthV3=" + testClientKey
+	if !slices.Contains(fake.Commands(), want) {
+		t.Errorf("expected command %q, got %v", want, fake.Commands())
+	}
+}
+
+func TestAddOnion_Rejected(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if strings.HasPrefix(cmd, "ADD_ONION") {
+			return "550 Onion address collision\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	_, err := client.AddOnion(AddOnionRequest{Key: testOnionKey, Ports: []OnionPort{{Virtual: 80}}})
+	if err == nil || !strings.Contains(err.Error(), "collision") {
+		t.Errorf("expected collision error, got %v", err)
+	}
+}
+
+func TestDelOnion_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		switch cmd {
+		case "DEL_ONION known":
+			return "250 OK\r\n"
+		case "DEL_ONION missing":
+			return "552 Unknown Onion Service id\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.DelOnion("known"); err != nil {
+		t.Errorf("DelOnion() error = %v", err)
+	}
+	if err := client.DelOnion("missing"); !errors.Is(err, ErrUnknownOnion) {
+		t.Errorf("expected ErrUnknownOnion, got %v", err)
+	}
+	if err := client.DelOnion("bad id"); err == nil {
+		t.Error("expected an invalid service id to be rejected")
+	}
+}
+
+func TestGetDetachedOnions_FakeTor(t *testing.T) {
+	detached := true
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO onions/detached" {
+			if !detached {
+				return "551 No onion services of the specified type.\r\n"
+			}
+			return "250+onions/detached=\r\nfirst\r\nsecond\r\n.\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	ids, err := client.GetDetachedOnions()
+	if err != nil {
+		t.Fatalf("GetDetachedOnions() error = %v", err)
+	}
+	if !slices.Equal(ids, []string{"first", "second"}) {
+		t.Errorf("unexpected ids %v", ids)
+	}
+
+	detached = false
+	ids, err = client.GetDetachedOnions()
+	if err != nil || ids != nil {
+		t.Errorf("expected no services without an error, got %v, %v", ids, err)
+	}
+}
+
+func TestParseHSDescEvent(t *testing.T) {
+	tests := []struct {
+		data      string
+		action    string
+		hsDir     string
+		reason    string
+		uploading bool
+	}{
+		{data: "UPLOAD abcdef NO_AUTH $AAAA~dir1 DESCID HSDIR_INDEX=1234", action: "UPLOAD", hsDir: "AAAA", uploading: true},
+		{data: "UPLOADED abcdef UNKNOWN $BBBB~dir2", action: "UPLOADED", hsDir: "BBBB", uploading: true},
+		{data: "FAILED abcdef NO_AUTH $CCCC~dir3 REASON=UPLOAD_REJECTED", action: "FAILED", hsDir: "CCCC", reason: "UPLOAD_REJECTED", uploading: true},
+		{data: "FAILED abcdef NO_AUTH $DDDD~dir4 REASON=NOT_FOUND", action: "FAILED", hsDir: "DDDD", reason: "NOT_FOUND"},
+		{data: "RECEIVED abcdef NO_AUTH $EEEE~dir5 DESCID", action: "RECEIVED", hsDir: "EEEE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			desc, err := ParseHSDescEvent(Event{Type: EventHSDesc, Data: tt.data})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if desc.Action != tt.action || desc.Address != "abcdef" || desc.HSDir != tt.hsDir ||
+				desc.Reason != tt.reason || desc.Uploading != tt.uploading {
+				t.Errorf("unexpected event: %+v", desc)
+			}
+		})
+	}
+
+	if _, err := ParseHSDescEvent(Event{Type: EventHSDesc, Data: "UPLOAD abcdef"}); err == nil {
+		t.Error("expected an error for a truncated event")
+	}
+}
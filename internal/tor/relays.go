@@ -0,0 +1,100 @@
+package tor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RelayInfo is a relay's entry in the current consensus.
+type RelayInfo struct {
+	Fingerprint string
+	Nickname    string
+	Address     string
+	ORPort      int
+	Flags       []string
+}
+
+// GetRelay looks up a relay's router status entry via GETINFO ns/id/<fingerprint>.
+func (c *Client) GetRelay(fingerprint string) (*RelayInfo, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	fingerprint = strings.TrimPrefix(fingerprint, "$")
+	key := "ns/id/" + fingerprint
+
+	info, err := c.GetInfo(key)
+	if err != nil {
+		return nil, err
+	}
+
+	relay, err := ParseRouterStatus(info[key])
+	if err != nil {
+		return nil, err
+	}
+	relay.Fingerprint = fingerprint
+
+	return relay, nil
+}
+
+// GetCountry resolves an IP address to a country code using Tor's GeoIP
+// database via GETINFO ip-to-country/<ip>. Tor reports "??" when unknown.
+func (c *Client) GetCountry(ip string) (string, error) {
+	if err := c.Connect(); err != nil {
+		return "", err
+	}
+
+	key := "ip-to-country/" + ip
+
+	info, err := c.GetInfo(key)
+	if err != nil {
+		return "", err
+	}
+
+	country, ok := info[key]
+	if !ok {
+		return "", fmt.Errorf("no country reported for %s", ip)
+	}
+
+	return country, nil
+}
+
+// ParseRouterStatus parses a router status entry as returned by ns/id/*:
+//
+//	r Nickname Identity Digest Date Time IP ORPort DirPort
+//	s Flags...
+func ParseRouterStatus(data string) (*RelayInfo, error) {
+	relay := &RelayInfo{}
+	found := false
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "r":
+			if len(fields) < 8 {
+				return nil, fmt.Errorf("malformed router status line: %q", line)
+			}
+			relay.Nickname = fields[1]
+			relay.Address = fields[6]
+			port, err := strconv.Atoi(fields[7])
+			if err != nil {
+				return nil, fmt.Errorf("malformed router ORPort %q: %w", fields[7], err)
+			}
+			relay.ORPort = port
+			found = true
+		case "s":
+			relay.Flags = fields[1:]
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("router status entry not found")
+	}
+
+	return relay, nil
+}
@@ -0,0 +1,84 @@
+package tor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRouterStatus(t *testing.T) {
+	data := "r exitrelay qvU+3sBsDIbV0aqB3Tv9zkyDVV4 Kyp5yXIuH0PRGYbqkDKh0vC5yxk 2024-05-01 11:22:33 185.220.101.5 9001 0\n" +
+		"s Exit Fast Running Stable Valid\n" +
+		"w Bandwidth=12000"
+
+	relay, err := ParseRouterStatus(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if relay.Nickname != "exitrelay" {
+		t.Errorf("expected nickname 'exitrelay', got %q", relay.Nickname)
+	}
+	if relay.Address != "185.220.101.5" {
+		t.Errorf("expected address '185.220.101.5', got %q", relay.Address)
+	}
+	if relay.ORPort != 9001 {
+		t.Errorf("expected ORPort 9001, got %d", relay.ORPort)
+	}
+	if strings.Join(relay.Flags, ",") != "Exit,Fast,Running,Stable,Valid" {
+		t.Errorf("unexpected flags: %v", relay.Flags)
+	}
+}
+
+func TestParseRouterStatus_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Empty", data: ""},
+		{name: "Short r line", data: "r nick ident"},
+		{name: "Bad ORPort", data: "r nick ident digest 2024-05-01 11:22:33 1.2.3.4 notaport 0"},
+		{name: "No r line", data: "s Exit Fast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRouterStatus(tt.data); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestGetRelayAndCountry_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		switch cmd {
+		case "GETINFO ns/id/CCCC":
+			return "250+ns/id/CCCC=\r\n" +
+				"r exitrelay qvU+3sBs Kyp5yXIu 2024-05-01 11:22:33 185.220.101.5 443 0\r\n" +
+				"s Exit Running\r\n" +
+				".\r\n250 OK\r\n"
+		case "GETINFO ip-to-country/185.220.101.5":
+			return "250-ip-to-country/185.220.101.5=de\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	relay, err := client.GetRelay("$CCCC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if relay.Fingerprint != "CCCC" || relay.Nickname != "exitrelay" || relay.Address != "185.220.101.5" {
+		t.Errorf("unexpected relay: %+v", relay)
+	}
+
+	country, err := client.GetCountry(relay.Address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country != "de" {
+		t.Errorf("expected country 'de', got %q", country)
+	}
+}
@@ -0,0 +1,47 @@
+package tor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SetSocksPorts replaces every SocksPort with a single SETCONF, so Tor opens
+// added listeners and closes removed ones without a restart. Each value is
+// a SocksPort line without the keyword, e.g. "0.0.0.0:9060 IsolateDestAddr".
+func (c *Client) SetSocksPorts(values []string) error {
+	if len(values) == 0 {
+		return errors.New("at least one SocksPort is required")
+	}
+
+	args := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid SocksPort %q", value)
+		}
+		args = append(args, "SocksPort="+quote(value))
+	}
+
+	return c.confCommand("SETCONF " + strings.Join(args, " "))
+}
+
+// GetSocksListeners returns the addresses Tor is accepting SOCKS
+// connections on, via GETINFO net/listeners/socks.
+func (c *Client) GetSocksListeners() ([]string, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo("net/listeners/socks")
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, address := range splitQuoted(info["net/listeners/socks"]) {
+		if address = unquote(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
@@ -0,0 +1,54 @@
+package tor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetSocksPorts_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, okHandler)
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.SetSocksPorts([]string{"0.0.0.0:9050", "0.0.0.0:9060 IsolateDestAddr IsolateSOCKSAuth"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := fake.Commands()
+	expected := `SETCONF SocksPort="0.0.0.0:9050" SocksPort="0.0.0.0:9060 IsolateDestAddr IsolateSOCKSAuth"`
+	if got := commands[len(commands)-1]; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSetSocksPorts_Invalid(t *testing.T) {
+	client := NewClient("127.0.0.1:1", "")
+
+	if err := client.SetSocksPorts(nil); err == nil {
+		t.Error("expected an error without any SocksPort")
+	}
+	if err := client.SetSocksPorts([]string{"9050\r\nControlPort 9051"}); err == nil {
+		t.Error("expected an error for a value with a line break")
+	}
+}
+
+func TestGetSocksListeners_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "GETINFO net/listeners/socks" {
+			return "250-net/listeners/socks=\"0.0.0.0:9050\" \"127.0.0.1:9060\"\r\n250 OK\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	listeners, err := client.GetSocksListeners()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"0.0.0.0:9050", "127.0.0.1:9060"}; !reflect.DeepEqual(listeners, expected) {
+		t.Errorf("expected %v, got %v", expected, listeners)
+	}
+}
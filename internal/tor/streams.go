@@ -0,0 +1,129 @@
+package tor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StreamReasonMisc is the generic RELAY_END reason for CloseStream.
+const StreamReasonMisc = 1
+
+// ErrUnknownStream is returned when Tor does not know the stream, or the
+// circuit it should be attached to.
+var ErrUnknownStream = errors.New("unknown stream or circuit")
+
+// Stream is a single entry from GETINFO stream-status or a STREAM event.
+type Stream struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	CircuitID string `json:"circuit_id"`
+	Target    string `json:"target"`
+	Reason    string `json:"reason,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+	Source    string `json:"source_addr,omitempty"`
+}
+
+// StreamStatuses lists every stream state defined by the control protocol.
+var StreamStatuses = []string{
+	"NEW", "NEWRESOLVE", "REMAP", "SENTCONNECT", "SENTRESOLVE",
+	"SUCCEEDED", "FAILED", "CLOSED", "DETACHED", "CONTROLLER_WAIT", "XOFF_SENT",
+	"XOFF_RECV", "XON_SENT", "XON_RECV",
+}
+
+// GetStreams returns all streams Tor currently knows about.
+func (c *Client) GetStreams() ([]Stream, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetInfo("stream-status")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStreamStatus(info["stream-status"])
+}
+
+// CloseStream closes a stream with a RELAY_END reason code, such as
+// StreamReasonMisc. It returns ErrUnknownStream if the stream does not exist.
+func (c *Client) CloseStream(id string, reason int) error {
+	if !ValidID(id) {
+		return fmt.Errorf("invalid stream id %q", id)
+	}
+	if reason < 1 || reason > 255 {
+		return fmt.Errorf("invalid stream close reason %d", reason)
+	}
+
+	return c.idCommand(fmt.Sprintf("CLOSESTREAM %s %d", id, reason), ErrUnknownStream, id)
+}
+
+// AttachStream attaches a stream to a circuit, or lets Tor choose one when
+// circuitID is "0". Tor only accepts streams that are waiting for a
+// controller (__LeaveStreamsUnattached) or were detached from a closed
+// circuit. It returns ErrUnknownStream if the stream or circuit does not
+// exist.
+func (c *Client) AttachStream(id, circuitID string) error {
+	if !ValidID(id) {
+		return fmt.Errorf("invalid stream id %q", id)
+	}
+	if !ValidID(circuitID) {
+		return fmt.Errorf("invalid circuit id %q", circuitID)
+	}
+
+	return c.idCommand("ATTACHSTREAM "+id+" "+circuitID, ErrUnknownStream, id)
+}
+
+// ParseStreamStatus parses the multi-line value of GETINFO stream-status.
+func ParseStreamStatus(data string) ([]Stream, error) {
+	streams := []Stream{}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		stream, err := ParseStream(line)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, stream)
+	}
+
+	return streams, nil
+}
+
+// ParseStreamEvent parses a STREAM event, which shares the stream-status format.
+func ParseStreamEvent(evt Event) (Stream, error) {
+	return ParseStream(evt.Data)
+}
+
+// ParseStream parses a single stream description:
+//
+//	StreamID SP StreamStatus SP CircuitID SP Target [SP KEYWORD=Value]...
+func ParseStream(line string) (Stream, error) {
+	positional, args := parseArgs(line)
+	if len(positional) < 4 {
+		return Stream{}, fmt.Errorf("malformed stream: %q", line)
+	}
+
+	return Stream{
+		ID:        positional[0],
+		Status:    positional[1],
+		CircuitID: positional[2],
+		Target:    positional[3],
+		Reason:    args["REASON"],
+		Purpose:   args["PURPOSE"],
+		Source:    args["SOURCE_ADDR"],
+	}, nil
+}
+
+// CountStreamsByStatus tallies streams per status.
+func CountStreamsByStatus(streams []Stream) map[string]int {
+	counts := make(map[string]int)
+	for _, stream := range streams {
+		counts[stream.Status]++
+	}
+	return counts
+}
@@ -0,0 +1,134 @@
+package tor
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Stream
+		wantErr bool
+	}{
+		{
+			name: "Succeeded stream",
+			line: "42 SUCCEEDED 7 indexer.example.com:443",
+			want: Stream{ID: "42", Status: "SUCCEEDED", CircuitID: "7", Target: "indexer.example.com:443"},
+		},
+		{
+			name: "Detached stream with keywords",
+			line: "43 DETACHED 0 203.0.113.9:80 REASON=TIMEOUT SOURCE_ADDR=127.0.0.1:50412 PURPOSE=USER",
+			want: Stream{
+				ID:        "43",
+				Status:    "DETACHED",
+				CircuitID: "0",
+				Target:    "203.0.113.9:80",
+				Reason:    "TIMEOUT",
+				Source:    "127.0.0.1:50412",
+				Purpose:   "USER",
+			},
+		},
+		{
+			name:    "Missing target",
+			line:    "44 NEW 0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := ParseStream(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stream != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, stream)
+			}
+		})
+	}
+}
+
+func TestParseStreamStatus(t *testing.T) {
+	data := "1 SUCCEEDED 3 a.example:443\n2 SUCCEEDED 3 b.example:443\n\n3 NEW 0 c.example:80"
+
+	streams, err := ParseStreamStatus(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(streams) != 3 {
+		t.Fatalf("expected 3 streams, got %d", len(streams))
+	}
+
+	counts := CountStreamsByStatus(streams)
+	if counts["SUCCEEDED"] != 2 || counts["NEW"] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
+
+func TestParseStreamStatus_Empty(t *testing.T) {
+	streams, err := ParseStreamStatus("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streams == nil || len(streams) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", streams)
+	}
+}
+
+func TestParseStreamStatus_Malformed(t *testing.T) {
+	if _, err := ParseStreamStatus("1 SUCCEEDED"); err == nil {
+		t.Error("expected error for malformed stream line")
+	}
+}
+
+func TestParseStreamEvent(t *testing.T) {
+	stream, err := ParseStreamEvent(Event{Type: EventStream, Data: "9 CLOSED 2 example.com:443 REASON=DONE"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stream.Status != "CLOSED" || stream.Reason != "DONE" {
+		t.Errorf("unexpected stream: %+v", stream)
+	}
+}
+
+func TestCloseAndAttachStream_FakeTor(t *testing.T) {
+	fake := newFakeTor(t, func(cmd string) string {
+		if cmd == "CLOSESTREAM 99 1" {
+			return "552 Unknown stream \"99\"\r\n"
+		}
+		return okHandler(cmd)
+	})
+
+	client := NewClient(fake.Addr(), "")
+	defer func() { _ = client.Close() }()
+
+	if err := client.CloseStream("7", StreamReasonMisc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.AttachStream("8", "0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.CloseStream("99", StreamReasonMisc); !errors.Is(err, ErrUnknownStream) {
+		t.Errorf("expected ErrUnknownStream, got %v", err)
+	}
+	if err := client.CloseStream("7", 0); err == nil {
+		t.Error("expected an error for an invalid reason")
+	}
+	if err := client.AttachStream("8", "1\r\nSIGNAL HALT"); err == nil {
+		t.Error("expected an error for an invalid circuit id")
+	}
+
+	expected := []string{"CLOSESTREAM 7 1", "ATTACHSTREAM 8 0", "CLOSESTREAM 99 1"}
+	if commands := fake.Commands(); !slices.Equal(commands[len(commands)-3:], expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+}
@@ -0,0 +1,124 @@
+package torrc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Bridge is a parsed bridge line of the form
+// "[transport] host:port [fingerprint] [key=value ...]".
+type Bridge struct {
+	Transport   string // Pluggable transport, empty for a plain bridge
+	Address     string // host:port
+	Fingerprint string // Uppercase hex identity, if given
+	Args        []string
+}
+
+// Label identifies the bridge in logs and notifications without its
+// transport arguments, which can hold secrets such as obfs4 certificates.
+func (b Bridge) Label() string {
+	if b.Transport == "" {
+		return b.Address
+	}
+	return b.Transport + " " + b.Address
+}
+
+// ParseBridge parses a bridge line as given to Tor's Bridge option.
+func ParseBridge(line string) (Bridge, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Bridge{}, errors.New("empty bridge line")
+	}
+
+	var b Bridge
+	if !strings.Contains(fields[0], ":") {
+		if !validTransport(fields[0]) {
+			return Bridge{}, fmt.Errorf("invalid transport name %q", fields[0])
+		}
+		b.Transport = fields[0]
+		fields = fields[1:]
+		if len(fields) == 0 {
+			return Bridge{}, errors.New("missing bridge address")
+		}
+	}
+
+	host, portStr, err := net.SplitHostPort(fields[0])
+	if err != nil {
+		return Bridge{}, fmt.Errorf("invalid bridge address %q: %v", fields[0], err)
+	}
+	if port, err := strconv.Atoi(portStr); err != nil || port < 1 || port > 65535 || host == "" {
+		return Bridge{}, fmt.Errorf("invalid bridge address %q", fields[0])
+	}
+	b.Address = fields[0]
+	fields = fields[1:]
+
+	if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+		fingerprint := strings.TrimPrefix(fields[0], "$")
+		if len(fingerprint) != 40 {
+			return Bridge{}, fmt.Errorf("invalid bridge fingerprint %q", fields[0])
+		}
+		if _, err := hex.DecodeString(fingerprint); err != nil {
+			return Bridge{}, fmt.Errorf("invalid bridge fingerprint %q", fields[0])
+		}
+		b.Fingerprint = strings.ToUpper(fingerprint)
+		fields = fields[1:]
+	}
+
+	for _, arg := range fields {
+		if key, _, ok := strings.Cut(arg, "="); !ok || key == "" {
+			return Bridge{}, fmt.Errorf("invalid bridge argument %q: expected key=value", arg)
+		}
+	}
+	if len(fields) > 0 && b.Transport == "" {
+		return Bridge{}, errors.New("bridge arguments require a pluggable transport")
+	}
+	b.Args = fields
+
+	return b, nil
+}
+
+// PluginTransports returns the transports a ClientTransportPlugin line
+// serves, e.g. obfs4 and webtunnel for "obfs4,webtunnel exec /usr/bin/lyrebird".
+func PluginTransports(plugin string) []string {
+	fields := strings.Fields(plugin)
+	if len(fields) == 0 {
+		return nil
+	}
+	return strings.Split(fields[0], ",")
+}
+
+// ValidatePlugin checks a ClientTransportPlugin line has the
+// "transports exec path [args]" form Tor expects of managed transports.
+func ValidatePlugin(plugin string) error {
+	fields := strings.Fields(plugin)
+	if len(fields) < 3 || fields[1] != "exec" {
+		return errors.New(`expected "<transports> exec <path> [args]"`)
+	}
+	for _, transport := range PluginTransports(plugin) {
+		if !validTransport(transport) {
+			return fmt.Errorf("invalid transport name %q", transport)
+		}
+	}
+	return nil
+}
+
+// validTransport reports whether name is a C identifier, as Tor requires of
+// pluggable transport names.
+func validTransport(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
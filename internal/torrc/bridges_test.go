@@ -0,0 +1,88 @@
+package torrc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseBridge(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Bridge
+		wantErr bool
+	}{
+		{
+			name: "plain",
+			line: "192.0.2.1:9001 0123456789abcdef0123456789abcdef01234567",
+			want: Bridge{Address: "192.0.2.1:9001", Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567"},
+		},
+		{
+			name: "obfs4",
+			line: "obfs4 192.0.2.2:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0",
+			want: Bridge{
+				Transport:   "obfs4",
+				Address:     "192.0.2.2:443",
+				Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567",
+				Args:        []string{"cert=AAAA", "iat-mode=0"},
+			},
+		},
+		{
+			name: "snowflake",
+			line: "snowflake 192.0.2.3:80 2B280B23E1107BB62ABFC40DDCC8824814F80A72 fronts=foursquare.com,github.githubassets.com ice=stun:stun.l.google.com:19302,stun:stun.antisip.com:3478",
+			want: Bridge{
+				Transport:   "snowflake",
+				Address:     "192.0.2.3:80",
+				Fingerprint: "2B280B23E1107BB62ABFC40DDCC8824814F80A72",
+				Args:        []string{"fronts=foursquare.com,github.githubassets.com", "ice=stun:stun.l.google.com:19302,stun:stun.antisip.com:3478"},
+			},
+		},
+		{
+			name: "webtunnel without fingerprint",
+			line: "webtunnel [2001:db8::1]:443 url=https://example.com/secret ver=0.0.1",
+			want: Bridge{Transport: "webtunnel", Address: "[2001:db8::1]:443", Args: []string{"url=https://example.com/secret", "ver=0.0.1"}},
+		},
+		{name: "empty", line: "  ", wantErr: true},
+		{name: "missing port", line: "obfs4 192.0.2.1", wantErr: true},
+		{name: "missing address", line: "obfs4", wantErr: true},
+		{name: "bad transport", line: "obfs-4 192.0.2.1:443", wantErr: true},
+		{name: "short fingerprint", line: "192.0.2.1:443 ABCDEF", wantErr: true},
+		{name: "bad argument", line: "obfs4 192.0.2.1:443 cert", wantErr: true},
+		{name: "arguments without transport", line: "192.0.2.1:443 cert=AAAA", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBridge(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBridge(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Transport != tt.want.Transport || got.Address != tt.want.Address ||
+				got.Fingerprint != tt.want.Fingerprint || !slices.Equal(got.Args, tt.want.Args) {
+				t.Errorf("ParseBridge(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBridgeLabel(t *testing.T) {
+	bridge, err := ParseBridge("obfs4 192.0.2.2:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=secret iat-mode=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bridge.Label(); got != "obfs4 192.0.2.2:443" {
+		t.Errorf("expected label without arguments, got %q", got)
+	}
+}
+
+func TestPluginTransports(t *testing.T) {
+	if got := PluginTransports("obfs4,webtunnel exec /usr/bin/lyrebird"); !slices.Equal(got, []string{"obfs4", "webtunnel"}) {
+		t.Errorf("unexpected transports %v", got)
+	}
+	if got := PluginTransports(""); got != nil {
+		t.Errorf("expected no transports, got %v", got)
+	}
+}
@@ -0,0 +1,501 @@
+// Package torrc renders Tor configuration files from typed settings.
+package torrc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// header warns that generated files are rewritten on every start.
+const header = "# Generated by torarr from its configuration; manual edits are overwritten.\n"
+
+// IsolationFlags lists the SocksPort stream isolation flags accepted in
+// SocksPort.Flags, in addition to SessionGroup=N.
+var IsolationFlags = []string{
+	"IsolateClientAddr",
+	"IsolateSOCKSAuth",
+	"IsolateClientProtocol",
+	"IsolateDestPort",
+	"IsolateDestAddr",
+	"KeepAliveIsolateSOCKSAuth",
+	"NoIsolateClientAddr",
+	"NoIsolateSOCKSAuth",
+}
+
+// LogLevels lists Tor's log severities from most to least verbose.
+var LogLevels = []string{"debug", "info", "notice", "warn", "err"}
+
+// Config holds the torrc options Torarr manages.
+type Config struct {
+	SocksPorts            []SocksPort
+	ControlPort           string // host:port or port; empty or "0" disables TCP control
+	ControlSocket         string // Path of a control socket, if any
+	CookieAuthentication  bool
+	HashedControlPassword string // Output of "tor --hash-password"
+	DataDirectory         string
+	LogLevel              string // Minimum severity logged to stdout
+
+	ExitNodes        string // Router sets, e.g. "{us},{ca}"
+	ExcludeNodes     string
+	ExcludeExitNodes string
+	StrictNodes      bool
+	NumEntryGuards   int // 0 keeps Tor's default
+
+	BandwidthRate  string // e.g. "1 MBytes"; empty keeps Tor's default
+	BandwidthBurst string
+
+	AccountingMax   string // e.g. "100 GBytes" per accounting period; empty disables accounting
+	AccountingStart string // e.g. "month 1 00:00"; empty keeps Tor's default
+
+	UseBridges             bool
+	Bridges                []string // Bridge lines without the "Bridge" keyword
+	ClientTransportPlugins []string // e.g. "obfs4 exec /usr/bin/lyrebird"
+}
+
+// SocksPort is a SOCKS listener with its isolation flags.
+type SocksPort struct {
+	Address string // host:port or port
+	Flags   []string
+}
+
+// Value returns the SocksPort option value: the address followed by the
+// flags in their canonical spelling. Unknown flags are passed through.
+func (p SocksPort) Value() string {
+	value := p.Address
+	for _, flag := range p.Flags {
+		if canonical, err := canonicalFlag(flag); err == nil {
+			flag = canonical
+		}
+		value += " " + flag
+	}
+	return value
+}
+
+// Validate reports every invalid value in c.
+func (c *Config) Validate() error {
+	var problems []error
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if len(c.SocksPorts) == 0 {
+		fail("at least one SocksPort is required")
+	}
+	socksPorts := make(map[string]bool)
+	for _, port := range c.SocksPorts {
+		if err := ValidateAddress(port.Address); err != nil {
+			fail("invalid SocksPort %q: %v", port.Address, err)
+		} else if number := AddressPort(port.Address); socksPorts[number] {
+			fail("duplicate SocksPort %s", number)
+		} else {
+			socksPorts[number] = true
+		}
+		for _, flag := range port.Flags {
+			if _, err := canonicalFlag(flag); err != nil {
+				fail("invalid SocksPort %s flag: %v", port.Address, err)
+			}
+		}
+	}
+
+	if c.ControlPort != "" && c.ControlPort != "0" {
+		if err := ValidateAddress(c.ControlPort); err != nil {
+			fail("invalid ControlPort %q: %v", c.ControlPort, err)
+		}
+	}
+	if c.HashedControlPassword != "" && !strings.HasPrefix(c.HashedControlPassword, "16:") {
+		fail("invalid HashedControlPassword: expected output of tor --hash-password")
+	}
+	if c.DataDirectory == "" {
+		fail("DataDirectory is required")
+	}
+	if c.LogLevel != "" && !slices.Contains(LogLevels, c.LogLevel) {
+		fail("invalid log level %q (valid: %s)", c.LogLevel, strings.Join(LogLevels, ", "))
+	}
+
+	for _, set := range []struct {
+		name, value string
+	}{
+		{"ExitNodes", c.ExitNodes},
+		{"ExcludeNodes", c.ExcludeNodes},
+		{"ExcludeExitNodes", c.ExcludeExitNodes},
+	} {
+		if err := ValidateRouterSet(set.value); err != nil {
+			fail("invalid %s %q: %v", set.name, set.value, err)
+		}
+	}
+	if c.NumEntryGuards < 0 {
+		fail("invalid NumEntryGuards %d: must not be negative", c.NumEntryGuards)
+	}
+
+	rate, rateErr := ParseBandwidth(c.BandwidthRate)
+	if rateErr != nil {
+		fail("invalid BandwidthRate %q: %v", c.BandwidthRate, rateErr)
+	}
+	burst, burstErr := ParseBandwidth(c.BandwidthBurst)
+	if burstErr != nil {
+		fail("invalid BandwidthBurst %q: %v", c.BandwidthBurst, burstErr)
+	}
+	if rateErr == nil && burstErr == nil && rate > 0 && burst > 0 && burst < rate {
+		fail("BandwidthBurst %q must be at least BandwidthRate %q", c.BandwidthBurst, c.BandwidthRate)
+	}
+
+	if _, err := ParseBandwidth(c.AccountingMax); err != nil {
+		fail("invalid AccountingMax %q: %v", c.AccountingMax, err)
+	}
+	if err := ValidateAccountingStart(c.AccountingStart); err != nil {
+		fail("invalid AccountingStart %q: %v", c.AccountingStart, err)
+	}
+
+	if c.UseBridges && len(c.Bridges) == 0 {
+		fail("UseBridges requires at least one bridge")
+	}
+	transports := make(map[string]bool)
+	for _, plugin := range c.ClientTransportPlugins {
+		if err := ValidatePlugin(plugin); err != nil {
+			fail("invalid ClientTransportPlugin %q: %v", plugin, err)
+		}
+		for _, transport := range PluginTransports(plugin) {
+			transports[transport] = true
+		}
+	}
+	for _, line := range c.Bridges {
+		bridge, err := ParseBridge(line)
+		if err != nil {
+			fail("invalid Bridge: %v", err)
+			continue
+		}
+		if bridge.Transport != "" && !transports[bridge.Transport] {
+			fail("Bridge %s uses transport %q without a ClientTransportPlugin", bridge.Address, bridge.Transport)
+		}
+	}
+
+	// Every value is written on a single line, so line breaks would inject
+	// extra options.
+	values := []string{c.ControlPort, c.ControlSocket, c.HashedControlPassword, c.DataDirectory,
+		c.BandwidthRate, c.BandwidthBurst, c.AccountingMax, c.AccountingStart}
+	values = append(values, c.Bridges...)
+	values = append(values, c.ClientTransportPlugins...)
+	for _, port := range c.SocksPorts {
+		values = append(values, port.Address)
+		values = append(values, port.Flags...)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			fail("invalid value %q: must not contain line breaks", value)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// Render validates c and returns the torrc contents.
+func Render(c Config) ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(header)
+	line := func(key, value string) {
+		fmt.Fprintf(&b, "%s %s\n", key, value)
+	}
+
+	for _, port := range c.SocksPorts {
+		line("SocksPort", port.Value())
+	}
+
+	if c.ControlPort != "" {
+		line("ControlPort", c.ControlPort)
+	}
+	if c.ControlSocket != "" {
+		line("ControlSocket", c.ControlSocket)
+	}
+	if c.CookieAuthentication {
+		line("CookieAuthentication", "1")
+	}
+	if c.HashedControlPassword != "" {
+		line("HashedControlPassword", c.HashedControlPassword)
+	}
+	line("DataDirectory", c.DataDirectory)
+	logLevel := c.LogLevel
+	if logLevel == "" {
+		logLevel = "notice"
+	}
+	line("Log", logLevel+" stdout")
+
+	if c.ExitNodes != "" {
+		line("ExitNodes", c.ExitNodes)
+	}
+	if c.ExcludeNodes != "" {
+		line("ExcludeNodes", c.ExcludeNodes)
+	}
+	if c.ExcludeExitNodes != "" {
+		line("ExcludeExitNodes", c.ExcludeExitNodes)
+	}
+	if c.StrictNodes {
+		line("StrictNodes", "1")
+	}
+	if c.NumEntryGuards > 0 {
+		line("NumEntryGuards", strconv.Itoa(c.NumEntryGuards))
+	}
+
+	if c.BandwidthRate != "" {
+		line("BandwidthRate", c.BandwidthRate)
+	}
+	if c.BandwidthBurst != "" {
+		line("BandwidthBurst", c.BandwidthBurst)
+	}
+	if c.AccountingMax != "" {
+		line("AccountingMax", c.AccountingMax)
+	}
+	if c.AccountingStart != "" {
+		line("AccountingStart", c.AccountingStart)
+	}
+
+	if c.UseBridges {
+		line("UseBridges", "1")
+	}
+	for _, plugin := range c.ClientTransportPlugins {
+		line("ClientTransportPlugin", plugin)
+	}
+	for _, bridge := range c.Bridges {
+		line("Bridge", bridge)
+	}
+
+	return b.Bytes(), nil
+}
+
+// Write renders c and atomically replaces the file at path with it.
+func Write(path string, c Config) error {
+	data, err := Render(c)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data)
+}
+
+// WriteFile replaces path via a temporary file in the same directory so Tor
+// never reads a partially written torrc.
+func WriteFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".torrc-*")
+	if err != nil {
+		return fmt.Errorf("failed to write torrc: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write torrc: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write torrc: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write torrc: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace torrc: %w", err)
+	}
+	return nil
+}
+
+// canonicalFlag returns the canonical spelling of a SocksPort isolation
+// flag, matched case-insensitively.
+func canonicalFlag(flag string) (string, error) {
+	if group, ok := cutPrefixFold(flag, "SessionGroup="); ok {
+		if n, err := strconv.Atoi(group); err != nil || n < 0 {
+			return "", fmt.Errorf("invalid session group %q", group)
+		}
+		return "SessionGroup=" + group, nil
+	}
+	for _, known := range IsolationFlags {
+		if strings.EqualFold(flag, known) {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("unknown isolation flag %q", flag)
+}
+
+// ValidFlag reports whether flag is a known SocksPort isolation flag.
+func ValidFlag(flag string) bool {
+	_, err := canonicalFlag(flag)
+	return err == nil
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// ValidateAddress accepts a port or host:port, as used by SocksPort and
+// ControlPort.
+func ValidateAddress(address string) error {
+	portStr := address
+	if strings.Contains(address, ":") {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if host == "" {
+			return errors.New("missing host")
+		}
+		portStr = port
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+	return nil
+}
+
+// AddressPort returns the port of a port or host:port address.
+func AddressPort(address string) string {
+	if _, port, err := net.SplitHostPort(address); err == nil {
+		return port
+	}
+	return address
+}
+
+// ValidateRouterSet checks the comma-separated list syntax of a router set;
+// the entries themselves (country codes, fingerprints, nicknames and
+// addresses) are checked by Tor.
+func ValidateRouterSet(set string) error {
+	if set == "" {
+		return nil
+	}
+	for _, entry := range strings.Split(set, ",") {
+		if entry == "" {
+			return errors.New("empty entry")
+		}
+		if strings.ContainsAny(entry, " \t\r\n") {
+			return fmt.Errorf("entry %q contains whitespace", entry)
+		}
+	}
+	return nil
+}
+
+// bandwidthUnits maps Tor's memory-unit names (lowercased) to bytes.
+var bandwidthUnits = map[string]int64{
+	"":          1,
+	"b":         1,
+	"byte":      1,
+	"bytes":     1,
+	"kb":        1 << 10,
+	"kbyte":     1 << 10,
+	"kbytes":    1 << 10,
+	"kilobyte":  1 << 10,
+	"kilobytes": 1 << 10,
+	"mb":        1 << 20,
+	"mbyte":     1 << 20,
+	"mbytes":    1 << 20,
+	"megabyte":  1 << 20,
+	"megabytes": 1 << 20,
+	"gb":        1 << 30,
+	"gbyte":     1 << 30,
+	"gbytes":    1 << 30,
+	"gigabyte":  1 << 30,
+	"gigabytes": 1 << 30,
+	"tb":        1 << 40,
+	"tbyte":     1 << 40,
+	"tbytes":    1 << 40,
+	"terabyte":  1 << 40,
+	"terabytes": 1 << 40,
+	"kbit":      1 << 10 / 8,
+	"kbits":     1 << 10 / 8,
+	"kilobit":   1 << 10 / 8,
+	"kilobits":  1 << 10 / 8,
+	"mbit":      1 << 20 / 8,
+	"mbits":     1 << 20 / 8,
+	"megabit":   1 << 20 / 8,
+	"megabits":  1 << 20 / 8,
+	"gbit":      1 << 30 / 8,
+	"gbits":     1 << 30 / 8,
+	"gigabit":   1 << 30 / 8,
+	"gigabits":  1 << 30 / 8,
+	"tbit":      1 << 40 / 8,
+	"tbits":     1 << 40 / 8,
+	"terabit":   1 << 40 / 8,
+	"terabits":  1 << 40 / 8,
+}
+
+// ValidateAccountingStart checks an AccountingStart value: "day HH:MM",
+// "week D HH:MM" with D from 1 (Monday) to 7, or "month D HH:MM" with D
+// from 1 to 28. An empty value is valid.
+func ValidateAccountingStart(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return errors.New("expected day, week or month")
+	}
+	maxDay := map[string]int{"day": 0, "week": 7, "month": 28}
+	limit, ok := maxDay[strings.ToLower(fields[0])]
+	if !ok {
+		return errors.New("expected day, week or month")
+	}
+	if limit == 0 && len(fields) != 2 {
+		return errors.New(`expected "day HH:MM"`)
+	}
+	if limit > 0 {
+		if len(fields) != 3 {
+			return fmt.Errorf(`expected "%s D HH:MM"`, strings.ToLower(fields[0]))
+		}
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || day < 1 || day > limit {
+			return fmt.Errorf("day must be between 1 and %d", limit)
+		}
+	}
+
+	clock := fields[len(fields)-1]
+	hour, minute, found := strings.Cut(clock, ":")
+	h, hErr := strconv.Atoi(hour)
+	m, mErr := strconv.Atoi(minute)
+	if !found || hErr != nil || mErr != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return fmt.Errorf("invalid time %q", clock)
+	}
+	return nil
+}
+
+// ParseBandwidth converts a Tor bandwidth value such as "1 MBytes" or
+// "800 KBits" to bytes per second. An empty value parses as 0.
+func ParseBandwidth(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, errors.New("expected a number and an optional unit")
+	}
+
+	number, unit := fields[0], ""
+	if len(fields) == 2 {
+		unit = fields[1]
+	} else if i := strings.IndexFunc(number, func(r rune) bool { return r < '0' || r > '9' }); i > 0 {
+		number, unit = number[:i], number[i:]
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid amount %q", number)
+	}
+
+	multiplier, ok := bandwidthUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	return n * multiplier, nil
+}
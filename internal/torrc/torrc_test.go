@@ -0,0 +1,224 @@
+package torrc
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestRender_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name: "minimal",
+			config: Config{
+				SocksPorts:    []SocksPort{{Address: "0.0.0.0:9050"}},
+				DataDirectory: "/var/lib/tor",
+			},
+		},
+		{
+			name: "full",
+			config: Config{
+				SocksPorts: []SocksPort{
+					{Address: "0.0.0.0:9050", Flags: []string{"isolatedestaddr", "IsolateSOCKSAuth"}},
+					{Address: "127.0.0.1:9150", Flags: []string{"SessionGroup=2"}},
+				},
+				ControlPort:            "127.0.0.1:9051",
+				CookieAuthentication:   true,
+				HashedControlPassword:  "16:010203040506070860E7FFDF52CE2082EAC77C46023680FFE53C67399C",
+				DataDirectory:          "/var/lib/tor",
+				LogLevel:               "err",
+				ExitNodes:              "{us},{ca}",
+				ExcludeNodes:           "{ru},{by}",
+				ExcludeExitNodes:       "{??}",
+				StrictNodes:            true,
+				NumEntryGuards:         2,
+				BandwidthRate:          "1 MBytes",
+				BandwidthBurst:         "2 MBytes",
+				AccountingMax:          "100 GBytes",
+				AccountingStart:        "month 1 00:00",
+				UseBridges:             true,
+				Bridges:                []string{"obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=AAAA iat-mode=0"},
+				ClientTransportPlugins: []string{"obfs4 exec /usr/bin/lyrebird"},
+			},
+		},
+		{
+			name: "control_socket",
+			config: Config{
+				SocksPorts:           []SocksPort{{Address: "9050"}},
+				ControlPort:          "0",
+				ControlSocket:        "/var/lib/tor/control.sock",
+				CookieAuthentication: true,
+				DataDirectory:        "/var/lib/tor",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.config)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".torrc")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("rendered torrc does not match %s:\n--- got ---\n%s--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			SocksPorts:    []SocksPort{{Address: "0.0.0.0:9050"}},
+			DataDirectory: "/var/lib/tor",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "no socks port", modify: func(c *Config) { c.SocksPorts = nil }, wantErr: "at least one SocksPort"},
+		{name: "bad socks port", modify: func(c *Config) { c.SocksPorts[0].Address = "0.0.0.0:99999" }, wantErr: "invalid SocksPort"},
+		{name: "unknown flag", modify: func(c *Config) { c.SocksPorts[0].Flags = []string{"IsolateEverything"} }, wantErr: "unknown isolation flag"},
+		{name: "duplicate socks port", modify: func(c *Config) {
+			c.SocksPorts = append(c.SocksPorts, SocksPort{Address: "127.0.0.1:9050", Flags: []string{"IsolateDestAddr"}})
+		}, wantErr: "duplicate SocksPort 9050"},
+		{name: "bad session group", modify: func(c *Config) { c.SocksPorts[0].Flags = []string{"SessionGroup=x"} }, wantErr: "invalid session group"},
+		{name: "bad control port", modify: func(c *Config) { c.ControlPort = "localhost" }, wantErr: "invalid ControlPort"},
+		{name: "unhashed password", modify: func(c *Config) { c.HashedControlPassword = "secret" }, wantErr: "HashedControlPassword"},
+		{name: "no data directory", modify: func(c *Config) { c.DataDirectory = "" }, wantErr: "DataDirectory is required"},
+		{name: "bad log level", modify: func(c *Config) { c.LogLevel = "verbose" }, wantErr: "invalid log level"},
+		{name: "empty router set entry", modify: func(c *Config) { c.ExitNodes = "{us},,{ca}" }, wantErr: "invalid ExitNodes"},
+		{name: "router set whitespace", modify: func(c *Config) { c.ExcludeNodes = "{us}, {ca}" }, wantErr: "invalid ExcludeNodes"},
+		{name: "negative guards", modify: func(c *Config) { c.NumEntryGuards = -1 }, wantErr: "NumEntryGuards"},
+		{name: "bad bandwidth unit", modify: func(c *Config) { c.BandwidthRate = "5 parsecs" }, wantErr: "invalid BandwidthRate"},
+		{name: "burst below rate", modify: func(c *Config) { c.BandwidthRate, c.BandwidthBurst = "2 MBytes", "1 MBytes" }, wantErr: "must be at least BandwidthRate"},
+		{name: "bad accounting max", modify: func(c *Config) { c.AccountingMax = "lots" }, wantErr: "invalid AccountingMax"},
+		{name: "bad accounting start", modify: func(c *Config) { c.AccountingStart = "month 31 00:00" }, wantErr: "invalid AccountingStart"},
+		{name: "bridges required", modify: func(c *Config) { c.UseBridges = true }, wantErr: "UseBridges requires"},
+		{name: "line break injection", modify: func(c *Config) { c.Bridges = []string{"obfs4 192.0.2.1:443\nExitNodes {ru}"} }, wantErr: "line breaks"},
+		{name: "bad bridge", modify: func(c *Config) { c.Bridges = []string{"obfs4 192.0.2.1"} }, wantErr: "invalid Bridge"},
+		{name: "missing transport plugin", modify: func(c *Config) { c.Bridges = []string{"snowflake 192.0.2.3:80"} }, wantErr: "without a ClientTransportPlugin"},
+		{name: "bad transport plugin", modify: func(c *Config) { c.ClientTransportPlugins = []string{"obfs4 /usr/bin/lyrebird"} }, wantErr: "invalid ClientTransportPlugin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAccountingStart(t *testing.T) {
+	for _, value := range []string{"", "day 04:30", "week 1 00:00", "Month 28 23:59"} {
+		if err := ValidateAccountingStart(value); err != nil {
+			t.Errorf("ValidateAccountingStart(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{" ", "year 1 00:00", "day 1 00:00", "week 8 00:00", "month 0 00:00", "month 1", "day 24:00", "day 12:60"} {
+		if err := ValidateAccountingStart(value); err == nil {
+			t.Errorf("ValidateAccountingStart(%q) expected an error", value)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: " ", wantErr: true},
+		{value: "512", want: 512},
+		{value: "100 KBytes", want: 100 << 10},
+		{value: "1 MB", want: 1 << 20},
+		{value: "1MBytes", want: 1 << 20},
+		{value: "2 gigabytes", want: 2 << 30},
+		{value: "800 kbits", want: 800 << 7},
+		{value: "8 Mbits", want: 1 << 20},
+		{value: "1.5 MBytes", wantErr: true},
+		{value: "-1 KBytes", wantErr: true},
+		{value: "1 MBytes extra", wantErr: true},
+		{value: "10 furlongs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBandwidth(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBandwidth(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBandwidth(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrc")
+	if err := os.WriteFile(path, []byte("SocksPort 9999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(path, Config{DataDirectory: "/var/lib/tor"}); err == nil {
+		t.Fatal("expected an invalid configuration to be rejected")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "SocksPort 9999\n" {
+		t.Errorf("expected the existing torrc to be kept on error, got %q", data)
+	}
+
+	c := Config{SocksPorts: []SocksPort{{Address: "9050"}}, DataDirectory: "/var/lib/tor"}
+	if err := Write(path, c); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "SocksPort 9050\n") {
+		t.Errorf("expected rendered torrc, got %q", data)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
@@ -3,25 +3,17 @@ set -e
 
 echo "Starting Torarr..."
 
-# Generate Tor control password if not set
-if [ -z "$TOR_CONTROL_PASSWORD" ]; then
-    TOR_CONTROL_PASSWORD="torarr$(date +%s | sha256sum | base64 | head -c 16)"
-    export TOR_CONTROL_PASSWORD
-    echo "Generated Tor control password: $TOR_CONTROL_PASSWORD"
+# In supervise mode the health server prepares torrc, runs Tor as its child
+# and restarts it if it exits.
+if [ "$1" = "supervise" ]; then
+    exec /usr/local/bin/healthserver supervise
 fi
 
-# Update torrc with hashed password
-if [ -n "$TOR_CONTROL_PASSWORD" ]; then
-    HASHED_PASSWORD=$(tor --hash-password "$TOR_CONTROL_PASSWORD" | tail -n 1)
-    sed -i "s|^HashedControlPassword.*|HashedControlPassword $HASHED_PASSWORD|" /etc/tor/torrc
-fi
-
-# Configure Exit Nodes if specified
-if [ -n "$TOR_EXIT_NODES" ]; then
-    echo "ExitNodes $TOR_EXIT_NODES" >> /etc/tor/torrc
-    echo "StrictNodes 1" >> /etc/tor/torrc
-    echo "Configured ExitNodes: $TOR_EXIT_NODES"
-fi
+# Apply TOR_CONTROL_PASSWORD, TOR_EXIT_NODES and TOR_LOG_RELAY to torrc, or
+# render the whole file from the configuration when TORRC_GENERATE is set.
+# Control port authentication uses the cookie in the DataDirectory unless a
+# password is configured.
+/usr/local/bin/healthserver torrc prepare
 
 # Start health server in background
 echo "Starting health server..."
@@ -33,4 +25,4 @@ trap 'echo "Shutting down..."; kill -TERM $HEALTH_PID 2>/dev/null || true; exit
 
 # Start Tor as main process
 echo "Starting Tor..."
-exec tor -f /etc/tor/torrc
+exec tor -f "${TORRC_FILE:-/etc/tor/torrc}"